@@ -3,6 +3,7 @@ package errors
 import (
 	"fmt"
 	"strings"
+	"unicode"
 
 	pkgerr "github.com/pkg/errors"
 )
@@ -29,6 +30,28 @@ const (
 	permissionError = "PermissionError"
 	// 仓库
 	repositoryError = "RepositoryError"
+	// 并发修改冲突（客户端可重新获取数据后重试）
+	conflict = "Conflict"
+	// 已永久迁移（例如命名空间改名）
+	moved = "Moved"
+	// 未知的内部错误（非 Result 类型的错误）
+	internalError = "InternalError"
+)
+
+// 错误分类（Result.Type），用于在包外区分错误，例如翻译错误信息
+const (
+	TypeInvalidParameter = invalidParameter
+	TypeNotFound         = notFoundError
+	TypeGraphQL          = graphQLError
+	TypeAlreadyExists    = alreadyExists
+	TypeAccessDenied     = accessDeniedError
+	TypeSQL              = sqlError
+	TypeUnauthorized     = unauthorized
+	TypePermission       = permissionError
+	TypeRepository       = repositoryError
+	TypeConflict         = conflict
+	TypeMoved            = moved
+	TypeInternal         = internalError
 )
 
 // 定义错误原因
@@ -55,6 +78,38 @@ const (
 	SvcServerNotReady = "SvcServerNotReady"
 	// 无权限
 	NoPermission = "NoPermission"
+	// 未改变
+	Unchanged = "Unchanged"
+	// 强度太弱
+	Weak = "Weak"
+	// 已锁定
+	Locked = "Locked"
+	// 最后一位owner
+	LastOwner = "LastOwner"
+	// 被已注销（软删除）的数据占用
+	Deleted = "Deleted"
+	// 已停用
+	Suspended = "Suspended"
+	// 不能对自己操作
+	Self = "Self"
+	// 请求过于频繁
+	RateLimited = "RateLimited"
+	// 系统保留
+	Reserved = "Reserved"
+	// 无法投递（例如邮箱域名没有MX记录）
+	Undeliverable = "Undeliverable"
+	// 主邮箱（例如不能删除）
+	Primary = "Primary"
+	// 仍被使用（例如用户还有仓库）
+	InUse = "InUse"
+	// 登录失败，不区分用户不存在、未激活、密码错误
+	InvalidCredentials = "InvalidCredentials"
+	// 超出大小限制（例如请求体）
+	TooLarge = "TooLarge"
+	// 冷却期内不允许再次操作（例如修改用户名），见 CooldownError
+	Cooldown = "Cooldown"
+	// 最后一种登录方式（例如没有密码时不能解绑最后一个第三方账号）
+	LastAuthMethod = "LastAuthMethod"
 )
 
 var httpCodeSet = map[string]int{
@@ -67,13 +122,32 @@ var httpCodeSet = map[string]int{
 	unauthorized:      401,
 	permissionError:   403,
 	repositoryError:   500,
+	conflict:          409,
+	moved:             301,
+	internalError:     500,
 }
 
+// 没有 model/reason 可用时的固定code
+const (
+	CodeOK           = "ok"
+	CodeInternal     = "internal"
+	CodeSQL          = "internal.sql"
+	CodeUnauthorized = "unauthorized"
+	CodeGraphQL      = "graphql.invalid"
+)
+
+// Result 接口统一的错误结构 {code, message, field}
+// Code 为稳定的、机器可读的错误码（例如 user.password.not_equal），前端应根据它来判断错误
+// Message 保留原有的<xxx>格式
 type Result struct {
 	Err        error  `json:"-"`
+	Type       string `json:"-"` // 错误分类，例如 InvalidParameter
 	Code       string `json:"code"`
 	StatusCode int    `json:"-"`
 	Message    string `json:"message"`
+	Field      string `json:"field,omitempty"`    // InvalidParameter 时为出错的字段
+	Location   string `json:"location,omitempty"` // Moved 时为新的path
+	RetryAt    int64  `json:"retry_at,omitempty"` // Cooldown 时为允许再次操作的时间戳
 }
 
 func (e *Result) Error() string {
@@ -116,6 +190,33 @@ func AccessDenied(model, reason string) error {
 	return mustCode(nil, accessDeniedError, model, reason)
 }
 
+// ConflictError 数据已被其他请求修改，客户端重新获取最新数据后可重试
+func ConflictError(model string) error {
+	return mustCode(nil, conflict, model)
+}
+
+// MovedError 资源已永久迁移，location 为新的path（由controller转换为跳转地址）
+func MovedError(model, location string) error {
+	r := newResult(nil, moved, model)
+	r.Location = location
+	return Trace(r)
+}
+
+// MovedLocation err 为 MovedError 时返回新的path
+func MovedLocation(err error) (string, bool) {
+	if r, ok := Cause(err).(*Result); ok && r.Type == moved {
+		return r.Location, true
+	}
+	return "", false
+}
+
+// CooldownError 冷却期内不允许再次操作，retryAt 为允许再次操作的时间戳
+func CooldownError(model string, retryAt int64) error {
+	r := newResult(nil, accessDeniedError, model, Cooldown)
+	r.RetryAt = retryAt
+	return Trace(r)
+}
+
 func PermissionError(reason string) error {
 	return mustCode(nil, permissionError, reason)
 }
@@ -136,16 +237,88 @@ func mustCode(err error, parts ...string) error {
 	if len(parts) == 0 {
 		panic("parts is required")
 	}
-	hc, ok := httpCodeSet[parts[0]]
+	return Trace(newResult(err, parts[0], parts[1:]...))
+}
+
+func newResult(err error, typ string, parts ...string) *Result {
+	hc, ok := httpCodeSet[typ]
 	if !ok {
 		hc = 500
 	}
-	return Trace(&Result{
+	code, field := stableCode(typ, parts...)
+	return &Result{
 		Err:        err,
-		Code:       parts[0],
+		Type:       typ,
+		Code:       code,
 		StatusCode: hc,
-		Message:    fmt.Sprintf("<%s>", strings.Join(parts, ".")),
-	})
+		Message:    fmt.Sprintf("<%s>", strings.Join(append([]string{typ}, parts...), ".")),
+		Field:      field,
+	}
+}
+
+// stableCode 根据错误分类及 model/field/reason 生成稳定的错误码（小写下划线，以.分隔）
+// 新增错误分类时必须在这里添加对应的规则
+func stableCode(typ string, parts ...string) (code, field string) {
+	switch typ {
+	case invalidParameter:
+		// model.field.reason
+		if len(parts) == 3 {
+			field = snakeCase(parts[1])
+		}
+		return joinCode(parts...), field
+	case notFoundError:
+		return joinCode(append(parts, "NotFound")...), ""
+	case alreadyExists, accessDeniedError:
+		// model.reason
+		return joinCode(parts...), ""
+	case conflict:
+		return joinCode(append(parts, "Conflict")...), ""
+	case moved:
+		return joinCode(append(parts, "Moved")...), ""
+	case permissionError:
+		return joinCode(append([]string{"Permission"}, parts...)...), ""
+	case repositoryError:
+		return joinCode(append([]string{"Repository"}, parts...)...), ""
+	case sqlError:
+		return CodeSQL, ""
+	case unauthorized:
+		return CodeUnauthorized, ""
+	case graphQLError:
+		return CodeGraphQL, ""
+	}
+	return CodeInternal, ""
+}
+
+func joinCode(parts ...string) string {
+	codes := make([]string, 0, len(parts))
+	for _, p := range parts {
+		codes = append(codes, snakeCase(p))
+	}
+	return strings.Join(codes, ".")
+}
+
+// snakeCase NotEqual => not_equal，SSHKey => ssh_key，TOTP => totp
+func snakeCase(s string) string {
+	var sb strings.Builder
+	rs := []rune(s)
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(rs[i-1]) || (i+1 < len(rs) && unicode.IsLower(rs[i+1]) && unicode.IsUpper(rs[i-1]))) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// ToResult 返回 err 对应的 Result；非 Result 类型的错误统一为 internal，避免向客户端暴露内部信息
+func ToResult(err error) *Result {
+	if r, ok := Cause(err).(*Result); ok {
+		return r
+	}
+	return newResult(err, internalError)
 }
 
 // 封装（避免在项目中使用时，引用多个包）
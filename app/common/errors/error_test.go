@@ -0,0 +1,74 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"User":              "user",
+		"NotEqual":          "not_equal",
+		"SSHKey":            "ssh_key",
+		"TOTP":              "totp",
+		"ID":                "id",
+		"UserEmail":         "user_email",
+		"SvcServerNotReady": "svc_server_not_ready",
+		"ConfirmPassword":   "confirm_password",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, snakeCase(in), in)
+	}
+}
+
+func TestResultCode(t *testing.T) {
+	cases := []struct {
+		err    error
+		code   string
+		field  string
+		status int
+	}{
+		{P(User, Password, NotEqual), "user.password.not_equal", "password", 400},
+		{NotFoundError(User), "user.not_found", "", 404},
+		{AlreadyExistsError(User, AlreadyExists), "user.already_exists", "", 409},
+		{AccessDenied(User, Locked), "user.locked", "", 403},
+		{ConflictError(Namespace), "namespace.conflict", "", 409},
+		{MovedError(Namespace, "/new"), "namespace.moved", "", 301},
+		{CooldownError(User, 100), "user.cooldown", "", 403},
+		{PermissionError(NoPermission), "permission.no_permission", "", 403},
+		{RepositoryError(SvcServerNotReady), "repository.svc_server_not_ready", "", 500},
+		{SQLError(New("boom")), CodeSQL, "", 500},
+		{Unauthorize(), CodeUnauthorized, "", 401},
+		{GraphQLError(), CodeGraphQL, "", 400},
+		{New("boom"), CodeInternal, "", 500},
+	}
+	for _, c := range cases {
+		r := ToResult(c.err)
+		assert.Equal(t, c.code, r.Code)
+		assert.Equal(t, c.field, r.Field, c.code)
+		assert.Equal(t, c.status, r.StatusCode, c.code)
+	}
+
+	// 每个错误分类都必须有对应的code规则
+	for typ := range httpCodeSet {
+		code, _ := stableCode(typ, User, Password, Invalid)
+		if typ != internalError {
+			assert.NotEqual(t, CodeInternal, code, typ)
+		}
+	}
+}
+
+func TestCooldownError(t *testing.T) {
+	r := ToResult(CooldownError(User, 1600000000))
+	assert.Equal(t, int64(1600000000), r.RetryAt)
+	assert.Equal(t, "<AccessDeniedError.User.Cooldown>", r.Message)
+	assert.Zero(t, ToResult(AccessDenied(User, Locked)).RetryAt)
+}
+
+func TestMovedLocation(t *testing.T) {
+	loc, ok := MovedLocation(MovedError(Namespace, "/new"))
+	assert.True(t, ok)
+	assert.Equal(t, "/new", loc)
+	assert.Equal(t, "<Moved.Namespace>", ToResult(MovedError(Namespace, "/new")).Message)
+}
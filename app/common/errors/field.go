@@ -11,4 +11,18 @@ const (
 	ConfirmPassword = "ConfirmPassword"
 	Code            = "Code"
 	Path            = "Path"
+	Token           = "Token"
+	ID              = "ID"
+	Role            = "Role"
+	PublicKey       = "PublicKey"
+	Title           = "Title"
+	Scopes          = "Scopes"
+	ExpiresIn       = "ExpiresIn"
+	Identifier      = "Identifier"
+	Body            = "Body" // 请求体（无法解析）
+	PublicEmail     = "PublicEmail"
+	IP              = "IP"
+	IdempotencyKey  = "IdempotencyKey" // Idempotency-Key 请求头
+	Provider        = "Provider"       // 第三方登录（github、google）
+	State           = "State"          // OAuth 的 state
 )
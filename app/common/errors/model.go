@@ -8,4 +8,15 @@ const (
 	ActivationCode = "ActivationCode"
 	Namespace      = "Namespace"
 	Repository     = "Repository"
+	Session        = "Session"
+	PasswordReset  = "PasswordReset"
+	EmailChange    = "EmailChange"
+	Organization   = "Organization"
+	Member         = "Member"
+	TOTP           = "TOTP"
+	SSHKey         = "SSHKey"
+	AccessToken    = "AccessToken"
+	Request        = "Request"
+	UserEmail      = "UserEmail"
+	OAuthIdentity  = "OAuthIdentity"
 )
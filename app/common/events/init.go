@@ -17,6 +17,7 @@ func InitMQ() error {
 	consumers := []mq.Consumer{
 		newEmailConsumer(),
 		newGitEventConsumer(),
+		newUserEventConsumer(),
 	}
 	err := MQ.Register(consumers...)
 	if err != nil {
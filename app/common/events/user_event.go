@@ -0,0 +1,93 @@
+package events
+
+import (
+	"time"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/common/mq"
+	"github.com/growerlab/backend/app/utils/conf"
+)
+
+// 用户生命周期事件
+const (
+	UserRegistered = "user.registered"
+	UserActivated  = "user.activated"
+	UserLoggedIn   = "user.logged_in"
+	UserDeleted    = "user.deleted"
+	// UserEmailChanged 主邮箱已修改（新邮箱验证通过）
+	UserEmailChanged = "user.email_changed"
+)
+
+type UserEvent struct {
+	Type       string `json:"type"`
+	UserID     int64  `json:"user_id"`
+	Username   string `json:"username"`
+	OccurredAt int64  `json:"occurred_at"`
+}
+
+func NewUserEvent(eventType string, userID int64, username string) *UserEvent {
+	return &UserEvent{
+		Type:       eventType,
+		UserID:     userID,
+		Username:   username,
+		OccurredAt: time.Now().Unix(),
+	}
+}
+
+// Bus 事件总线
+// 必须在事务提交之后再调用 Publish，避免订阅者收到被回滚的事件
+type Bus interface {
+	Publish(event *UserEvent) error
+}
+
+// UserBus 默认通过消息队列异步推送到 webhook（见 UserEventConsumer）
+var UserBus Bus = &UserEventConsumer{}
+
+var _ mq.Consumer = (*UserEventConsumer)(nil)
+
+// UserEventConsumer 消费用户事件，推送到配置的 webhook
+type UserEventConsumer struct{}
+
+func newUserEventConsumer() mq.Consumer {
+	return &UserEventConsumer{}
+}
+
+func (*UserEventConsumer) Name() string {
+	return "user_event"
+}
+
+func (*UserEventConsumer) DefaultField() string {
+	return "default"
+}
+
+// Publish 未配置 webhook 时直接忽略
+func (u *UserEventConsumer) Publish(event *UserEvent) error {
+	if webhookFromConf() == nil {
+		return nil
+	}
+	if MQ == nil {
+		return errors.New("message queue is not initialized")
+	}
+	return async(u.Name(), u.DefaultField(), event)
+}
+
+func (u *UserEventConsumer) Consume(payload *mq.Payload) error {
+	event := new(UserEvent)
+	err := getPayload(payload, u.DefaultField(), event)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	hook := webhookFromConf()
+	if hook == nil {
+		return nil
+	}
+	return hook.Deliver(event.Type, event)
+}
+
+func webhookFromConf() *Webhook {
+	c := conf.GetConf()
+	if c == nil || c.Webhook == nil || len(c.Webhook.URL) == 0 {
+		return nil
+	}
+	return NewWebhook(c.Webhook.URL, c.Webhook.Secret)
+}
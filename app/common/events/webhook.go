@@ -0,0 +1,74 @@
+package events
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/growerlab/backend/app/common/errors"
+)
+
+const (
+	WebhookEventHeader     = "X-Growerlab-Event"
+	WebhookSignatureHeader = "X-Growerlab-Signature" // sha256=<hex(HMAC-SHA256(secret, body))>
+
+	webhookTimeout = 10 * time.Second
+)
+
+// Webhook 将事件以JSON的形式POST到指定地址
+type Webhook struct {
+	URL    string
+	Secret string
+
+	client *http.Client
+}
+
+func NewWebhook(url, secret string) *Webhook {
+	return &Webhook{
+		URL:    url,
+		Secret: secret,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Deliver 推送事件，接收方返回非2xx时视为失败
+func (w *Webhook) Deliver(eventType string, event interface{}) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, eventType)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+Sign(w.Secret, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Trace(fmt.Errorf("webhook %s responded with status %d", w.URL, resp.StatusCode))
+	}
+	return nil
+}
+
+// Sign 计算 body 的 HMAC-SHA256 签名（hex）
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
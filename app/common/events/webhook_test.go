@@ -0,0 +1,49 @@
+package events
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookDeliver(t *testing.T) {
+	var received *UserEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, UserRegistered, r.Header.Get(WebhookEventHeader))
+
+		// 接收方使用相同的secret验证签名
+		want := "sha256=" + Sign("secret", body)
+		assert.True(t, hmac.Equal([]byte(want), []byte(r.Header.Get(WebhookSignatureHeader))))
+
+		received = new(UserEvent)
+		assert.Nil(t, json.Unmarshal(body, received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	event := NewUserEvent(UserRegistered, 1, "moli")
+	err := NewWebhook(server.URL, "secret").Deliver(event.Type, event)
+	assert.Nil(t, err)
+	assert.Equal(t, event, received)
+}
+
+func TestWebhookDeliverFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	event := NewUserEvent(UserDeleted, 1, "moli")
+	assert.NotNil(t, NewWebhook(server.URL, "secret").Deliver(event.Type, event))
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		Sign("key", []byte("The quick brown fox jumps over the lazy dog")))
+}
@@ -0,0 +1,126 @@
+package i18n
+
+import (
+	"github.com/growerlab/backend/app/common/errors"
+)
+
+// catalog 各语言的错误信息，未收录的错误保持原有的<xxx>格式
+// 先写具体的 (subject, field, reason)，再写按 reason（或错误分类）的通用信息
+var catalog = map[string]map[Key]string{
+	ZhCN: {
+		{errors.User, errors.Password, errors.NotEqual}:         "密码错误",
+		{errors.User, errors.Password, errors.InvalidLength}:    "密码长度不符合要求",
+		{errors.User, errors.Password, errors.Weak}:             "密码强度太弱",
+		{errors.User, errors.Password, errors.Unchanged}:        "新密码不能与旧密码相同",
+		{errors.User, errors.ConfirmPassword, errors.NotEqual}:  "两次输入的密码不一致",
+		{errors.User, errors.Email, errors.Invalid}:             "邮箱格式不正确",
+		{errors.User, errors.Email, errors.AlreadyExists}:       "邮箱已被使用",
+		{errors.User, errors.Email, errors.Undeliverable}:       "该邮箱无法接收邮件",
+		{errors.User, errors.Username, errors.Invalid}:          "用户名格式不正确",
+		{errors.User, errors.Username, errors.AlreadyExists}:    "用户名已被使用",
+		{errors.User, errors.Username, errors.Reserved}:         "该用户名为系统保留",
+		{errors.User, "", errors.Locked}:                        "账号已被锁定，请稍后再试",
+		{errors.User, "", errors.Suspended}:                     "账号已被停用",
+		{errors.User, "", errors.NotActivated}:                  "账号未激活",
+		{errors.User, "", errors.Cooldown}:                      "修改过于频繁，请在冷却期结束后再试",
+		{errors.User, "", errors.InvalidCredentials}:            "用户名或密码错误",
+		{errors.User, "", errors.TypeNotFound}:                  "用户不存在",
+		{errors.TOTP, errors.Code, errors.NotEqual}:             "两步验证码错误",
+		{errors.TOTP, errors.Code, errors.Used}:                 "该两步验证码已使用，请等待下一个验证码",
+		{errors.Repository, "", errors.TypeConflict}:            "两个账号都拥有仓库，请先处理其中一方的仓库",
+		{errors.Session, "", errors.Empty}:                      "请先登录",
+		{errors.Session, "", errors.Invalid}:                    "登录已失效，请重新登录",
+		{errors.Request, errors.IdempotencyKey, errors.InUse}:   "Idempotency-Key 已被用于其他请求",
+		{errors.User, errors.Password, errors.AlreadyExists}:    "已设置过密码，请使用修改密码",
+		{errors.OAuthIdentity, errors.Provider, errors.Invalid}: "不支持该登录方式",
+		{errors.OAuthIdentity, errors.State, errors.NotEqual}:   "登录请求已失效，请重新发起",
+		{errors.OAuthIdentity, errors.Code, errors.Invalid}:     "第三方授权失败或已过期，请重试",
+		{errors.OAuthIdentity, errors.Email, errors.Empty}:      "第三方账号没有已验证的邮箱",
+		{errors.OAuthIdentity, "", errors.AlreadyExists}:        "该第三方账号已被绑定",
+		{errors.OAuthIdentity, "", errors.TypeNotFound}:         "未绑定该第三方账号",
+		{"", errors.Token, errors.Expired}:                      "链接已过期",
+		{"", errors.Token, errors.Used}:                         "链接已被使用",
+		{"", "", errors.Invalid}:                                "参数不正确",
+		{"", "", errors.InvalidLength}:                          "长度不符合要求",
+		{"", "", errors.Empty}:                                  "不能为空",
+		{"", "", errors.NotEqual}:                               "不匹配",
+		{"", "", errors.Expired}:                                "已过期",
+		{"", "", errors.Used}:                                   "已被使用",
+		{"", "", errors.AlreadyExists}:                          "已存在",
+		{"", "", errors.Unchanged}:                              "未发生变化",
+		{"", "", errors.NoPermission}:                           "没有权限",
+		{"", "", errors.Self}:                                   "不能对自己进行该操作",
+		{"", "", errors.LastOwner}:                              "至少需要保留一位所有者",
+		{"", "", errors.LastAuthMethod}:                         "至少需要保留一种登录方式",
+		{"", "", errors.InUse}:                                  "仍在使用中",
+		{"", "", errors.RateLimited}:                            "请求过于频繁，请稍后再试",
+		{"", "", errors.TooLarge}:                               "内容过大",
+		{"", "", errors.Reserved}:                               "系统保留",
+		{"", "", errors.Deleted}:                                "已被注销的账号占用，请联系管理员",
+		{"", "", errors.TypeNotFound}:                           "内容不存在",
+		{"", "", errors.TypeConflict}:                           "数据已被修改，请刷新后重试",
+		{"", "", errors.TypeMoved}:                              "已迁移",
+		{"", "", errors.TypeUnauthorized}:                       "请先登录",
+		{"", "", errors.TypeSQL}:                                "服务器内部错误",
+		{"", "", errors.TypeInternal}:                           "服务器内部错误",
+		{"", "", errors.SvcServerNotReady}:                      "仓库服务暂不可用",
+	},
+	En: {
+		{errors.User, errors.Password, errors.NotEqual}:         "Wrong password",
+		{errors.User, errors.Password, errors.InvalidLength}:    "Password length is invalid",
+		{errors.User, errors.Password, errors.Weak}:             "Password is too weak",
+		{errors.User, errors.Password, errors.Unchanged}:        "New password must differ from the current one",
+		{errors.User, errors.ConfirmPassword, errors.NotEqual}:  "Passwords do not match",
+		{errors.User, errors.Email, errors.Invalid}:             "Email address is invalid",
+		{errors.User, errors.Email, errors.AlreadyExists}:       "Email address is already in use",
+		{errors.User, errors.Email, errors.Undeliverable}:       "Email address cannot receive mail",
+		{errors.User, errors.Username, errors.Invalid}:          "Username is invalid",
+		{errors.User, errors.Username, errors.AlreadyExists}:    "Username is already taken",
+		{errors.User, errors.Username, errors.Reserved}:         "Username is reserved",
+		{errors.User, "", errors.Locked}:                        "Account is locked, please try again later",
+		{errors.User, "", errors.Suspended}:                     "Account is suspended",
+		{errors.User, "", errors.NotActivated}:                  "Account is not activated",
+		{errors.User, "", errors.Cooldown}:                      "Changed too recently, please try again after the cooldown",
+		{errors.User, "", errors.InvalidCredentials}:            "Incorrect username or password",
+		{errors.User, "", errors.TypeNotFound}:                  "User not found",
+		{errors.TOTP, errors.Code, errors.NotEqual}:             "Wrong two-factor code",
+		{errors.TOTP, errors.Code, errors.Used}:                 "This two-factor code was already used, please wait for the next one",
+		{errors.Repository, "", errors.TypeConflict}:            "Both accounts own repositories, resolve one side first",
+		{errors.Session, "", errors.Empty}:                      "Please sign in",
+		{errors.Session, "", errors.Invalid}:                    "Session expired, please sign in again",
+		{errors.Request, errors.IdempotencyKey, errors.InUse}:   "Idempotency-Key was already used for a different request",
+		{errors.User, errors.Password, errors.AlreadyExists}:    "Password is already set, use change password instead",
+		{errors.OAuthIdentity, errors.Provider, errors.Invalid}: "Sign-in provider is not supported",
+		{errors.OAuthIdentity, errors.State, errors.NotEqual}:   "Sign-in request expired, please start again",
+		{errors.OAuthIdentity, errors.Code, errors.Invalid}:     "Authorization failed or expired, please try again",
+		{errors.OAuthIdentity, errors.Email, errors.Empty}:      "The linked account has no verified email address",
+		{errors.OAuthIdentity, "", errors.AlreadyExists}:        "This account is already linked",
+		{errors.OAuthIdentity, "", errors.TypeNotFound}:         "This account is not linked",
+		{"", errors.Token, errors.Expired}:                      "Link has expired",
+		{"", errors.Token, errors.Used}:                         "Link has already been used",
+		{"", "", errors.Invalid}:                                "Invalid value",
+		{"", "", errors.InvalidLength}:                          "Invalid length",
+		{"", "", errors.Empty}:                                  "Value is required",
+		{"", "", errors.NotEqual}:                               "Value does not match",
+		{"", "", errors.Expired}:                                "Expired",
+		{"", "", errors.Used}:                                   "Already used",
+		{"", "", errors.AlreadyExists}:                          "Already exists",
+		{"", "", errors.Unchanged}:                              "Nothing changed",
+		{"", "", errors.NoPermission}:                           "Permission denied",
+		{"", "", errors.Self}:                                   "You cannot do this to yourself",
+		{"", "", errors.LastOwner}:                              "At least one owner is required",
+		{"", "", errors.LastAuthMethod}:                         "At least one sign-in method is required",
+		{"", "", errors.InUse}:                                  "Still in use",
+		{"", "", errors.RateLimited}:                            "Too many requests, please try again later",
+		{"", "", errors.TooLarge}:                               "Content is too large",
+		{"", "", errors.Reserved}:                               "Reserved",
+		{"", "", errors.Deleted}:                                "Held by a deleted account, please contact an administrator",
+		{"", "", errors.TypeNotFound}:                           "Not found",
+		{"", "", errors.TypeConflict}:                           "Data was modified, please refresh and retry",
+		{"", "", errors.TypeMoved}:                              "Moved",
+		{"", "", errors.TypeUnauthorized}:                       "Please sign in",
+		{"", "", errors.TypeSQL}:                                "Internal server error",
+		{"", "", errors.TypeInternal}:                           "Internal server error",
+		{"", "", errors.SvcServerNotReady}:                      "Repository service is unavailable",
+	},
+}
@@ -0,0 +1,129 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/growerlab/backend/app/common/errors"
+)
+
+// Default 默认语言：不翻译，保持原有的<xxx>格式
+const Default = ""
+
+// 支持的语言
+const (
+	ZhCN = "zh-CN"
+	En   = "en"
+)
+
+// Key 错误信息的索引 (subject, field, reason)，为空表示任意
+type Key struct {
+	Subject string
+	Field   string
+	Reason  string
+}
+
+// Match 根据 Accept-Language 选出支持的语言，没有匹配时返回 Default
+// 例如 "en-US,en;q=0.9,zh-CN;q=0.8" => en
+func Match(acceptLanguage string) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	var tags []tag
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		opts := strings.Split(strings.TrimSpace(item), ";")
+		lang := strings.TrimSpace(opts[0])
+		if len(lang) == 0 {
+			continue
+		}
+		q := 1.0
+		for _, opt := range opts[1:] {
+			opt = strings.TrimSpace(opt)
+			if strings.HasPrefix(opt, "q=") {
+				v, err := strconv.ParseFloat(opt[2:], 64)
+				if err != nil {
+					v = 0
+				}
+				q = v
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, tag{lang: lang, q: q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	for _, t := range tags {
+		if loc := supported(t.lang); loc != Default {
+			return loc
+		}
+	}
+	return Default
+}
+
+// supported 先完全匹配（忽略大小写），再按主语言匹配（例如 zh-TW => zh-CN）
+func supported(lang string) string {
+	base := strings.ToLower(strings.SplitN(lang, "-", 2)[0])
+	var byBase string
+	for loc := range catalog {
+		if strings.EqualFold(loc, lang) {
+			return loc
+		}
+		if strings.ToLower(strings.SplitN(loc, "-", 2)[0]) == base {
+			byBase = loc
+		}
+	}
+	return byBase
+}
+
+// Translate 返回 r 在 locale 下的错误信息
+// 依次查找 (subject, field, reason)、(subject, "", reason)、("", field, reason)、("", "", reason)
+func Translate(locale string, r *errors.Result) (string, bool) {
+	messages, ok := catalog[locale]
+	if !ok || r == nil {
+		return "", false
+	}
+	k := keyOf(r)
+	for _, candidate := range []Key{
+		k,
+		{Subject: k.Subject, Reason: k.Reason},
+		{Field: k.Field, Reason: k.Reason},
+		{Reason: k.Reason},
+	} {
+		if msg, ok := messages[candidate]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// keyOf 解析 Result.Message（<Type.Subject.Field.Reason>）得到索引
+// 没有 reason 的错误分类（例如 NotFound）以分类作为 reason
+func keyOf(r *errors.Result) Key {
+	parts := strings.Split(strings.Trim(r.Message, "<>"), ".")
+	if len(parts) > 0 {
+		parts = parts[1:]
+	}
+	switch r.Type {
+	case errors.TypeInvalidParameter:
+		if len(parts) == 3 {
+			return Key{Subject: parts[0], Field: parts[1], Reason: parts[2]}
+		}
+	case errors.TypeAlreadyExists, errors.TypeAccessDenied:
+		if len(parts) == 2 {
+			return Key{Subject: parts[0], Reason: parts[1]}
+		}
+	case errors.TypeNotFound, errors.TypeConflict, errors.TypeMoved:
+		if len(parts) == 1 {
+			return Key{Subject: parts[0], Reason: r.Type}
+		}
+	case errors.TypePermission, errors.TypeRepository:
+		if len(parts) == 1 {
+			return Key{Reason: parts[0]}
+		}
+	}
+	return Key{Reason: r.Type}
+}
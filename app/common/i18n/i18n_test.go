@@ -0,0 +1,51 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	cases := map[string]string{
+		"":                           Default,
+		"*":                          Default,
+		"fr-FR":                      Default,
+		"en":                         En,
+		"en-US,en;q=0.9,zh-CN;q=0.8": En,
+		"fr;q=0.9,zh-TW;q=0.8":       ZhCN,
+		"en;q=0.5, zh-cn":            ZhCN,
+		"zh-CN;q=0, en;q=0.1":        En,
+	}
+	for header, want := range cases {
+		assert.Equal(t, want, Match(header), header)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	result := func(err error) *errors.Result { return errors.ToResult(err) }
+
+	// 默认语言不翻译
+	_, ok := Translate(Default, result(errors.P(errors.User, errors.Password, errors.NotEqual)))
+	assert.False(t, ok)
+
+	msg, ok := Translate(En, result(errors.P(errors.User, errors.Password, errors.NotEqual)))
+	assert.True(t, ok)
+	assert.Equal(t, "Wrong password", msg)
+
+	msg, _ = Translate(ZhCN, result(errors.AccessDenied(errors.User, errors.Locked)))
+	assert.Equal(t, "账号已被锁定，请稍后再试", msg)
+
+	// 回退到 ("", field, reason) 及按 reason 的通用信息
+	msg, _ = Translate(En, result(errors.P(errors.PasswordReset, errors.Token, errors.Expired)))
+	assert.Equal(t, "Link has expired", msg)
+	msg, _ = Translate(En, result(errors.P(errors.SSHKey, errors.Title, errors.InvalidLength)))
+	assert.Equal(t, "Invalid length", msg)
+	msg, _ = Translate(En, result(errors.NotFoundError(errors.Namespace)))
+	assert.Equal(t, "Not found", msg)
+	msg, _ = Translate(En, result(errors.PermissionError(errors.NoPermission)))
+	assert.Equal(t, "Permission denied", msg)
+	msg, _ = Translate(En, result(errors.New("boom")))
+	assert.Equal(t, "Internal server error", msg)
+}
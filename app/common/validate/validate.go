@@ -0,0 +1,99 @@
+package validate
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/growerlab/backend/app/common/errors"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	"github.com/growerlab/backend/app/utils/regex"
+)
+
+// 用户名、邮箱的校验规则
+// 在查询数据库（例如 ExistsEmailOrUsername）之前调用，格式不合法时直接返回 InvalidParameter
+// 规则参数均可在初始化时调整，调用方无需修改
+
+type UsernameRules struct {
+	MinLen   int
+	MaxLen   int
+	Pattern  *regexp.Regexp         // 允许的字符，同时要求以字母开头
+	Reserved func(name string) bool // 是否为保留字
+}
+
+type EmailRules struct {
+	MaxLen   int
+	CheckMX  bool                                   // 是否检查域名的MX记录
+	LookupMX func(domain string) ([]*net.MX, error) // 可替换，便于测试
+}
+
+// Username 用户名（同时也是命名空间path）的规则，组织名同样适用
+var Username = &UsernameRules{
+	MinLen:   4,
+	MaxLen:   40,
+	Pattern:  regexp.MustCompile(`^[a-z][a-z0-9_-]*$`),
+	Reserved: nsModel.IsReserved,
+}
+
+var Email = &EmailRules{
+	MaxLen:   254,
+	CheckMX:  false,
+	LookupMX: net.LookupMX,
+}
+
+// ValidateUsername 校验用户名的格式
+func ValidateUsername(name string) error {
+	if reason := UsernameReason(name); len(reason) > 0 {
+		return errors.P(errors.User, errors.Username, reason)
+	}
+	return nil
+}
+
+// ValidateEmail 校验邮箱的格式，开启 CheckMX 时还会检查域名能否接收邮件
+func ValidateEmail(email string) error {
+	if reason := EmailReason(email); len(reason) > 0 {
+		return errors.P(errors.User, errors.Email, reason)
+	}
+	return nil
+}
+
+// UsernameReason 返回用户名不合法的原因，合法时返回空字符串
+func UsernameReason(name string) string {
+	return Username.Check(name)
+}
+
+// EmailReason 返回邮箱不合法的原因，合法时返回空字符串
+func EmailReason(email string) string {
+	return Email.Check(email)
+}
+
+func (r *UsernameRules) Check(name string) string {
+	if len(name) < r.MinLen || len(name) > r.MaxLen {
+		return errors.InvalidLength
+	}
+	if !r.Pattern.MatchString(name) {
+		return errors.Invalid
+	}
+	if r.Reserved != nil && r.Reserved(name) {
+		return errors.Reserved
+	}
+	return ""
+}
+
+func (r *EmailRules) Check(email string) string {
+	if len(email) == 0 || len(email) > r.MaxLen {
+		return errors.InvalidLength
+	}
+	// 只检查语法，govalidator.IsEmail 会查询DNS，是否查询由 CheckMX 决定
+	at := strings.LastIndex(email, "@")
+	if at < 1 || at > 64 || !regex.Match(email, regex.EmailRegex) {
+		return errors.Invalid
+	}
+	if r.CheckMX {
+		mx, err := r.LookupMX(email[at+1:])
+		if err != nil || len(mx) == 0 {
+			return errors.Undeliverable
+		}
+	}
+	return ""
+}
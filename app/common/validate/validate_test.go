@@ -0,0 +1,52 @@
+package validate
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsernameReason(t *testing.T) {
+	assert.Equal(t, "", UsernameReason("moli"))
+	assert.Equal(t, "", UsernameReason("mo-li_2"))
+	assert.Equal(t, errors.InvalidLength, UsernameReason("abc"))
+	assert.Equal(t, errors.InvalidLength, UsernameReason(strings.Repeat("a", Username.MaxLen+1)))
+	assert.Equal(t, errors.Invalid, UsernameReason("mo li"))
+	assert.Equal(t, errors.Invalid, UsernameReason("Moli"))
+	assert.Equal(t, errors.Invalid, UsernameReason("2moli"))
+	assert.Equal(t, errors.Invalid, UsernameReason("_moli"))
+	assert.Equal(t, errors.Reserved, UsernameReason("admin"))
+}
+
+func TestEmailReason(t *testing.T) {
+	assert.Equal(t, "", EmailReason("moli@example.com"))
+	assert.Equal(t, errors.InvalidLength, EmailReason(""))
+	assert.Equal(t, "", EmailReason("mo.li+git@mail.growerlab.net"))
+	assert.Equal(t, errors.Invalid, EmailReason("moli.example.com"))
+	assert.Equal(t, errors.Invalid, EmailReason("moli@localhost"))
+	assert.Equal(t, errors.Invalid, EmailReason("mo li@example.com"))
+	assert.Equal(t, errors.Invalid, EmailReason("@example.com"))
+
+	rules := &EmailRules{
+		MaxLen:  254,
+		CheckMX: true,
+		LookupMX: func(domain string) ([]*net.MX, error) {
+			if domain == "example.com" {
+				return []*net.MX{{Host: "mx.example.com"}}, nil
+			}
+			return nil, &net.DNSError{Err: "no such host", Name: domain}
+		},
+	}
+	assert.Equal(t, "", rules.Check("moli@example.com"))
+	assert.Equal(t, errors.Undeliverable, rules.Check("moli@nowhere.org"))
+}
+
+func TestValidateUsername(t *testing.T) {
+	assert.Nil(t, ValidateUsername("moli"))
+	assert.Equal(t,
+		errors.P(errors.User, errors.Username, errors.Reserved).Error(),
+		ValidateUsername("admin").Error())
+}
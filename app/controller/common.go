@@ -1,18 +1,50 @@
 package controller
 
 import (
+	stderrors "errors"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/growerlab/backend/app/utils/logger"
 
 	"github.com/gin-gonic/gin"
 	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/common/i18n"
+	"github.com/growerlab/backend/app/model/db"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/growerlab/backend/app/service/user"
+	"github.com/growerlab/backend/app/utils/clientip"
+	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/growerlab/backend/app/utils/uuid"
 )
 
 const (
 	MaxGraphQLRequestBody = int64(1 << 20) // 1MB
+
+	RequestIDHeader = "X-Request-ID"
+	requestIDMaxLen = 64
+
+	// IdempotencyKeyHeader 客户端重试时携带相同的值，避免重复执行（目前用于注册）
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	ctxLocaleKey = "locale"
 )
 
+// RequestID 为每个请求分配request id（客户端或上游代理已提供时沿用），并写入响应头
+// 之后通过 logger.Ctx 输出的日志均带有 request_id
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(RequestIDHeader)
+	if len(id) == 0 || len(id) > requestIDMaxLen {
+		id = uuid.UUID()
+	}
+	c.Header(RequestIDHeader, id)
+	logger.AddCtxFields(c, logger.Fields{logger.FieldRequestID: id})
+	c.Next()
+}
+
 func LimitGETRequestBody(ctx *gin.Context) {
 	if ctx.Request.Method != http.MethodGet {
 		return
@@ -23,20 +55,64 @@ func LimitGETRequestBody(ctx *gin.Context) {
 	}
 }
 
-func Render(c *gin.Context, payload interface{}, err error) {
-	if err != nil {
-		cerr := errors.Cause(err)
-		if e, ok := cerr.(*errors.Result); ok {
-			c.AbortWithStatusJSON(e.StatusCode, cerr)
+var errBodyTooLarge = stderrors.New("request body too large")
+
+// LimitRequestBody 限制请求体的大小，超出 max 字节时返回 InvalidParameter，max<=0 时不限制
+// Content-Length 超出时直接拒绝；未声明长度（chunked）的请求在读取（bind）时检查，避免解析JSON时分配过多内存
+func LimitRequestBody(max int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if max <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > max {
+			Render(c, nil, errors.P(errors.Request, errors.Body, errors.TooLarge))
+			return
+		}
+		c.Request.Body = &limitedBody{ReadCloser: c.Request.Body, remaining: max}
+		c.Next()
+	}
+}
+
+// limitedBody 与 http.MaxBytesReader 类似，但超出时返回 errBodyTooLarge，便于 bind 区分
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// 多读1个字节，判断是否刚好读完
+		var one [1]byte
+		n, err := b.ReadCloser.Read(one[:])
+		if n > 0 {
+			return 0, errBodyTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
 
-			if e2 := errors.Cause(e.Err); e2 != nil {
-				logger.Error("render2: %+v\n", e2)
-			}
-		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, cerr)
+// bind 根据 Content-Type 解析JSON或表单（application/x-www-form-urlencoded、multipart/form-data）
+// 无法解析时返回 InvalidParameter
+func bind(c *gin.Context, obj interface{}) error {
+	if err := c.ShouldBind(obj); err != nil {
+		if stderrors.Is(err, errBodyTooLarge) {
+			return errors.P(errors.Request, errors.Body, errors.TooLarge)
 		}
-		logger.Error("render: %+v\n", err)
+		return errors.Wrap(errors.P(errors.Request, errors.Body, errors.Invalid), err.Error())
+	}
+	return nil
+}
 
+func Render(c *gin.Context, payload interface{}, err error) {
+	if err != nil {
+		renderError(c, err)
 		return
 	}
 	if payload != nil {
@@ -45,10 +121,156 @@ func Render(c *gin.Context, payload interface{}, err error) {
 	}
 
 	c.AbortWithStatusJSON(http.StatusOK, &errors.Result{
-		Code: "ok",
+		Code: errors.CodeOK,
 	})
 }
 
+// renderError 统一输出错误结构 {code, message, field}
+func renderError(c *gin.Context, err error) {
+	if stderrors.Is(err, errBodyTooLarge) {
+		// c.BindJSON 直接返回读取请求体时的错误
+		err = errors.P(errors.Request, errors.Body, errors.TooLarge)
+	}
+	e := errors.ToResult(err)
+	if e.RetryAt > 0 {
+		if wait := e.RetryAt - time.Now().Unix(); wait > 0 {
+			c.Header("Retry-After", strconv.FormatInt(wait, 10))
+		}
+	}
+	if msg, ok := i18n.Translate(c.GetString(ctxLocaleKey), e); ok {
+		localized := *e
+		localized.Message = msg
+		c.AbortWithStatusJSON(e.StatusCode, &localized)
+	} else {
+		c.AbortWithStatusJSON(e.StatusCode, e)
+	}
+
+	if e2 := errors.Cause(e.Err); e2 != nil {
+		logger.Ctx(c).Error("render2: %+v", e2)
+	}
+	logger.Ctx(c).Error("render: %+v", err)
+}
+
+// Locale 根据 Accept-Language 选择错误信息的语言，没有匹配时保持原有的<xxx>格式
+func Locale(c *gin.Context) {
+	c.Set(ctxLocaleKey, i18n.Match(c.GetHeader("Accept-Language")))
+	c.Next()
+}
+
+// ErrorHandler 处理通过 c.Error 记录、但handler未输出响应的错误
+func ErrorHandler(c *gin.Context) {
+	c.Next()
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+	renderError(c, c.Errors.Last().Err)
+}
+
+// RefreshSession 滑动延长当前用户session的过期时间
+// 失败时仅记录日志，不影响当前请求
+func RefreshSession(c *gin.Context) {
+	if err := user.RefreshSession(c); err != nil {
+		logger.Error("refresh session: %+v\n", err)
+	}
+	c.Next()
+}
+
+// AuthRequired 从token（cookie或header）中解析当前用户，并保存到context中
+// 之后的handler可以通过 session.CurrentUser 获取，token不存在或无效时终止请求
+// 需要当前用户命名空间（path）的路由可以传入 userModel.FillNamespace()，避免之后再查询一次
+func AuthRequired(opts ...userModel.TokenOption) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := session.GetUserToken(c)
+		if len(token) == 0 {
+			Render(c, nil, errors.AccessDenied(errors.Session, errors.Empty))
+			return
+		}
+		u, err := userModel.GetUserByUserTokenFromIPContext(c.Request.Context(), db.DB, token, clientip.FromRequest(c.Request), opts...)
+		if err != nil {
+			Render(c, nil, err)
+			return
+		}
+		if u == nil {
+			Render(c, nil, errors.AccessDenied(errors.Session, errors.Invalid))
+			return
+		}
+		session.SetCurrentUser(c, u)
+		logger.AddCtxFields(c, logger.Fields{logger.FieldUserID: u.ID})
+		c.Next()
+	}
+}
+
+// AuthRequiredWithScopes 同 AuthRequired，但同时接受个人访问令牌（API、git over https等非浏览器客户端）
+// 令牌必须包含所有的 scopes，已撤销、已过期的令牌不能认证
+// 只用于令牌可以访问的路由；账号设置等路由使用 AuthRequired，只接受session
+func AuthRequiredWithScopes(scopes ...string) gin.HandlerFunc {
+	sessionAuth := AuthRequired()
+	return func(c *gin.Context) {
+		token := session.GetUserToken(c)
+		if !user.IsAccessToken(token) {
+			sessionAuth(c)
+			return
+		}
+		u, err := user.AuthenticateAccessToken(token, scopes...)
+		if err != nil {
+			Render(c, nil, err)
+			return
+		}
+		if u == nil {
+			Render(c, nil, errors.AccessDenied(errors.AccessToken, errors.Invalid))
+			return
+		}
+		session.SetCurrentUser(c, u)
+		logger.AddCtxFields(c, logger.Fields{logger.FieldUserID: u.ID})
+		c.Next()
+	}
+}
+
+// RequireRole 当前用户必须拥有 roles 中的任意一个角色（super_admin 拥有所有角色），需要在 AuthRequired 之后使用
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, err := session.CurrentUser(c)
+		if err != nil {
+			Render(c, nil, err)
+			return
+		}
+		if !u.HasRole(roles...) {
+			Render(c, nil, errors.AccessDenied(errors.User, errors.NoPermission))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RejectImpersonated 不允许管理员代登录的session访问，需要在 AuthRequired 之后使用
+// 用于创建凭证、修改账号安全设置的路由
+func RejectImpersonated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := user.RejectImpersonated(c); err != nil {
+			Render(c, nil, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireVerified 当前用户必须已验证邮箱（激活），需要在 AuthRequired 之后使用
+// 配置了 account.staff_skip_verified 时，管理后台的用户不受限制
+func RequireVerified() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, err := session.CurrentUser(c)
+		if err != nil {
+			Render(c, nil, err)
+			return
+		}
+		if !u.Verified() && !(u.IsStaff() && conf.GetConf().GetAccount().StaffSkipVerified) {
+			Render(c, nil, errors.AccessDenied(errors.User, errors.NotActivated))
+			return
+		}
+		c.Next()
+	}
+}
+
 // CORSForLocal 处理本地访问的CORS
 func CORSForLocal(c *gin.Context) {
 	// if !conf.GetConf().Debug {
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/model/accesstoken"
+	"github.com/growerlab/backend/app/model/db"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/growerlab/backend/app/utils/secret"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver 按表返回固定数据的数据库驱动，使中间件到model的整个认证过程可以在没有MySQL的情况下测试
+type fakeDriver struct {
+	mu     sync.Mutex
+	tables map[string]func(args []driver.Value) map[string]driver.Value
+	execs  []string
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{d: c.d, query: query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.execs = append(s.d.execs, s.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	selected := s.query[len("SELECT "):strings.Index(s.query, " FROM ")]
+	columns := strings.Split(selected, ", ")
+	for i, c := range columns {
+		columns[i] = strings.Trim(c[strings.LastIndex(c, ".")+1:], "`")
+	}
+
+	rows := &fakeRows{columns: columns}
+	for table, find := range s.d.tables {
+		if !strings.Contains(s.query, "FROM "+table+" ") {
+			continue
+		}
+		if row := find(args); row != nil {
+			values := make([]driver.Value, len(columns))
+			for i, c := range columns {
+				values[i] = row[c]
+			}
+			rows.values = append(rows.values, values)
+		}
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestAuthRequiredWithScopes(t *testing.T) {
+	tokens := map[string]map[string]driver.Value{
+		"glp_valid":   {"id": int64(1), "scopes": "repo:read,user"},
+		"glp_revoked": {"id": int64(2), "scopes": "user", "revoked_at": int64(1000)},
+		"glp_expired": {"id": int64(3), "scopes": "user", "expired_at": int64(1000)},
+	}
+	tokenRows := make(map[string]map[string]driver.Value)
+	for token, row := range tokens {
+		row["user_id"], row["name"], row["token_hash"], row["created_at"] = int64(7), "cli", secret.HashToken(token), int64(1)
+		tokenRows[secret.HashToken(token)] = row
+	}
+	fake := &fakeDriver{tables: map[string]func(args []driver.Value) map[string]driver.Value{
+		accesstoken.TableName: func(args []driver.Value) map[string]driver.Value {
+			return tokenRows[args[0].(string)]
+		},
+		"`user`": func(args []driver.Value) map[string]driver.Value {
+			if args[0] != int64(7) {
+				return nil
+			}
+			return map[string]driver.Value{
+				"id": int64(7), "email": "moli@example.com", "encrypted_password": "", "username": "moli",
+				"name": "moli", "public_email": "", "created_at": int64(1), "register_ip": "", "is_admin": false,
+				"namespace_id": int64(1), "failed_login_count": int64(0), "normalized_email": "moli@example.com",
+				"version": int64(0), "roles": "",
+			}
+		},
+	}}
+	sql.Register("fake-access-token", fake)
+	conn, err := sql.Open("fake-access-token", "")
+	assert.Nil(t, err)
+
+	old := db.DB
+	db.DB = &db.DBQuery{Ext: sqlx.NewDb(conn, "mysql")}
+	defer func() { db.DB = old }()
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/user", AuthRequiredWithScopes(accesstoken.ScopeUser), func(c *gin.Context) {
+		u, err := session.CurrentUser(c)
+		if err != nil {
+			Render(c, nil, err)
+			return
+		}
+		c.String(http.StatusOK, u.Username)
+	})
+	engine.GET("/repo", AuthRequiredWithScopes(accesstoken.ScopeRepoWrite), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	request := func(path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("/user", "glp_valid")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "moli", w.Body.String())
+	assert.Len(t, fake.execs, 1) // last_used_at
+
+	for _, token := range []string{"glp_revoked", "glp_expired", "glp_unknown"} {
+		w = request("/user", token)
+		assert.Equal(t, http.StatusForbidden, w.Code, token)
+	}
+	// 缺少权限
+	w = request("/repo", "glp_valid")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Len(t, fake.execs, 1)
+}
@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/service/health"
+)
+
+// Liveness 存活探针
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Liveness())
+}
+
+// Readiness 就绪探针，依赖不可用时返回503
+func Readiness(c *gin.Context) {
+	status := health.Readiness(c.Request.Context())
+	code := http.StatusOK
+	if !status.OK() {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, status)
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/service/namespace"
+)
+
+func CheckNamespaceAvailable(c *gin.Context) {
+	result, err := namespace.CheckNamespaceAvailable(c.Query("path"))
+	Render(c, result, err)
+}
+
+func ReservedNamespaces(c *gin.Context) {
+	Render(c, namespace.ReservedPaths(), nil)
+}
+
+func RenameNamespace(c *gin.Context) {
+	var req namespace.RenamePayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := namespace.Rename(c, req.NamespaceID, req.Path)
+	Render(c, nil, err)
+}
+
+func TransferNamespace(c *gin.Context) {
+	var req namespace.TransferPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := namespace.TransferNamespace(c, req.NamespaceID, req.UserID)
+	Render(c, nil, err)
+}
+
+// renderNamespace 同 Render，命名空间已改名（MovedError）时，Location 指向将旧path替换为新path后的地址
+func renderNamespace(c *gin.Context, payload interface{}, err error) {
+	if location, ok := errors.MovedLocation(err); ok {
+		old := "/" + c.Param("namespace") + "/"
+		u := *c.Request.URL
+		u.Path = strings.Replace(u.Path, old, "/"+location+"/", 1)
+		c.Header("Location", u.RequestURI())
+	}
+	Render(c, payload, err)
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	"github.com/growerlab/backend/app/service/org"
+)
+
+func CreateOrganization(c *gin.Context) {
+	var req org.CreateOrganizationPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	ns, err := org.CreateOrganization(c, req.Path)
+	Render(c, ns, err)
+}
+
+func AddOrganizationMember(c *gin.Context) {
+	var req org.AddMemberPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := org.AddMember(c, req.Path, req.Username, nsModel.MemberRole(req.Role))
+	Render(c, nil, err)
+}
+
+func RemoveOrganizationMember(c *gin.Context) {
+	var req org.RemoveMemberPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := org.RemoveMember(c, req.Path, req.Username)
+	Render(c, nil, err)
+}
+
+func OrganizationMembers(c *gin.Context) {
+	path := c.Param("path")
+	members, err := org.ListMembers(c, path)
+	Render(c, members, err)
+}
@@ -8,7 +8,7 @@ import (
 func Repositories(c *gin.Context) {
 	namespace := c.Param("namespace")
 	repos, err := repository.ListRepositories(c, namespace)
-	Render(c, repos, err)
+	renderNamespace(c, repos, err)
 }
 
 func Repository(c *gin.Context) {
@@ -16,7 +16,7 @@ func Repository(c *gin.Context) {
 	name := c.Param("name")
 
 	repo, err := repository.GetRepository(c, namespace, name)
-	Render(c, repo, err)
+	renderNamespace(c, repo, err)
 }
 
 func CreateRepository(c *gin.Context) {
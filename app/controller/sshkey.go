@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/service/sshkey"
+)
+
+func ListSSHKeys(c *gin.Context) {
+	page, _ := strconv.ParseUint(c.Query("page"), 10, 64)
+	per, _ := strconv.ParseUint(c.Query("per"), 10, 64)
+	keys, err := sshkey.ListSSHKeys(c, page, per)
+	Render(c, keys, err)
+}
+
+func AddSSHKey(c *gin.Context) {
+	var req sshkey.AddSSHKeyPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	key, err := sshkey.AddSSHKey(c, req.Title, req.PublicKey)
+	Render(c, key, err)
+}
+
+func DeleteSSHKey(c *gin.Context) {
+	var req sshkey.DeleteSSHKeyPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := sshkey.DeleteSSHKey(c, req.ID)
+	Render(c, nil, err)
+}
@@ -1,9 +1,15 @@
 package controller
 
 import (
+	"net/http"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 
+	"github.com/growerlab/backend/app/common/errors"
+	userModel "github.com/growerlab/backend/app/model/user"
 	"github.com/growerlab/backend/app/service/user"
+	"github.com/growerlab/backend/app/utils/clientip"
 )
 
 func RegisterUser(c *gin.Context) {
@@ -12,10 +18,11 @@ func RegisterUser(c *gin.Context) {
 		Render(c, nil, err)
 		return
 	}
+	req.IdempotencyKey = c.GetHeader(IdempotencyKeyHeader)
 
-	clientIP := c.ClientIP()
-	err := user.Register(&req, clientIP)
-	Render(c, nil, err)
+	clientIP := clientip.FromRequest(c.Request)
+	result, err := user.RegisterContext(c.Request.Context(), &req, clientIP)
+	Render(c, result, err)
 }
 
 func ActivateUser(c *gin.Context) {
@@ -28,12 +35,443 @@ func ActivateUser(c *gin.Context) {
 	Render(c, nil, err)
 }
 
+func ResendActivation(c *gin.Context) {
+	var req user.ResendActivationPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.ResendActivation(c, req.Email)
+	Render(c, nil, err)
+}
+
+func RequestPasswordReset(c *gin.Context) {
+	var req user.PasswordResetPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.RequestPasswordReset(c, req.Email)
+	Render(c, nil, err)
+}
+
+func ConfirmPasswordReset(c *gin.Context) {
+	var req user.PasswordResetConfirmPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.ConfirmPasswordReset(c, req.Token, req.Password)
+	Render(c, nil, err)
+}
+
+func ChangePassword(c *gin.Context) {
+	var req user.ChangePasswordPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	result, err := user.ChangePassword(c, req.OldPassword, req.NewPassword)
+	Render(c, result, err)
+}
+
+func DeleteAccount(c *gin.Context) {
+	var req user.DeleteAccountPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.DeleteAccount(c, req.Password)
+	Render(c, nil, err)
+}
+
+func ListMySessions(c *gin.Context) {
+	page, _ := strconv.ParseUint(c.Query("page"), 10, 64)
+	per, _ := strconv.ParseUint(c.Query("per"), 10, 64)
+	result, err := user.ListMySessions(c, page, per)
+	Render(c, result, err)
+}
+
+func RevokeSession(c *gin.Context) {
+	var req user.RevokeSessionPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	result, err := user.RevokeSession(c, req.ID)
+	Render(c, result, err)
+}
+
+func ChangeUsername(c *gin.Context) {
+	var req user.ChangeUsernamePayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.ChangeUsername(c, req.Username)
+	Render(c, nil, err)
+}
+
+func ChangeEmail(c *gin.Context) {
+	var req user.ChangeEmailPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.ChangeEmail(c, req.Email, req.Password)
+	Render(c, nil, err)
+}
+
+func ConfirmEmailChange(c *gin.Context) {
+	var req user.ConfirmEmailChangePayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.ConfirmEmailChange(c, req.Token)
+	Render(c, nil, err)
+}
+
+func RevertEmailChange(c *gin.Context) {
+	var req user.RevertEmailChangePayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.RevertEmailChange(c, req.Token)
+	Render(c, nil, err)
+}
+
+func ListEmails(c *gin.Context) {
+	emails, err := user.ListEmails(c)
+	Render(c, emails, err)
+}
+
+func AddEmail(c *gin.Context) {
+	var req user.AddEmailPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.AddEmail(c, req.Email)
+	Render(c, nil, err)
+}
+
+func VerifyEmail(c *gin.Context) {
+	var req user.VerifyEmailPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.VerifyEmail(req.Token)
+	Render(c, nil, err)
+}
+
+func SetPrimaryEmail(c *gin.Context) {
+	var req user.EmailIDPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.SetPrimaryEmail(c, req.ID)
+	Render(c, nil, err)
+}
+
+func DeleteEmail(c *gin.Context) {
+	var req user.EmailIDPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.DeleteEmail(c, req.ID)
+	Render(c, nil, err)
+}
+
+func SearchUsers(c *gin.Context) {
+	var limit uint64
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.ParseUint(l, 10, 64)
+	}
+	withEmail, _ := strconv.ParseBool(c.Query("email"))
+	users, err := user.SearchUsers(c, c.Query("q"), limit, withEmail)
+	Render(c, users, err)
+}
+
+func UserProfile(c *gin.Context) {
+	profile, err := user.GetPublicProfile(c.Param("username"))
+	Render(c, profile, err)
+}
+
+func UpdateProfile(c *gin.Context) {
+	var req user.UpdateProfilePayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	result, err := user.UpdateProfile(c, &req)
+	Render(c, result, err)
+}
+
+func AdminImportUsers(c *gin.Context) {
+	var req user.ImportUsersPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	result, err := user.ImportUsers(c, &req)
+	Render(c, result, err)
+}
+
+func AdminListUsers(c *gin.Context) {
+	page, _ := strconv.ParseUint(c.Query("page"), 10, 64)
+	per, _ := strconv.ParseUint(c.Query("per"), 10, 64)
+	filter := userModel.UserFilter{
+		Verified:     queryBool(c, "verified"),
+		IsAdmin:      queryBool(c, "is_admin"),
+		CreatedFrom:  queryInt64(c, "created_from"),
+		CreatedUntil: queryInt64(c, "created_until"),
+	}
+	result, err := user.AdminListUsers(c, filter, page, per)
+	Render(c, result, err)
+}
+
+func AdminFindUsersByIP(c *gin.Context) {
+	page, _ := strconv.ParseUint(c.Query("page"), 10, 64)
+	per, _ := strconv.ParseUint(c.Query("per"), 10, 64)
+	result, err := user.AdminFindUsersByIP(c, c.Query("ip"), page, per)
+	Render(c, result, err)
+}
+
+func AdminGetUser(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		Render(c, nil, errors.P(errors.User, errors.ID, errors.Invalid))
+		return
+	}
+	result, err := user.AdminGetUser(c, id)
+	Render(c, result, err)
+}
+
+// queryBool、queryInt64 参数不存在或无法解析时返回nil（不筛选）
+func queryBool(c *gin.Context, key string) *bool {
+	v, err := strconv.ParseBool(c.Query(key))
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+func queryInt64(c *gin.Context, key string) *int64 {
+	v, err := strconv.ParseInt(c.Query(key), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+func DeactivateUser(c *gin.Context) {
+	var req user.SetUserActivePayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.DeactivateUser(c, req.UserID)
+	Render(c, nil, err)
+}
+
+func SetUserRoles(c *gin.Context) {
+	var req user.SetUserRolesPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.SetUserRoles(c, &req)
+	Render(c, nil, err)
+}
+
+func RestoreUser(c *gin.Context) {
+	var req user.SetUserActivePayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.RestoreUser(c, req.UserID)
+	Render(c, nil, err)
+}
+
+func HardDeleteUser(c *gin.Context) {
+	var req user.HardDeleteUserPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	result, err := user.HardDeleteUser(c, req.UserID, req.DryRun)
+	Render(c, result, err)
+}
+
+func AdminResetUsernameCooldown(c *gin.Context) {
+	var req user.ResetUsernameCooldownPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.AdminResetUsernameCooldown(c, req.UserID)
+	Render(c, nil, err)
+}
+
+func AdminFindDuplicateAccounts(c *gin.Context) {
+	result, err := user.AdminFindDuplicateAccounts(c)
+	Render(c, result, err)
+}
+
+func MergeAccounts(c *gin.Context) {
+	var req user.MergeAccountsPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	result, err := user.MergeAccounts(c, req.KeepUserID, req.MergeUserID)
+	Render(c, result, err)
+}
+
+func ImpersonateUser(c *gin.Context) {
+	var req user.ImpersonatePayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	result, err := user.Impersonate(c, req.UserID)
+	Render(c, result, err)
+}
+
+func ReactivateUser(c *gin.Context) {
+	var req user.SetUserActivePayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.ReactivateUser(c, req.UserID)
+	Render(c, nil, err)
+}
+
+func EnrollTOTP(c *gin.Context) {
+	result, err := user.EnrollTOTP(c)
+	Render(c, result, err)
+}
+
+func ConfirmTOTP(c *gin.Context) {
+	var req user.ConfirmTOTPPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	result, err := user.ConfirmTOTP(c, req.Code)
+	Render(c, result, err)
+}
+
+func ListAccessTokens(c *gin.Context) {
+	tokens, err := user.ListAccessTokens(c)
+	Render(c, tokens, err)
+}
+
+func CreateAccessToken(c *gin.Context) {
+	var req user.CreateAccessTokenPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	result, err := user.CreateAccessToken(c, &req)
+	Render(c, result, err)
+}
+
+func RevokeAccessToken(c *gin.Context) {
+	var req user.RevokeAccessTokenPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.RevokeAccessToken(c, req.ID)
+	Render(c, nil, err)
+}
+
+func ListLoginHistory(c *gin.Context) {
+	limit, _ := strconv.ParseUint(c.Query("limit"), 10, 64)
+	result, err := user.ListLoginHistory(c, limit)
+	Render(c, result, err)
+}
+
+// ExportUserData 以附件形式下载当前用户的数据
+func ExportUserData(c *gin.Context) {
+	data, err := user.ExportUserData(c)
+	if err != nil {
+		Render(c, nil, err)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="growerlab-export.json"`)
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
 func LoginUser(c *gin.Context) {
 	var input user.LoginBasicAuth
-	if err := c.BindJSON(&input); err != nil {
+	if err := bind(c, &input); err != nil {
 		Render(c, nil, err)
 		return
 	}
 	result, err := user.Login(c, &input)
 	Render(c, result, err)
 }
+
+func LogoutUser(c *gin.Context) {
+	err := user.Logout(c)
+	Render(c, nil, err)
+}
+
+func LogoutAllUser(c *gin.Context) {
+	result, err := user.LogoutAll(c)
+	Render(c, result, err)
+}
+
+func OAuthAuthorize(c *gin.Context) {
+	result, err := user.OAuthAuthorize(c, c.Param("provider"))
+	Render(c, result, err)
+}
+
+func OAuthCallback(c *gin.Context) {
+	var req user.OAuthCallbackPayload
+	if err := bind(c, &req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	result, err := user.HandleOAuthCallback(c, c.Param("provider"), &req)
+	Render(c, result, err)
+}
+
+func ListOAuthIdentities(c *gin.Context) {
+	result, err := user.ListOAuthIdentities(c)
+	Render(c, result, err)
+}
+
+func LinkOAuthIdentity(c *gin.Context) {
+	var req user.OAuthCallbackPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	result, err := user.LinkOAuthIdentity(c, c.Param("provider"), &req)
+	Render(c, result, err)
+}
+
+func UnlinkOAuthIdentity(c *gin.Context) {
+	err := user.UnlinkOAuthIdentity(c, c.Param("provider"))
+	Render(c, nil, err)
+}
+
+func SetPassword(c *gin.Context) {
+	var req user.SetPasswordPayload
+	if err := c.BindJSON(&req); err != nil {
+		Render(c, nil, err)
+		return
+	}
+	err := user.SetPassword(c, req.Password)
+	Render(c, nil, err)
+}
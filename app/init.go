@@ -7,7 +7,12 @@ import (
 	"github.com/growerlab/backend/app/common/notify"
 	"github.com/growerlab/backend/app/common/permission"
 	"github.com/growerlab/backend/app/model/db"
+	"github.com/growerlab/backend/app/service/cleanup"
+	nsSvc "github.com/growerlab/backend/app/service/namespace"
+	userSvc "github.com/growerlab/backend/app/service/user"
+	"github.com/growerlab/backend/app/utils/clientip"
 	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/growerlab/backend/app/utils/pwd"
 )
 
 // 需要初始化的全局数据放在这里
@@ -15,11 +20,17 @@ import (
 //
 func init() {
 	onStart(conf.LoadConfig)
+	onStart(pwd.InitPassword)
+	onStart(nsSvc.InitReserved)
+	onStart(userSvc.InitConfig)
+	onStart(clientip.InitClientIP)
 	onStart(db.InitMemDB)
 	onStart(db.InitDatabase)
+	onStart(db.MigrateOnStart)
 	onStart(notify.InitNotify)
 	onStart(permission.InitPermission)
 	onStart(events.InitMQ)
+	onStart(cleanup.Start)
 }
 
 func onStart(fn func() error) {
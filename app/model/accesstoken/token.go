@@ -0,0 +1,116 @@
+package accesstoken
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+const TableName = "personal_access_token"
+
+var columns = []string{
+	"id",
+	"user_id",
+	"name",
+	"token_hash",
+	"scopes",
+	"created_at",
+	"expired_at",
+	"last_used_at",
+	"revoked_at",
+}
+
+func AddAccessToken(tx sqlx.Execer, t *AccessToken) error {
+	t.CreatedAt = time.Now().Unix()
+
+	sql, args, _ := sq.Insert(TableName).
+		Columns(columns[1:]...).
+		Values(
+			t.UserID,
+			t.Name,
+			t.TokenHash,
+			t.Scopes,
+			t.CreatedAt,
+			t.ExpiredAt,
+			nil,
+			nil,
+		).ToSql()
+
+	ret, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	t.ID, err = ret.LastInsertId()
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+func GetAccessToken(src sqlx.Queryer, id int64) (*AccessToken, error) {
+	return getAccessToken(src, sq.Eq{"id": id})
+}
+
+// GetAccessTokenByHash 不检查是否撤销、过期，调用者需使用 Valid 判断
+func GetAccessTokenByHash(src sqlx.Queryer, tokenHash string) (*AccessToken, error) {
+	return getAccessToken(src, sq.Eq{"token_hash": tokenHash})
+}
+
+// ListAccessTokensByUser 用户未撤销的token
+func ListAccessTokensByUser(src sqlx.Queryer, userID int64) ([]*AccessToken, error) {
+	return listAccessTokensByCond(src, sq.Eq{"user_id": userID, "revoked_at": nil})
+}
+
+func RevokeAccessToken(tx sqlx.Execer, id int64) error {
+	sql, args, _ := sq.Update(TableName).
+		Set("revoked_at", time.Now().Unix()).
+		Where(sq.Eq{"id": id, "revoked_at": nil}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+func UpdateLastUsed(tx sqlx.Execer, id int64) error {
+	sql, args, _ := sq.Update(TableName).
+		Set("last_used_at", time.Now().Unix()).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+func getAccessToken(src sqlx.Queryer, cond sq.Sqlizer) (*AccessToken, error) {
+	tokens, err := listAccessTokensByCond(src, cond)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) > 0 {
+		return tokens[0], nil
+	}
+	return nil, nil
+}
+
+func listAccessTokensByCond(src sqlx.Queryer, cond sq.Sqlizer) ([]*AccessToken, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(TableName).
+		Where(cond).
+		OrderBy("id DESC").
+		ToSql()
+
+	result := make([]*AccessToken, 0)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return result, nil
+}
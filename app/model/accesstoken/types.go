@@ -0,0 +1,59 @@
+package accesstoken
+
+import "strings"
+
+// 权限范围
+const (
+	ScopeRepoRead  = "repo:read"
+	ScopeRepoWrite = "repo:write"
+	ScopeUser      = "user"
+)
+
+var ScopeSet = map[string]struct{}{
+	ScopeRepoRead:  {},
+	ScopeRepoWrite: {},
+	ScopeUser:      {},
+}
+
+// AccessToken 个人访问令牌，用于API、git over https等非浏览器客户端
+// 数据库中只保存token的hash
+type AccessToken struct {
+	ID         int64  `db:"id"`
+	UserID     int64  `db:"user_id"`
+	Name       string `db:"name"`
+	TokenHash  string `db:"token_hash"`
+	Scopes     string `db:"scopes"` // 以逗号分隔
+	CreatedAt  int64  `db:"created_at"`
+	ExpiredAt  *int64 `db:"expired_at"` // nil 表示永不过期
+	LastUsedAt *int64 `db:"last_used_at"`
+	RevokedAt  *int64 `db:"revoked_at"`
+}
+
+func (t *AccessToken) ScopeList() []string {
+	if len(t.Scopes) == 0 {
+		return []string{}
+	}
+	return strings.Split(t.Scopes, ",")
+}
+
+// HasScopes 是否包含所有的 scopes
+func (t *AccessToken) HasScopes(scopes ...string) bool {
+	owned := make(map[string]struct{})
+	for _, s := range t.ScopeList() {
+		owned[s] = struct{}{}
+	}
+	for _, s := range scopes {
+		if _, ok := owned[s]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Valid 在 now 时是否可用于认证（未撤销、未过期）
+func (t *AccessToken) Valid(now int64) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiredAt == nil || *t.ExpiredAt >= now
+}
@@ -56,6 +56,61 @@ func GetCode(src sqlx.Queryer, code string) (*ActivationCode, error) {
 	return nil, nil
 }
 
+// GetLatestCode 用户最近一次生成的激活码
+func GetLatestCode(src sqlx.Queryer, userID int64) (*ActivationCode, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(tableName).
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("created_at DESC").
+		Limit(1).
+		ToSql()
+
+	var data = make([]*ActivationCode, 0)
+	err := sqlx.Select(src, &data, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	if len(data) > 0 {
+		return data[0], nil
+	}
+	return nil, nil
+}
+
+// DeleteUnusedCodes 删除用户所有未使用的激活码（重新发送激活邮件时，只有最新的链接有效）
+func DeleteUnusedCodes(tx sqlx.Execer, userID int64) error {
+	sql, args, _ := sq.Delete(tableName).
+		Where(sq.Eq{"user_id": userID, "used_at": nil}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// DeleteExpiredCodesBatch 单次删除的最大行数，同 session.DeleteExpiredSessionsBatch
+const DeleteExpiredCodesBatch = 1000
+
+// DeleteExpiredCodes 删除 expired_at < before 的激活码（最多 DeleteExpiredCodesBatch 行），返回删除的数量
+func DeleteExpiredCodes(tx sqlx.Execer, before int64) (int64, error) {
+	sql, args, _ := sq.Delete(tableName).
+		Where(sq.Lt{"expired_at": before}).
+		OrderBy("expired_at").
+		Limit(DeleteExpiredCodesBatch).
+		ToSql()
+
+	ret, err := tx.Exec(sql, args...)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	n, err := ret.RowsAffected()
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	return n, nil
+}
+
 // ActivateCode
 func ActivateCode(tx sqlx.Execer, code string) error {
 	sql, args, _ := sq.Update(tableName).
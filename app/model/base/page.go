@@ -0,0 +1,56 @@
+package base
+
+// Page 分页结果（page从0开始）
+type Page[T any] struct {
+	Items   []T    `json:"items"`
+	Total   int64  `json:"total"`
+	Page    uint64 `json:"page"`
+	Per     uint64 `json:"per"`
+	HasNext bool   `json:"has_next"`
+}
+
+// NewPage items 为第 page 页的数据，total 为满足条件的总数
+func NewPage[T any](items []T, total int64, page, per uint64) *Page[T] {
+	if items == nil {
+		items = make([]T, 0)
+	}
+	return &Page[T]{
+		Items:   items,
+		Total:   total,
+		Page:    page,
+		Per:     per,
+		HasNext: total > 0 && (page+1)*per < uint64(total),
+	}
+}
+
+// Paginate 分别执行列表查询和计数查询，组装为 Page
+func Paginate[T any](
+	page, per uint64,
+	list func(page, per uint64) ([]T, error),
+	count func() (int64, error),
+) (*Page[T], error) {
+	items, err := list(page, per)
+	if err != nil {
+		return nil, err
+	}
+	total, err := count()
+	if err != nil {
+		return nil, err
+	}
+	return NewPage(items, total, page, per), nil
+}
+
+// MapPage 转换 Page 中的每一项（例如 model => 接口返回的结构），分页信息不变
+func MapPage[T, U any](p *Page[T], fn func(T) U) *Page[U] {
+	items := make([]U, 0, len(p.Items))
+	for _, item := range p.Items {
+		items = append(items, fn(item))
+	}
+	return &Page[U]{
+		Items:   items,
+		Total:   p.Total,
+		Page:    p.Page,
+		Per:     p.Per,
+		HasNext: p.HasNext,
+	}
+}
@@ -0,0 +1,38 @@
+package base
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPage(t *testing.T) {
+	assert.True(t, NewPage([]int{1, 2}, 5, 0, 2).HasNext)
+	assert.True(t, NewPage([]int{3, 4}, 5, 1, 2).HasNext)
+	assert.False(t, NewPage([]int{5}, 5, 2, 2).HasNext)
+	assert.False(t, NewPage([]int{1, 2}, 4, 1, 2).HasNext)
+
+	empty := NewPage[int](nil, 0, 0, 10)
+	assert.NotNil(t, empty.Items)
+	assert.False(t, empty.HasNext)
+}
+
+func TestPaginateAndMap(t *testing.T) {
+	data := []int{1, 2, 3, 4, 5}
+	p, err := Paginate(1, 2,
+		func(page, per uint64) ([]int, error) {
+			return data[page*per : page*per+per], nil
+		},
+		func() (int64, error) {
+			return int64(len(data)), nil
+		})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3, 4}, p.Items)
+	assert.True(t, p.HasNext)
+
+	s := MapPage(p, strconv.Itoa)
+	assert.Equal(t, []string{"3", "4"}, s.Items)
+	assert.Equal(t, p.Total, s.Total)
+	assert.True(t, s.HasNext)
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ErrNumDupEntry 违反唯一约束（对应 PostgreSQL 的 unique_violation 23505）
+const ErrNumDupEntry = 1062
+
+// MySQL 8: Duplicate entry 'x' for key 'user.unq_email'；旧版本不包含表名：for key 'unq_email'
+var dupKeyRegex = regexp.MustCompile(`for key '([^']+)'`)
+
+// DuplicateKey err 为违反唯一约束的错误时，返回约束（索引）的名称，不包含表名
+func DuplicateKey(err error) (key string, ok bool) {
+	e := mysqlError(err)
+	if e == nil || e.Number != ErrNumDupEntry {
+		return "", false
+	}
+	m := dupKeyRegex.FindStringSubmatch(e.Message)
+	if len(m) < 2 {
+		return "", true
+	}
+	key = m[1]
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	return key, true
+}
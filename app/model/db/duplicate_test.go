@@ -0,0 +1,25 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateKey(t *testing.T) {
+	err := errors.SQLError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@b.com' for key 'user.unq_email'"})
+	key, ok := DuplicateKey(err)
+	assert.True(t, ok)
+	assert.Equal(t, "unq_email", key)
+
+	key, ok = DuplicateKey(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'moli' for key 'unq_username'"})
+	assert.True(t, ok)
+	assert.Equal(t, "unq_username", key)
+
+	_, ok = DuplicateKey(errors.SQLError(&mysql.MySQLError{Number: 1213, Message: "Deadlock found"}))
+	assert.False(t, ok)
+	_, ok = DuplicateKey(errors.New("other"))
+	assert.False(t, ok)
+}
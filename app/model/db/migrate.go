@@ -0,0 +1,238 @@
+package db
+
+import (
+	"context"
+	stderrors "errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/growerlab/backend/app/utils/logger"
+	"github.com/growerlab/backend/migration"
+	"github.com/jmoiron/sqlx"
+)
+
+// MigrationTable 记录已执行的迁移
+const MigrationTable = "schema_migrations"
+
+const createMigrationTable = "CREATE TABLE IF NOT EXISTS `" + MigrationTable + "` (" +
+	"`version` varchar(64) NOT NULL, " +
+	"`applied_at` bigint NOT NULL, " +
+	"PRIMARY KEY (`version`)" +
+	") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci"
+
+// Migration 一次数据库迁移，Version 为目录名（例如 F20191013）
+type Migration struct {
+	Version string
+	Up      []string
+	Down    []string // 为空时不支持回滚
+}
+
+// LoadMigrations 读取 fsys 中的迁移，按版本号排序
+func LoadMigrations(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	migrations := make([]*Migration, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		version := e.Name()
+		up, err := fs.ReadFile(fsys, path.Join(version, version+".sql"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "migration %s", version)
+		}
+		m := &Migration{Version: version, Up: SplitStatements(string(up))}
+		down, err := fs.ReadFile(fsys, path.Join(version, version+".down.sql"))
+		if err == nil {
+			m.Down = SplitStatements(string(down))
+		} else if !stderrors.Is(err, fs.ErrNotExist) {
+			return nil, errors.Wrapf(err, "migration %s", version)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// SplitStatements 按 ; 拆分sql文件（go-sql-driver默认不支持一次执行多条语句）
+// 忽略引号中的 ;、以及 -- 开头的注释行
+func SplitStatements(s string) []string {
+	var stmts []string
+	var sb strings.Builder
+	var quote rune
+	flush := func() {
+		if stmt := strings.TrimSpace(sb.String()); len(stmt) > 0 {
+			stmts = append(stmts, stmt)
+		}
+		sb.Reset()
+	}
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if quote == 0 && strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		for _, r := range line {
+			switch {
+			case quote != 0:
+				if r == quote {
+					quote = 0
+				}
+			case r == '\'' || r == '"' || r == '`':
+				quote = r
+			case r == ';':
+				flush()
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	flush()
+	return stmts
+}
+
+// Migrate 执行所有未执行的迁移（migration 目录中的sql文件）
+func Migrate(ctx context.Context) error {
+	migrations, err := LoadMigrations(migration.FS)
+	if err != nil {
+		return err
+	}
+	return migrateUp(ctx, migrations)
+}
+
+// MigrateDown 按版本倒序回滚最近执行的 steps 个迁移
+func MigrateDown(ctx context.Context, steps int) error {
+	migrations, err := LoadMigrations(migration.FS)
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if len(m.Down) == 0 {
+			return errors.Errorf("migration %s can not be rolled back", m.Version)
+		}
+		err = applyMigration(ctx, m.Version, m.Down, false)
+		if err != nil {
+			return err
+		}
+		steps--
+	}
+	return nil
+}
+
+// PendingMigrations 尚未执行的迁移版本
+func PendingMigrations(ctx context.Context) ([]string, error) {
+	migrations, err := LoadMigrations(migration.FS)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pending(migrations, applied), nil
+}
+
+// MigrateOnStart 配置了 db.auto_migrate 时，启动时执行迁移
+func MigrateOnStart() error {
+	if !conf.GetConf().Database.AutoMigrate {
+		return nil
+	}
+	return Migrate(context.Background())
+}
+
+func migrateUp(ctx context.Context, migrations []*Migration) error {
+	applied, err := appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	versions := make(map[string]*Migration, len(migrations))
+	for _, m := range migrations {
+		versions[m.Version] = m
+	}
+	for _, version := range pending(migrations, applied) {
+		err = applyMigration(ctx, version, versions[version].Up, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pending(migrations []*Migration, applied map[string]struct{}) []string {
+	versions := make([]string, 0)
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			versions = append(versions, m.Version)
+		}
+	}
+	return versions
+}
+
+func appliedMigrations(ctx context.Context) (map[string]struct{}, error) {
+	if DB == nil {
+		return nil, errors.New("database is not initialized")
+	}
+	if _, err := DB.ExecContext(ctx, createMigrationTable); err != nil {
+		return nil, errors.SQLError(err)
+	}
+	sql, args, _ := sq.Select("version").From(MigrationTable).ToSql()
+	versions := make([]string, 0)
+	if err := sqlx.SelectContext(ctx, DB, &versions, sql, args...); err != nil {
+		return nil, errors.SQLError(err)
+	}
+	applied := make(map[string]struct{}, len(versions))
+	for _, v := range versions {
+		applied[v] = struct{}{}
+	}
+	return applied, nil
+}
+
+// applyMigration 在一个事务中执行迁移并记录（或删除）版本号
+// 注意MySQL的DDL（CREATE/ALTER TABLE等）会隐式提交事务，这类语句失败时无法回滚已执行的部分
+func applyMigration(ctx context.Context, version string, stmts []string, up bool) error {
+	err := TransactContext(ctx, func(tx ContextExt) error {
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return errors.SQLError(errors.Wrapf(err, "migration %s", version))
+			}
+		}
+		var sql string
+		var args []interface{}
+		if up {
+			sql, args, _ = sq.Insert(MigrationTable).
+				Columns("version", "applied_at").
+				Values(version, time.Now().Unix()).
+				ToSql()
+		} else {
+			sql, args, _ = sq.Delete(MigrationTable).Where(sq.Eq{"version": version}).ToSql()
+		}
+		_, err := tx.ExecContext(ctx, sql, args...)
+		if err != nil {
+			return errors.SQLError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if up {
+		logger.Info("migration %s applied", version)
+	} else {
+		logger.Info("migration %s rolled back", version)
+	}
+	return nil
+}
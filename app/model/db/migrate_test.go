@@ -0,0 +1,65 @@
+package db
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/growerlab/backend/migration"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	stmts := SplitStatements(`
+-- 注释; 不拆分
+ALTER TABLE t ADD COLUMN a varchar(10) NOT NULL DEFAULT ';' COMMENT 'a;b';
+
+UPDATE t SET a = "x;y" WHERE ` + "`b;`" + ` = 1;
+DELETE FROM t`)
+	assert.Equal(t, []string{
+		"ALTER TABLE t ADD COLUMN a varchar(10) NOT NULL DEFAULT ';' COMMENT 'a;b'",
+		`UPDATE t SET a = "x;y" WHERE ` + "`b;`" + ` = 1`,
+		"DELETE FROM t",
+	}, stmts)
+
+	assert.Empty(t, SplitStatements("\n-- only comment\n"))
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"F2/F2.sql":      {Data: []byte("SELECT 2;")},
+		"F2/F2.down.sql": {Data: []byte("SELECT -2;")},
+		"F1/F1.sql":      {Data: []byte("")},
+		"README.md":      {Data: []byte("ignored")},
+	}
+	migrations, err := LoadMigrations(fsys)
+	assert.Nil(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, "F1", migrations[0].Version)
+	assert.Empty(t, migrations[0].Up)
+	assert.Empty(t, migrations[0].Down)
+	assert.Equal(t, []string{"SELECT 2"}, migrations[1].Up)
+	assert.Equal(t, []string{"SELECT -2"}, migrations[1].Down)
+
+	assert.Equal(t, []string{"F2"}, pending(migrations, map[string]struct{}{"F1": {}}))
+
+	// 缺少升级文件
+	_, err = LoadMigrations(fstest.MapFS{"F3/other.sql": {}})
+	assert.NotNil(t, err)
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(migration.FS)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, len(migrations), 2)
+	assert.Equal(t, "F20191013", migrations[0].Version)
+	// 迁移执行器之前的表结构需要在 F20261014 修改它们之前创建
+	assert.Equal(t, "F20261013", migrations[1].Version)
+	assert.Contains(t, migrations[1].Up, "UPDATE `activate_code` SET `used_at` = NULL WHERE `used_at` = 0")
+	assert.NotEmpty(t, migrations[1].Down)
+	// 已执行的迁移不能再修改，之后的表结构变化各自使用新的版本，并且都可以回滚
+	assert.Equal(t, "F20261014", migrations[2].Version)
+	assert.Len(t, migrations[2].Up, 4)
+	for _, m := range migrations[2:] {
+		assert.NotEmpty(t, m.Down, m.Version)
+	}
+}
@@ -1,10 +1,12 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
 	"runtime/debug"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/growerlab/backend/app/common/errors"
@@ -22,7 +24,10 @@ func InitDatabase() error {
 	var err error
 	var config = conf.GetConf()
 	DB, err = DoInitDatabase(config.Database.URL, config.Debug)
-	return err
+	if err != nil {
+		return err
+	}
+	return initReplica(config.Database.ReplicaURL, config.Debug)
 }
 
 func DoInitDatabase(databaseURL string, debug bool) (*DBQuery, error) {
@@ -44,7 +49,31 @@ func DoInitDatabase(databaseURL string, debug bool) (*DBQuery, error) {
 
 func Transact(txFn func(tx sqlx.Ext) error) (err error) {
 	txa := DB.MustBegin()
+	return transact(txa, func(tx *DBQuery) error {
+		return txFn(tx)
+	})
+}
+
+// ContextExt 同时支持带 context 和不带 context 的查询，方便事务中混合调用新旧函数
+type ContextExt interface {
+	sqlx.Ext
+	sqlx.QueryerContext
+	sqlx.ExecerContext
+}
+
+// TransactContext 同 Transact，但事务绑定到 ctx 上
+// ctx 被取消时（例如客户端断开连接），正在执行的sql被中止，事务回滚
+func TransactContext(ctx context.Context, txFn func(tx ContextExt) error) (err error) {
+	txa, err := DB.BeginTxx(ctx)
+	if err != nil {
+		return err
+	}
+	return transact(txa, func(tx *DBQuery) error {
+		return txFn(tx)
+	})
+}
 
+func transact(txa *DBQuery, txFn func(tx *DBQuery) error) (err error) {
 	defer func() {
 		if p := recover(); p != nil {
 			logger.Warn("%s: %s", p, debug.Stack())
@@ -103,11 +132,40 @@ func (d *DBQuery) Exec(query string, args ...interface{}) (sql.Result, error) {
 	return d.Ext.Exec(query, args...)
 }
 
+func (d *DBQuery) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	d.Println(query, args...)
+	return d.Ext.(sqlx.QueryerContext).QueryContext(ctx, query, args...)
+}
+
+func (d *DBQuery) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	d.Println(query, args...)
+	return d.Ext.(sqlx.QueryerContext).QueryxContext(ctx, query, args...)
+}
+
+func (d *DBQuery) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	d.Println(query, args...)
+	return d.Ext.(sqlx.QueryerContext).QueryRowxContext(ctx, query, args...)
+}
+
+func (d *DBQuery) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	d.Println(query, args...)
+	return d.Ext.(sqlx.ExecerContext).ExecContext(ctx, query, args...)
+}
+
 func (d *DBQuery) MustBegin() *DBQuery {
 	d.Println("begin")
 	return &DBQuery{Ext: d.Ext.(*sqlx.DB).MustBegin(), debug: d.debug, logger: d.logger}
 }
 
+func (d *DBQuery) BeginTxx(ctx context.Context) (*DBQuery, error) {
+	d.Println("begin")
+	tx, err := d.Ext.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return &DBQuery{Ext: tx, debug: d.debug, logger: d.logger}, nil
+}
+
 func (d *DBQuery) Commit() error {
 	d.Println("commit")
 	return d.Ext.(*sqlx.Tx).Commit()
@@ -117,3 +175,22 @@ func (d *DBQuery) Rollback() error {
 	d.Println("rollback")
 	return d.Ext.(*sqlx.Tx).Rollback()
 }
+
+// PingTimeout Ping 的最长等待时间
+const PingTimeout = 2 * time.Second
+
+// Ping 检查主库连接是否可用（执行 SELECT 1）
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("database is not initialized")
+	}
+	ctx, cancel := context.WithTimeout(ctx, PingTimeout)
+	defer cancel()
+
+	var one int
+	err := DB.QueryRowxContext(ctx, "SELECT 1").Scan(&one)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/growerlab/backend/app/model/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ReplicaDB 只读副本，未配置时为nil
+	ReplicaDB *DBQuery
+)
+
+// Replica 只读查询使用的数据源（例如 ListAllUsers、SearchUsers）
+// 配置了只读副本时使用副本，否则使用主库
+// 返回值只实现了 sqlx.Queryer，所以无法用于写操作；写操作和事务始终使用主库（DB、Transact）
+// 注意：副本存在复制延迟，读取刚写入的数据时应使用主库
+func Replica() sqlx.Queryer {
+	if ReplicaDB != nil {
+		return &readOnly{q: ReplicaDB}
+	}
+	return &readOnly{q: DB}
+}
+
+// ReplicaContext 同 Replica，支持 context
+func ReplicaContext() sqlx.QueryerContext {
+	return Replica().(sqlx.QueryerContext)
+}
+
+func initReplica(replicaURL string, debug bool) error {
+	if len(replicaURL) == 0 {
+		ReplicaDB = nil
+		return nil
+	}
+	var err error
+	ReplicaDB, err = DoInitDatabase(replicaURL, debug)
+	return err
+}
+
+// readOnly 隐藏主库 DBQuery 的 Exec 等写方法
+type readOnly struct {
+	q sqlx.Queryer
+}
+
+func (r *readOnly) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return r.q.Query(query, args...)
+}
+
+func (r *readOnly) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	return r.q.Queryx(query, args...)
+}
+
+func (r *readOnly) QueryRowx(query string, args ...interface{}) *sqlx.Row {
+	return r.q.QueryRowx(query, args...)
+}
+
+func (r *readOnly) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return utils.QueryerContext(r.q).QueryContext(ctx, query, args...)
+}
+
+func (r *readOnly) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	return utils.QueryerContext(r.q).QueryxContext(ctx, query, args...)
+}
+
+func (r *readOnly) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	return utils.QueryerContext(r.q).QueryRowxContext(ctx, query, args...)
+}
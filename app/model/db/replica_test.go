@@ -0,0 +1,83 @@
+package db
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder 记录收到的sql
+type recorder struct {
+	queries []string
+	execs   []string
+}
+
+func (r *recorder) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	r.queries = append(r.queries, query)
+	return nil, nil
+}
+
+func (r *recorder) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	r.queries = append(r.queries, query)
+	return nil, nil
+}
+
+func (r *recorder) QueryRowx(query string, args ...interface{}) *sqlx.Row {
+	r.queries = append(r.queries, query)
+	return nil
+}
+
+func (r *recorder) Exec(query string, args ...interface{}) (sql.Result, error) {
+	r.execs = append(r.execs, query)
+	return nil, nil
+}
+
+func (r *recorder) DriverName() string         { return "mysql" }
+func (r *recorder) Rebind(query string) string { return query }
+func (r *recorder) BindNamed(q string, a interface{}) (string, []interface{}, error) {
+	return q, nil, nil
+}
+
+func setup(withReplica bool) (primary, replica *recorder) {
+	primary, replica = &recorder{}, &recorder{}
+	DB = &DBQuery{Ext: primary, logger: ioutil.Discard}
+	ReplicaDB = nil
+	if withReplica {
+		ReplicaDB = &DBQuery{Ext: replica, logger: ioutil.Discard}
+	}
+	return
+}
+
+func TestReplicaRoutesReads(t *testing.T) {
+	primary, replica := setup(true)
+	defer setup(false)
+
+	_, _ = Replica().Queryx("SELECT 1")
+	assert.Equal(t, []string{"SELECT 1"}, replica.queries)
+	assert.Empty(t, primary.queries)
+}
+
+func TestReplicaFallbackToPrimary(t *testing.T) {
+	primary, replica := setup(false)
+
+	_, _ = Replica().Queryx("SELECT 1")
+	assert.Equal(t, []string{"SELECT 1"}, primary.queries)
+	assert.Empty(t, replica.queries)
+}
+
+func TestReplicaIsReadOnly(t *testing.T) {
+	primary, replica := setup(true)
+	defer setup(false)
+
+	// Replica 不能用于写操作
+	_, ok := Replica().(sqlx.Execer)
+	assert.False(t, ok)
+
+	// 写操作始终使用主库
+	_, _ = DB.Exec("UPDATE user SET name = ''")
+	assert.Equal(t, []string{"UPDATE user SET name = ''"}, primary.execs)
+	assert.Empty(t, replica.execs)
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/utils/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// MySQL 中可以通过重试整个事务解决的错误
+// （对应 PostgreSQL 的 serialization_failure 40001 / deadlock_detected 40P01）
+const (
+	ErrNumLockWaitTimeout = 1205
+	ErrNumDeadlock        = 1213
+)
+
+const TransactMaxRetries = 3
+
+var transactRetryBackoff = 20 * time.Millisecond
+
+// TransactRetry 同 Transact，但遇到死锁或锁等待超时时，回滚并重试整个事务（最多 TransactMaxRetries 次，指数退避）
+// 注意：txFn 可能被执行多次，必须是幂等的：
+// 只通过 tx 修改数据，不要在其中修改外部状态（例如设置cookie、发送邮件），在 txFn 中赋值的变量在每次执行时都要完整地重新赋值
+func TransactRetry(txFn func(tx sqlx.Ext) error) error {
+	return retry(func() error {
+		return Transact(txFn)
+	})
+}
+
+func retry(fn func() error) (err error) {
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= TransactMaxRetries || !retryable(err) {
+			return err
+		}
+		num, _ := MySQLErrorNumber(err)
+		logger.Warn("retry transaction (attempt %d, mysql error %d): %v", attempt+1, num, err)
+		time.Sleep(transactRetryBackoff << attempt)
+	}
+}
+
+func retryable(err error) bool {
+	num, ok := MySQLErrorNumber(err)
+	return ok && (num == ErrNumDeadlock || num == ErrNumLockWaitTimeout)
+}
+
+// MySQLErrorNumber 获取（可能被 errors.SQLError 等包装过的）mysql错误码
+func MySQLErrorNumber(err error) (uint16, bool) {
+	if e := mysqlError(err); e != nil {
+		return e.Number, true
+	}
+	return 0, false
+}
+
+func mysqlError(err error) *mysql.MySQLError {
+	for err != nil {
+		switch e := errors.Cause(err).(type) {
+		case *mysql.MySQLError:
+			return e
+		case *errors.Result:
+			err = e.Err
+		default:
+			return nil
+		}
+	}
+	return nil
+}
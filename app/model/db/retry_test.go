@@ -0,0 +1,71 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryable(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: ErrNumDeadlock, Message: "Deadlock found"}
+	lockWait := &mysql.MySQLError{Number: ErrNumLockWaitTimeout, Message: "Lock wait timeout exceeded"}
+
+	assert.True(t, retryable(deadlock))
+	assert.True(t, retryable(lockWait))
+	assert.True(t, retryable(errors.SQLError(deadlock)))
+	assert.True(t, retryable(errors.SQLError(lockWait)))
+
+	assert.False(t, retryable(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}))
+	assert.False(t, retryable(errors.SQLError(errors.New("boom"))))
+	assert.False(t, retryable(errors.New("boom")))
+}
+
+func TestMySQLError(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: ErrNumDeadlock}
+	assert.Equal(t, deadlock, mysqlError(deadlock))
+	assert.Equal(t, deadlock, mysqlError(errors.SQLError(deadlock)))
+	assert.Nil(t, mysqlError(errors.New("boom")))
+	assert.Nil(t, mysqlError(nil))
+
+	num, ok := MySQLErrorNumber(errors.SQLError(&mysql.MySQLError{Number: ErrNumLockWaitTimeout}))
+	assert.True(t, ok)
+	assert.Equal(t, uint16(ErrNumLockWaitTimeout), num)
+}
+
+func TestRetry(t *testing.T) {
+	backoff := transactRetryBackoff
+	transactRetryBackoff = 0
+	defer func() { transactRetryBackoff = backoff }()
+
+	// 一直死锁时，最多重试 TransactMaxRetries 次
+	calls := 0
+	err := retry(func() error {
+		calls++
+		return errors.SQLError(&mysql.MySQLError{Number: ErrNumDeadlock})
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, TransactMaxRetries+1, calls)
+
+	// 重试后成功
+	calls = 0
+	err = retry(func() error {
+		calls++
+		if calls < 2 {
+			return &mysql.MySQLError{Number: ErrNumLockWaitTimeout}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+
+	// 其他错误不重试
+	calls = 0
+	err = retry(func() error {
+		calls++
+		return errors.SQLError(&mysql.MySQLError{Number: 1062})
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,126 @@
+package emailchange
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/utils/secret"
+	"github.com/jmoiron/sqlx"
+)
+
+var tableName = "email_change"
+var columns = []string{
+	"id",
+	"user_id",
+	"email",
+	"token",
+	"created_at",
+	"used_at",
+	"expired_at",
+	"previous_email",
+	"revert_token",
+	"revert_expired_at",
+	"reverted_at",
+}
+
+// AddEmailChange 只保存 c.Token 的hash
+func AddEmailChange(tx sqlx.Execer, c *EmailChange) error {
+	c.CreatedAt = time.Now().Unix()
+	c.TokenHash = secret.HashToken(c.Token)
+
+	sql, args, _ := sq.Insert(tableName).
+		Columns(columns[1:]...).
+		Values(
+			c.UserID,
+			c.Email,
+			c.TokenHash,
+			c.CreatedAt,
+			nil,
+			c.ExpiredAt,
+			"",
+			nil,
+			nil,
+			nil,
+		).ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// GetEmailChange 通过（用户提交的）token获取，比较的是token的hash
+func GetEmailChange(src sqlx.Queryer, token string) (*EmailChange, error) {
+	return getEmailChange(src, sq.Eq{"token": secret.HashToken(token)})
+}
+
+// GetEmailChangeByRevertToken 通过发送到旧邮箱的恢复token获取，比较的是token的hash
+func GetEmailChangeByRevertToken(src sqlx.Queryer, revertToken string) (*EmailChange, error) {
+	return getEmailChange(src, sq.Eq{"revert_token": secret.HashToken(revertToken)})
+}
+
+func getEmailChange(src sqlx.Queryer, cond sq.Sqlizer) (*EmailChange, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(tableName).
+		Where(cond).
+		Limit(1).
+		ToSql()
+
+	var data = make([]*EmailChange, 0)
+	err := sqlx.Select(src, &data, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	if len(data) > 0 {
+		return data[0], nil
+	}
+	return nil, nil
+}
+
+// UseEmailChange 将token标记为已使用
+func UseEmailChange(tx sqlx.Execer, token string) error {
+	sql, args, _ := sq.Update(tableName).
+		Set("used_at", time.Now().Unix()).
+		Where(sq.Eq{"token": secret.HashToken(token)}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// SetRevert 新邮箱验证通过后，记录旧邮箱及恢复token（只保存hash）
+func SetRevert(tx sqlx.Execer, id int64, previousEmail, revertToken string, revertExpiredAt int64) error {
+	sql, args, _ := sq.Update(tableName).
+		SetMap(map[string]interface{}{
+			"previous_email":    previousEmail,
+			"revert_token":      secret.HashToken(revertToken),
+			"revert_expired_at": revertExpiredAt,
+		}).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// UseRevert 将恢复token标记为已使用
+func UseRevert(tx sqlx.Execer, id int64) error {
+	sql, args, _ := sq.Update(tableName).
+		Set("reverted_at", time.Now().Unix()).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package emailchange
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/growerlab/backend/app/utils/secret"
+	"github.com/stretchr/testify/assert"
+)
+
+// execRecorder 记录执行的sql和参数
+type execRecorder struct {
+	query string
+	args  []interface{}
+}
+
+func (r *execRecorder) Exec(query string, args ...interface{}) (sql.Result, error) {
+	r.query, r.args = query, args
+	return nil, nil
+}
+
+func TestAddEmailChange(t *testing.T) {
+	rec := &execRecorder{}
+	c := &EmailChange{UserID: 7, Email: "new@example.com", Token: "plain-token", ExpiredAt: 100}
+	assert.Nil(t, AddEmailChange(rec, c))
+
+	// 数据库中只保存hash
+	assert.Equal(t, secret.HashToken("plain-token"), c.TokenHash)
+	assert.Equal(t, c.TokenHash, rec.args[2])
+	assert.NotContains(t, rec.args, "plain-token")
+}
+
+func TestUseEmailChange(t *testing.T) {
+	rec := &execRecorder{}
+	assert.Nil(t, UseEmailChange(rec, "plain-token"))
+	assert.Equal(t, secret.HashToken("plain-token"), rec.args[1])
+}
+
+func TestSetRevert(t *testing.T) {
+	rec := &execRecorder{}
+	assert.Nil(t, SetRevert(rec, 3, "old@example.com", "plain-revert-token", 100))
+	assert.Contains(t, rec.args, secret.HashToken("plain-revert-token"))
+	assert.NotContains(t, rec.args, "plain-revert-token")
+}
@@ -0,0 +1,20 @@
+package emailchange
+
+// EmailChange 待验证的新邮箱
+// 在新邮箱验证通过之前，用户的主邮箱（user.email）保持不变
+type EmailChange struct {
+	ID        int64  `db:"id"`
+	UserID    int64  `db:"user_id"`
+	Email     string `db:"email"`
+	Token     string `db:"-"`     // 只在创建时有值，用于生成验证链接
+	TokenHash string `db:"token"` // sha256(token)
+	CreatedAt int64  `db:"created_at"`
+	UsedAt    *int64 `db:"used_at"`
+	ExpiredAt int64  `db:"expired_at"`
+
+	// 新邮箱验证通过后，向旧邮箱发送通知，通过恢复token可在 RevertExpiredAt 之前恢复旧邮箱
+	PreviousEmail   string  `db:"previous_email"`
+	RevertTokenHash *string `db:"revert_token"` // sha256(恢复token)
+	RevertExpiredAt *int64  `db:"revert_expired_at"`
+	RevertedAt      *int64  `db:"reverted_at"`
+}
@@ -0,0 +1,96 @@
+package idempotency
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+var tableName = "idempotency_key"
+var columns = []string{
+	"id",
+	"scope",
+	"client_key",
+	"request_hash",
+	"resource_id",
+	"created_at",
+	"expired_at",
+}
+
+// AddKey 保存幂等键，同一个 scope 下 client_key 唯一
+// 并发的重复请求会在唯一约束上等待，先提交的一方成功，另一方返回违反唯一约束的错误
+func AddKey(tx sqlx.Execer, k *Key) error {
+	sql, args, _ := sq.Insert(tableName).
+		Columns(columns[1:]...).
+		Values(
+			k.Scope,
+			k.ClientKey,
+			k.RequestHash,
+			k.ResourceID,
+			k.CreatedAt,
+			k.ExpiredAt,
+		).ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// GetKey 不过滤已过期的键，由调用者通过 Key.Expired 判断
+func GetKey(src sqlx.Queryer, scope, clientKey string) (*Key, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(tableName).
+		Where(sq.Eq{"scope": scope, "client_key": clientKey}).
+		Limit(1).
+		ToSql()
+
+	var data = make([]*Key, 0)
+	err := sqlx.Select(src, &data, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	if len(data) > 0 {
+		return data[0], nil
+	}
+	return nil, nil
+}
+
+// DeleteExpiredKey 删除已过期的同名幂等键，以便重新使用
+func DeleteExpiredKey(tx sqlx.Execer, scope, clientKey string, now int64) error {
+	sql, args, _ := sq.Delete(tableName).
+		Where(sq.And{
+			sq.Eq{"scope": scope, "client_key": clientKey},
+			sq.Lt{"expired_at": now},
+		}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// DeleteExpiredKeysBatch 单次删除的最大行数，同 session.DeleteExpiredSessionsBatch
+const DeleteExpiredKeysBatch = 1000
+
+// DeleteExpiredKeys 删除 expired_at < before 的幂等键（最多 DeleteExpiredKeysBatch 行），返回删除的数量
+func DeleteExpiredKeys(tx sqlx.Execer, before int64) (int64, error) {
+	sql, args, _ := sq.Delete(tableName).
+		Where(sq.Lt{"expired_at": before}).
+		OrderBy("expired_at").
+		Limit(DeleteExpiredKeysBatch).
+		ToSql()
+
+	ret, err := tx.Exec(sql, args...)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	n, err := ret.RowsAffected()
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	return n, nil
+}
@@ -0,0 +1,26 @@
+package idempotency
+
+// 使用幂等键的操作
+const (
+	ScopeRegister = "register"
+)
+
+// KeyMaxLen 客户端提供的幂等键的最大长度
+const KeyMaxLen = 64
+
+// Key 客户端通过 Idempotency-Key 提交的幂等键，以及首次执行的结果
+// 有效期内使用相同的键重试时，直接返回首次执行的结果，不会重复执行
+type Key struct {
+	ID          int64  `db:"id"`
+	Scope       string `db:"scope"`
+	ClientKey   string `db:"client_key"`
+	RequestHash string `db:"request_hash"` // 请求内容的摘要，用于发现同一个键被用于不同的请求
+	ResourceID  int64  `db:"resource_id"`  // 首次执行的结果，例如注册的用户id
+	CreatedAt   int64  `db:"created_at"`
+	ExpiredAt   int64  `db:"expired_at"`
+}
+
+// Expired 是否已过期，过期的键可以被重新使用
+func (k *Key) Expired(now int64) bool {
+	return k.ExpiredAt < now
+}
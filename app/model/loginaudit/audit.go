@@ -0,0 +1,75 @@
+package loginaudit
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+var tableName = "login_audit"
+var columns = []string{
+	"id",
+	"user_id",
+	"identifier",
+	"client_ip",
+	"user_agent",
+	"success",
+	"created_at",
+}
+
+func AddLoginAudit(tx sqlx.Execer, a *LoginAudit) error {
+	a.CreatedAt = time.Now().Unix()
+
+	sql, args, _ := sq.Insert(tableName).
+		Columns(columns[1:]...).
+		Values(
+			a.UserID,
+			a.Identifier,
+			a.ClientIP,
+			a.UserAgent,
+			a.Success,
+			a.CreatedAt,
+		).ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// ListLoginAuditsByUser 用户最近的登录记录，按时间倒序
+func ListLoginAuditsByUser(src sqlx.Queryer, userID int64, limit uint64) ([]*LoginAudit, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(tableName).
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(limit).
+		ToSql()
+
+	result := make([]*LoginAudit, 0)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return result, nil
+}
+
+// DeleteLoginAuditBefore 删除 cutoff 之前的登录记录，返回删除的数量
+func DeleteLoginAuditBefore(tx sqlx.Execer, cutoff int64) (int64, error) {
+	sql, args, _ := sq.Delete(tableName).
+		Where(sq.Lt{"created_at": cutoff}).
+		ToSql()
+
+	ret, err := tx.Exec(sql, args...)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	n, err := ret.RowsAffected()
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	return n, nil
+}
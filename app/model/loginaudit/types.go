@@ -0,0 +1,12 @@
+package loginaudit
+
+// LoginAudit 登录记录（只追加），包含成功和失败的登录
+type LoginAudit struct {
+	ID         int64  `db:"id"`
+	UserID     int64  `db:"user_id"`    // 用户不存在时为0
+	Identifier string `db:"identifier"` // 登录时使用的用户名或邮箱（不记录密码）
+	ClientIP   string `db:"client_ip"`
+	UserAgent  string `db:"user_agent"`
+	Success    bool   `db:"success"`
+	CreatedAt  int64  `db:"created_at"`
+}
@@ -6,3 +6,15 @@ const (
 	TypeUser NamespaceType = 1
 	TypeOrg  NamespaceType = 2
 )
+
+// MemberRole 组织成员的角色
+type MemberRole int
+
+const (
+	RoleOwner  MemberRole = 1
+	RoleMember MemberRole = 2
+)
+
+func (r MemberRole) Valid() bool {
+	return r == RoleOwner || r == RoleMember
+}
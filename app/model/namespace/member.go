@@ -0,0 +1,133 @@
+package namespace
+
+import (
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+var memberTable = "namespace_member"
+var memberColumns = []string{
+	"id",
+	"namespace_id",
+	"user_id",
+	"role",
+	"created_at",
+}
+
+func AddMember(tx sqlx.Execer, m *Member) error {
+	m.CreatedAt = time.Now().Unix()
+
+	sql, args, _ := sq.Insert(memberTable).
+		Columns(memberColumns[1:]...).
+		Values(
+			m.NamespaceID,
+			m.UserID,
+			m.Role,
+			m.CreatedAt,
+		).ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+func DeleteMember(tx sqlx.Execer, namespaceID, userID int64) error {
+	sql, args, _ := sq.Delete(memberTable).
+		Where(sq.Eq{"namespace_id": namespaceID, "user_id": userID}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// UpdateMemberRole 修改成员的角色
+func UpdateMemberRole(tx sqlx.Execer, namespaceID, userID int64, role MemberRole) error {
+	sql, args, _ := sq.Update(memberTable).
+		Set("role", role).
+		Where(sq.Eq{"namespace_id": namespaceID, "user_id": userID}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+func GetMember(src sqlx.Queryer, namespaceID, userID int64) (*Member, error) {
+	members, err := listMembersByCond(src, sq.Eq{"namespace_id": namespaceID, "user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	if len(members) > 0 {
+		return members[0], nil
+	}
+	return nil, nil
+}
+
+func ListMembers(src sqlx.Queryer, namespaceID int64) ([]*Member, error) {
+	return listMembersByCond(src, sq.Eq{"namespace_id": namespaceID})
+}
+
+// ListOwnersForUpdate 组织的所有owner，并锁定这些行（需在事务中调用）
+// 用于移除成员时，避免并发操作导致组织没有owner
+func ListOwnersForUpdate(src sqlx.Queryer, namespaceID int64) ([]*Member, error) {
+	sql, args, _ := sq.Select(memberColumns...).
+		From(memberTable).
+		Where(sq.Eq{"namespace_id": namespaceID, "role": RoleOwner}).
+		Suffix("FOR UPDATE").
+		ToSql()
+
+	result := make([]*Member, 0)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return result, nil
+}
+
+func listMembersByCond(src sqlx.Queryer, cond sq.Sqlizer) ([]*Member, error) {
+	sql, args, _ := sq.Select(memberColumns...).
+		From(memberTable).
+		Where(cond).
+		OrderBy("id ASC").
+		ToSql()
+
+	result := make([]*Member, 0)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return result, nil
+}
+
+// ListNamespacesByMember 用户作为成员加入的命名空间（例如组织）
+// 与 ListNamespacesByOwner 不同，这里不要求用户是命名空间的 owner_id
+func ListNamespacesByMember(src sqlx.Queryer, nsType NamespaceType, userID int64) ([]*Namespace, error) {
+	joinColumns := utils.SqlColumnsComplementTable(table, columns...)
+	sql, args, _ := sq.Select(joinColumns...).
+		From(table).
+		Join(fmt.Sprintf("%s ON %s.namespace_id = %s.id", memberTable, memberTable, table)).
+		Where(sq.Eq{
+			fmt.Sprintf("%s.user_id", memberTable): userID,
+			fmt.Sprintf("%s.type", table):          nsType,
+		}).
+		ToSql()
+
+	result := make([]*Namespace, 0)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return result, nil
+}
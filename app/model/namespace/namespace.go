@@ -1,8 +1,12 @@
 package namespace
 
 import (
+	"context"
+	"strings"
+
 	sq "github.com/Masterminds/squirrel"
 	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
 	"github.com/growerlab/backend/app/model/utils"
 	"github.com/jmoiron/sqlx"
 )
@@ -27,9 +31,51 @@ func AddNamespace(tx sqlx.Queryer, ns *Namespace) error {
 		ToSql()
 
 	err := tx.QueryRowx(sql, args...).Scan(&ns.ID)
+	if err != nil {
+		// path 已被并发创建的命名空间（用户或组织）占用
+		if _, dup := db.DuplicateKey(err); dup {
+			return errors.AlreadyExistsError(errors.Namespace, errors.Path)
+		}
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+func UpdatePath(tx sqlx.Execer, id int64, path string) error {
+	sql, args, _ := sq.Update(table).
+		Set("path", path).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// ErrOwnerChanged 转让时命名空间的owner已被并发修改
+var ErrOwnerChanged = errors.New("namespace: owner changed")
+
+// UpdateOwner 将命名空间的 owner_id 从 oldOwnerID 修改为 newOwnerID
+// owner 已不是 oldOwnerID 时返回 ErrOwnerChanged
+func UpdateOwner(tx sqlx.Execer, id, oldOwnerID, newOwnerID int64) error {
+	sql, args, _ := sq.Update(table).
+		Set("owner_id", newOwnerID).
+		Where(sq.Eq{"id": id, "owner_id": oldOwnerID}).
+		ToSql()
+
+	res, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	affected, err := res.RowsAffected()
 	if err != nil {
 		return errors.SQLError(err)
 	}
+	if affected == 0 {
+		return errors.Trace(ErrOwnerChanged)
+	}
 	return nil
 }
 
@@ -37,6 +83,21 @@ func GetNamespaceByPath(src sqlx.Queryer, path string) (*Namespace, error) {
 	return getNamespaceByCond(src, sq.Eq{"path": path})
 }
 
+// PathExists path 是否已被命名空间占用（不区分大小写）
+func PathExists(src sqlx.Queryer, path string) (bool, error) {
+	sql, args, _ := sq.Select("COUNT(*)").
+		From(table).
+		Where(sq.Expr("LOWER(path) = ?", strings.ToLower(path))).
+		ToSql()
+
+	var count int64
+	err := src.QueryRowx(sql, args...).Scan(&count)
+	if err != nil {
+		return false, errors.SQLError(err)
+	}
+	return count > 0, nil
+}
+
 func GetNamespaceByOwnerID(src sqlx.Queryer, ownerID int64) (*Namespace, error) {
 	return getNamespaceByCond(src, sq.Eq{"owner_id": ownerID})
 }
@@ -57,18 +118,26 @@ func getNamespaceByCond(src sqlx.Queryer, cond sq.Sqlizer) (*Namespace, error) {
 }
 
 func ListNamespacesByOwner(src sqlx.Queryer, userType NamespaceType, ownerIDs ...int64) ([]*Namespace, error) {
+	return ListNamespacesByOwnerContext(context.Background(), utils.QueryerContext(src), userType, ownerIDs...)
+}
+
+func ListNamespacesByOwnerContext(ctx context.Context, src sqlx.QueryerContext, userType NamespaceType, ownerIDs ...int64) ([]*Namespace, error) {
 	where := sq.And{
 		sq.Eq{"owner_id": ownerIDs},
 		sq.Eq{"type": userType},
 	}
-	return listNamespaceByCond(src, where)
+	return listNamespaceByCondContext(ctx, src, where)
 }
 
 func listNamespaceByCond(src sqlx.Queryer, cond sq.Sqlizer) ([]*Namespace, error) {
+	return listNamespaceByCondContext(context.Background(), utils.QueryerContext(src), cond)
+}
+
+func listNamespaceByCondContext(ctx context.Context, src sqlx.QueryerContext, cond sq.Sqlizer) ([]*Namespace, error) {
 	sql, args, _ := sq.Select(columns...).From(table).Where(cond).ToSql()
 
 	result := make([]*Namespace, 0)
-	err := sqlx.Select(src, &result, sql, args...)
+	err := sqlx.SelectContext(ctx, src, &result, sql, args...)
 	if err != nil {
 		return nil, errors.SQLError(err)
 	}
@@ -0,0 +1,56 @@
+package namespace
+
+import (
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+var redirectTable = "namespace_redirect"
+var redirectColumns = []string{
+	"id",
+	"namespace_id",
+	"path",
+	"created_at",
+}
+
+// AddRedirect 记录命名空间改名前的path
+func AddRedirect(tx sqlx.Execer, namespaceID int64, oldPath string) error {
+	sql, args, _ := sq.Insert(redirectTable).
+		Columns(redirectColumns[1:]...).
+		Values(
+			namespaceID,
+			oldPath,
+			time.Now().Unix(),
+		).ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// GetRedirectByPath 最近一次以 path 为旧path的跳转（不区分大小写），不存在时返回nil
+// path 被新的命名空间占用时，应优先使用该命名空间，调用者需要先按 path 查找命名空间
+func GetRedirectByPath(src sqlx.Queryer, path string) (*Redirect, error) {
+	sql, args, _ := sq.Select(redirectColumns...).
+		From(redirectTable).
+		Where(sq.Expr("LOWER(path) = ?", strings.ToLower(path))).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(1).
+		ToSql()
+
+	result := make([]*Redirect, 0, 1)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	if len(result) > 0 {
+		return result[0], nil
+	}
+	return nil, nil
+}
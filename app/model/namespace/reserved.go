@@ -0,0 +1,107 @@
+package namespace
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultReservedPaths 不允许用作用户名、组织名的path（小写）
+// 包括网页中的顶级路由、静态资源目录，以及容易被用来冒充官方账号的名称
+var DefaultReservedPaths = []string{
+	"about",
+	"admin",
+	"api",
+	"assets",
+	"blog",
+	"create",
+	"dashboard",
+	"explore",
+	"get",
+	"gist",
+	"git",
+	"healthz",
+	"help",
+	"home",
+	"issues",
+	"login",
+	"logout",
+	"metrics",
+	"new",
+	"organizations",
+	"orgs",
+	"post",
+	"profile",
+	"project",
+	"projects",
+	"pulls",
+	"readyz",
+	"register",
+	"repositories",
+	"repository",
+	"setting",
+	"settings",
+	"signin",
+	"signout",
+	"signup",
+	"static",
+	"team",
+	"udmin",
+	"update",
+	"user",
+	"username",
+	"users",
+}
+
+var reserved = struct {
+	sync.RWMutex
+	set map[string]struct{}
+}{set: make(map[string]struct{})}
+
+func init() {
+	AddReserved(DefaultReservedPaths...)
+}
+
+// AddReserved 增加保留的path（例如配置中的 account.reserved_namespaces），不区分大小写
+func AddReserved(paths ...string) {
+	reserved.Lock()
+	defer reserved.Unlock()
+	for _, p := range paths {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if len(p) > 0 {
+			reserved.set[p] = struct{}{}
+		}
+	}
+}
+
+// ReserveRoutes 将路由的第一段（例如 /healthz、/api/v1/... 中的 healthz、api）设为保留，避免命名空间的地址与路由冲突
+// 以 : 或 * 开头的参数段会被忽略
+func ReserveRoutes(routes ...string) {
+	for _, r := range routes {
+		first := strings.SplitN(strings.TrimPrefix(r, "/"), "/", 2)[0]
+		if len(first) == 0 || first[0] == ':' || first[0] == '*' {
+			continue
+		}
+		AddReserved(first)
+	}
+}
+
+// IsReserved path 是否为保留的名称，不区分大小写
+func IsReserved(path string) bool {
+	reserved.RLock()
+	defer reserved.RUnlock()
+	_, ok := reserved.set[strings.ToLower(path)]
+	return ok
+}
+
+// ReservedList 所有保留的path（按字母排序），供前端提交前检查
+func ReservedList() []string {
+	reserved.RLock()
+	defer reserved.RUnlock()
+	list := make([]string, 0, len(reserved.set))
+	for p := range reserved.set {
+		list = append(list, p)
+	}
+	sort.Strings(list)
+	return list
+}
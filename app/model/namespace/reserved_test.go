@@ -0,0 +1,31 @@
+package namespace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReserved(t *testing.T) {
+	assert.True(t, IsReserved("admin"))
+	assert.True(t, IsReserved("Settings"))
+	assert.True(t, IsReserved("ASSETS"))
+	assert.False(t, IsReserved("moli"))
+
+	AddReserved(" Security ")
+	assert.True(t, IsReserved("security"))
+	assert.Contains(t, ReservedList(), "security")
+}
+
+func TestReserveRoutes(t *testing.T) {
+	ReserveRoutes("/webhooks/:id", "/:namespace/:repo", "/*path", "/")
+	assert.True(t, IsReserved("webhooks"))
+	assert.False(t, IsReserved(":namespace"))
+	assert.False(t, IsReserved("*path"))
+	assert.False(t, IsReserved(""))
+
+	list := ReservedList()
+	for i := 1; i < len(list); i++ {
+		assert.True(t, list[i-1] < list[i])
+	}
+}
@@ -0,0 +1,54 @@
+package namespace
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+var transferTable = "namespace_transfer"
+var transferColumns = []string{
+	"id",
+	"namespace_id",
+	"from_user_id",
+	"to_user_id",
+	"created_at",
+}
+
+// AddTransfer 记录命名空间owner的转让
+func AddTransfer(tx sqlx.Execer, t *Transfer) error {
+	t.CreatedAt = time.Now().Unix()
+
+	sql, args, _ := sq.Insert(transferTable).
+		Columns(transferColumns[1:]...).
+		Values(
+			t.NamespaceID,
+			t.FromUserID,
+			t.ToUserID,
+			t.CreatedAt,
+		).ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// ListTransfers 命名空间的转让记录，按时间先后排列
+func ListTransfers(src sqlx.Queryer, namespaceID int64) ([]*Transfer, error) {
+	sql, args, _ := sq.Select(transferColumns...).
+		From(transferTable).
+		Where(sq.Eq{"namespace_id": namespaceID}).
+		OrderBy("id ASC").
+		ToSql()
+
+	result := make([]*Transfer, 0)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return result, nil
+}
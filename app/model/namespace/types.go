@@ -6,3 +6,37 @@ type Namespace struct {
 	OwnerID int64  `db:"owner_id"`
 	Type    int    `db:"type"`
 }
+
+// Member 组织成员
+type Member struct {
+	ID          int64 `db:"id"`
+	NamespaceID int64 `db:"namespace_id"`
+	UserID      int64 `db:"user_id"`
+	Role        int   `db:"role"`
+	CreatedAt   int64 `db:"created_at"`
+}
+
+// Redirect 命名空间改名后，旧path到命名空间的跳转
+type Redirect struct {
+	ID          int64  `db:"id"`
+	NamespaceID int64  `db:"namespace_id"`
+	Path        string `db:"path"`
+	CreatedAt   int64  `db:"created_at"`
+}
+
+// Quarantined 旧path在改名后 days 天内是否仍保留给原命名空间（其他命名空间不能使用）
+func (r *Redirect) Quarantined(days int, now int64) bool {
+	if days <= 0 {
+		return false
+	}
+	return now < r.CreatedAt+int64(days)*24*60*60
+}
+
+// Transfer 组织命名空间的owner转让记录
+type Transfer struct {
+	ID          int64 `db:"id"`
+	NamespaceID int64 `db:"namespace_id"`
+	FromUserID  int64 `db:"from_user_id"`
+	ToUserID    int64 `db:"to_user_id"`
+	CreatedAt   int64 `db:"created_at"`
+}
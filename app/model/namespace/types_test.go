@@ -0,0 +1,17 @@
+package namespace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectQuarantined(t *testing.T) {
+	r := &Redirect{CreatedAt: 1000}
+	day := int64(24 * 60 * 60)
+
+	assert.True(t, r.Quarantined(1, 1000))
+	assert.True(t, r.Quarantined(1, 1000+day-1))
+	assert.False(t, r.Quarantined(1, 1000+day))
+	assert.False(t, r.Quarantined(0, 1000))
+}
@@ -0,0 +1,133 @@
+package oauthidentity
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+const TableName = "oauth_identity"
+
+var columns = []string{
+	"id",
+	"user_id",
+	"provider",
+	"provider_user_id",
+	"login",
+	"email",
+	"created_at",
+	"last_login_at",
+}
+
+// AddIdentity 唯一约束 (provider, provider_user_id)、(user_id, provider)，调用者需要先检查是否已绑定
+func AddIdentity(tx sqlx.Execer, i *Identity) error {
+	i.CreatedAt = time.Now().Unix()
+
+	sql, args, _ := sq.Insert(TableName).
+		Columns(columns[1:]...).
+		Values(
+			i.UserID,
+			i.Provider,
+			i.ProviderUserID,
+			i.Login,
+			i.Email,
+			i.CreatedAt,
+			i.LastLoginAt,
+		).ToSql()
+
+	ret, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	i.ID, err = ret.LastInsertId()
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// GetIdentity 第三方账号绑定的用户
+func GetIdentity(src sqlx.Queryer, provider, providerUserID string) (*Identity, error) {
+	return getIdentity(src, sq.Eq{"provider": provider, "provider_user_id": providerUserID})
+}
+
+// GetIdentityByUser 用户绑定的某个第三方账号
+func GetIdentityByUser(src sqlx.Queryer, userID int64, provider string) (*Identity, error) {
+	return getIdentity(src, sq.Eq{"user_id": userID, "provider": provider})
+}
+
+func ListIdentitiesByUser(src sqlx.Queryer, userID int64) ([]*Identity, error) {
+	return listIdentitiesByCond(src, sq.Eq{"user_id": userID})
+}
+
+func CountIdentitiesByUser(src sqlx.Queryer, userID int64) (int64, error) {
+	sql, args, _ := sq.Select("COUNT(*)").
+		From(TableName).
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+
+	var count int64
+	err := src.QueryRowx(sql, args...).Scan(&count)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	return count, nil
+}
+
+func DeleteIdentity(tx sqlx.Execer, id int64) error {
+	sql, args, _ := sq.Delete(TableName).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// UpdateLastLogin 通过第三方账号登录时，更新登录时间和第三方的用户名、邮箱
+func UpdateLastLogin(tx sqlx.Execer, id int64, login, email string) error {
+	sql, args, _ := sq.Update(TableName).
+		SetMap(map[string]interface{}{
+			"login":         login,
+			"email":         email,
+			"last_login_at": time.Now().Unix(),
+		}).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+func getIdentity(src sqlx.Queryer, cond sq.Sqlizer) (*Identity, error) {
+	identities, err := listIdentitiesByCond(src, cond)
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) > 0 {
+		return identities[0], nil
+	}
+	return nil, nil
+}
+
+func listIdentitiesByCond(src sqlx.Queryer, cond sq.Sqlizer) ([]*Identity, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(TableName).
+		Where(cond).
+		OrderBy("id").
+		ToSql()
+
+	result := make([]*Identity, 0)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,14 @@
+package oauthidentity
+
+// Identity 用户绑定的第三方账号（github、google），每个用户每个第三方最多绑定一个账号
+// 通过 (provider, provider_user_id) 找到用户，不使用第三方的邮箱（邮箱可能变化）
+type Identity struct {
+	ID             int64  `db:"id"`
+	UserID         int64  `db:"user_id"`
+	Provider       string `db:"provider"`
+	ProviderUserID string `db:"provider_user_id"`
+	Login          string `db:"login"` // 第三方的用户名（展示用），可能为空
+	Email          string `db:"email"` // 绑定时第三方的邮箱（展示用）
+	CreatedAt      int64  `db:"created_at"`
+	LastLoginAt    *int64 `db:"last_login_at"`
+}
@@ -0,0 +1,63 @@
+package recoverycode
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+var tableName = "recovery_code"
+var columns = []string{
+	"id",
+	"user_id",
+	"code_hash",
+	"created_at",
+	"used_at",
+}
+
+// ReplaceCodes 删除用户原有的恢复码，并保存新的恢复码
+func ReplaceCodes(tx sqlx.Execer, userID int64, codeHashes []string) error {
+	sql, args, _ := sq.Delete(tableName).
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	builder := sq.Insert(tableName).Columns(columns[1:]...)
+	for _, h := range codeHashes {
+		builder = builder.Values(userID, h, now, nil)
+	}
+	sql, args, _ = builder.ToSql()
+	_, err = tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// UseCode 使用一个恢复码，每个恢复码只能使用一次
+// 返回false表示恢复码不存在或已被使用
+func UseCode(tx sqlx.Execer, userID int64, codeHash string) (bool, error) {
+	sql, args, _ := sq.Update(tableName).
+		Set("used_at", time.Now().Unix()).
+		Where(sq.Eq{"user_id": userID, "code_hash": codeHash, "used_at": nil}).
+		ToSql()
+
+	ret, err := tx.Exec(sql, args...)
+	if err != nil {
+		return false, errors.SQLError(err)
+	}
+	n, err := ret.RowsAffected()
+	if err != nil {
+		return false, errors.SQLError(err)
+	}
+	return n > 0, nil
+}
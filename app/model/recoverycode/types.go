@@ -0,0 +1,9 @@
+package recoverycode
+
+type RecoveryCode struct {
+	ID        int64  `db:"id"`
+	UserID    int64  `db:"user_id"`
+	CodeHash  string `db:"code_hash"`
+	CreatedAt int64  `db:"created_at"`
+	UsedAt    *int64 `db:"used_at"`
+}
@@ -66,6 +66,16 @@ func ListRepositoriesByNamespace(src sqlx.Queryer, namespaceID int64) ([]*Reposi
 	return listRepositoriesByCond(src, columns, where)
 }
 
+// ExistsRepositoriesOfUser 用户是否创建过仓库（owner_id），或用户的命名空间下是否还有仓库
+func ExistsRepositoriesOfUser(src sqlx.Queryer, userID, namespaceID int64) (bool, error) {
+	where := sq.Or{sq.Eq{"owner_id": userID}, sq.Eq{"namespace_id": namespaceID}}
+	result, err := listRepositoriesByCond(src, []string{"id"}, where)
+	if err != nil {
+		return false, err
+	}
+	return len(result) > 0, nil
+}
+
 func GetRepositoryByNsWithPath(src sqlx.Queryer, namespaceID int64, path string) (*Repository, error) {
 	where := sq.And{sq.Eq{"namespace_id": namespaceID, "path": path}}
 	repos, err := listRepositoriesByCond(src, columns, where)
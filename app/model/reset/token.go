@@ -0,0 +1,95 @@
+package reset
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/utils/secret"
+	"github.com/jmoiron/sqlx"
+)
+
+var tableName = "password_reset"
+var columns = []string{
+	"id",
+	"user_id",
+	"token",
+	"created_at",
+	"used_at",
+	"expired_at",
+}
+
+// AddPasswordReset 只保存 r.Token 的hash
+func AddPasswordReset(tx sqlx.Execer, r *PasswordReset) error {
+	r.CreatedAt = time.Now().Unix()
+	r.TokenHash = secret.HashToken(r.Token)
+
+	sql, args, _ := sq.Insert(tableName).
+		Columns(columns[1:]...).
+		Values(
+			r.UserID,
+			r.TokenHash,
+			r.CreatedAt,
+			nil,
+			r.ExpiredAt,
+		).ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// GetPasswordReset 通过（用户提交的）token获取，比较的是token的hash
+func GetPasswordReset(src sqlx.Queryer, token string) (*PasswordReset, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(tableName).
+		Where(sq.Eq{"token": secret.HashToken(token)}).
+		Limit(1).
+		ToSql()
+
+	var data = make([]*PasswordReset, 0)
+	err := sqlx.Select(src, &data, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	if len(data) > 0 {
+		return data[0], nil
+	}
+	return nil, nil
+}
+
+// GetLatestPasswordReset 用户最近一次申请的重置token，created_at 即最后一次发送邮件的时间
+func GetLatestPasswordReset(src sqlx.Queryer, userID int64) (*PasswordReset, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(tableName).
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(1).
+		ToSql()
+
+	var data = make([]*PasswordReset, 0)
+	err := sqlx.Select(src, &data, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	if len(data) > 0 {
+		return data[0], nil
+	}
+	return nil, nil
+}
+
+// UsePasswordReset 将重置token标记为已使用
+func UsePasswordReset(tx sqlx.Execer, token string) error {
+	sql, args, _ := sq.Update(tableName).
+		Set("used_at", time.Now().Unix()).
+		Where(sq.Eq{"token": secret.HashToken(token)}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
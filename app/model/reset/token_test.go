@@ -0,0 +1,37 @@
+package reset
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/growerlab/backend/app/utils/secret"
+	"github.com/stretchr/testify/assert"
+)
+
+// execRecorder 记录执行的sql和参数
+type execRecorder struct {
+	query string
+	args  []interface{}
+}
+
+func (r *execRecorder) Exec(query string, args ...interface{}) (sql.Result, error) {
+	r.query, r.args = query, args
+	return nil, nil
+}
+
+func TestAddPasswordReset(t *testing.T) {
+	rec := &execRecorder{}
+	r := &PasswordReset{UserID: 7, Token: "plain-token", ExpiredAt: 100}
+	assert.Nil(t, AddPasswordReset(rec, r))
+
+	// 数据库中只保存hash
+	assert.Equal(t, secret.HashToken("plain-token"), r.TokenHash)
+	assert.Equal(t, r.TokenHash, rec.args[1])
+	assert.NotContains(t, rec.args, "plain-token")
+}
+
+func TestUsePasswordReset(t *testing.T) {
+	rec := &execRecorder{}
+	assert.Nil(t, UsePasswordReset(rec, "plain-token"))
+	assert.Equal(t, secret.HashToken("plain-token"), rec.args[1])
+}
@@ -0,0 +1,11 @@
+package reset
+
+type PasswordReset struct {
+	ID        int64  `db:"id"`
+	UserID    int64  `db:"user_id"`
+	Token     string `db:"-"`     // 只在创建时有值，用于生成重置链接
+	TokenHash string `db:"token"` // sha256(token)
+	CreatedAt int64  `db:"created_at"`
+	UsedAt    *int64 `db:"used_at"`
+	ExpiredAt int64  `db:"expired_at"`
+}
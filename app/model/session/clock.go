@@ -0,0 +1,13 @@
+package session
+
+import "github.com/growerlab/backend/app/utils/clock"
+
+// clk 当前时间的来源，测试时通过 SetClock 替换
+var clk = clock.Real
+
+// SetClock 替换当前时间的来源（测试用），返回恢复为原来时钟的函数
+func SetClock(c clock.Clock) (restore func()) {
+	old := clk
+	clk = c
+	return func() { clk = old }
+}
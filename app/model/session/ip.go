@@ -0,0 +1,48 @@
+package session
+
+import (
+	"net"
+	"strings"
+)
+
+// 绑定IP的session只比较网络前缀，避免同一网络内IP变化（例如移动网络、NAT）导致频繁掉线
+const (
+	IPv4PrefixBits = 24
+	IPv6PrefixBits = 64
+)
+
+// SameNetwork a、b 是否属于同一网络（IPv4 /24，IPv6 /64）
+// 任意一个无法解析时返回false
+func SameNetwork(a, b string) bool {
+	ipA, ipB := parseIP(a), parseIP(b)
+	if ipA == nil || ipB == nil {
+		return false
+	}
+
+	v4A, v4B := ipA.To4(), ipB.To4()
+	if (v4A == nil) != (v4B == nil) {
+		return false
+	}
+	if v4A != nil {
+		mask := net.CIDRMask(IPv4PrefixBits, 32)
+		return v4A.Mask(mask).Equal(v4B.Mask(mask))
+	}
+	mask := net.CIDRMask(IPv6PrefixBits, 128)
+	return ipA.Mask(mask).Equal(ipB.Mask(mask))
+}
+
+// parseIP 兼容以下格式：
+// - 1.2.3.4、::1
+// - 带端口 1.2.3.4:80、[::1]:80
+// - X-Forwarded-For 形式的列表，取第一个（最初的客户端）
+func parseIP(s string) net.IP {
+	if i := strings.Index(s, ","); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.Trim(s, "[]")
+	return net.ParseIP(s)
+}
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSameNetworkIPv4(t *testing.T) {
+	assert.True(t, SameNetwork("192.168.1.10", "192.168.1.10"))
+	assert.True(t, SameNetwork("192.168.1.10", "192.168.1.200"))
+	assert.False(t, SameNetwork("192.168.1.10", "192.168.2.10"))
+	assert.True(t, SameNetwork("192.168.1.10:5000", "192.168.1.11"))
+	// IPv4-mapped IPv6
+	assert.True(t, SameNetwork("::ffff:10.0.0.1", "10.0.0.2"))
+}
+
+func TestSameNetworkIPv6(t *testing.T) {
+	assert.True(t, SameNetwork("2001:db8:1:2::1", "2001:db8:1:2:ffff::1"))
+	assert.False(t, SameNetwork("2001:db8:1:2::1", "2001:db8:1:3::1"))
+	assert.True(t, SameNetwork("[2001:db8:1:2::1]:443", "2001:db8:1:2::2"))
+	assert.False(t, SameNetwork("2001:db8::1", "10.0.0.1"))
+}
+
+func TestSameNetworkInvalid(t *testing.T) {
+	assert.False(t, SameNetwork("", "10.0.0.1"))
+	assert.False(t, SameNetwork("unknown", "unknown"))
+}
+
+func TestSameNetworkForwarded(t *testing.T) {
+	// X-Forwarded-For 原始值：取最初的客户端
+	assert.True(t, SameNetwork("203.0.113.7, 10.0.0.1", "203.0.113.8"))
+	assert.False(t, SameNetwork("203.0.113.7, 10.0.0.1", "10.0.0.1"))
+
+	// 通过代理访问时，gin 的 ClientIP 取 X-Forwarded-For 中的客户端地址
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+	c.Request.RemoteAddr = "10.0.0.1:34567"
+	c.Request.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	assert.True(t, SameNetwork("203.0.113.20", c.ClientIP()))
+	assert.False(t, SameNetwork("10.0.0.1", c.ClientIP()))
+}
@@ -1,7 +1,12 @@
 package session
 
 import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
 	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/utils"
+	"github.com/jmoiron/sqlx"
 )
 
 const TableName = "session"
@@ -13,6 +18,9 @@ var columns = []string{
 	"client_ip",
 	"created_at",
 	"expired_at",
+	"bind_ip",
+	"impersonator_id",
+	"user_agent",
 }
 
 func (m *model) Add(sess *Session) error {
@@ -22,8 +30,245 @@ func (m *model) Add(sess *Session) error {
 		sess.ClientIP,
 		sess.CreatedAt,
 		sess.ExpiredAt,
+		sess.BindIP,
+		sess.ImpersonatorID,
+		sess.UserAgent,
 	}
 	var err error
 	sess.ID, err = m.Insert(columns[1:], values).Exec()
 	return errors.SQLError(err)
 }
+
+func DeleteSessionByToken(tx sqlx.Execer, token string) error {
+	sql, args, _ := sq.Delete(TableName).
+		Where(sq.Eq{"token": token}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// DeleteSessionsByOwner 删除用户的所有session，返回删除的数量
+func DeleteSessionsByOwner(tx sqlx.Execer, ownerID int64) (int64, error) {
+	sql, args, _ := sq.Delete(TableName).
+		Where(sq.Eq{"owner_id": ownerID}).
+		ToSql()
+
+	result, err := tx.Exec(sql, args...)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	n, err := result.RowsAffected()
+	return n, errors.SQLError(err)
+}
+
+// DeleteOtherSessionsByOwner 删除用户除 exceptToken 以外的所有session
+func DeleteOtherSessionsByOwner(tx sqlx.Execer, ownerID int64, exceptToken string) (int64, error) {
+	sql, args, _ := sq.Delete(TableName).
+		Where(sq.And{
+			sq.Eq{"owner_id": ownerID},
+			sq.NotEq{"token": exceptToken},
+		}).
+		ToSql()
+
+	result, err := tx.Exec(sql, args...)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	n, err := result.RowsAffected()
+	return n, errors.SQLError(err)
+}
+
+func GetSession(src sqlx.Queryer, id int64) (*Session, error) {
+	return getSession(src, sq.Eq{"id": id})
+}
+
+// GetSessionByToken 获取token对应的session，不存在时返回nil
+// 这里不过滤已过期的session，以便调用者区分「已过期」与「不存在」
+func GetSessionByToken(src sqlx.Queryer, token string) (*Session, error) {
+	return GetSessionByTokenContext(context.Background(), utils.QueryerContext(src), token)
+}
+
+func GetSessionByTokenContext(ctx context.Context, src sqlx.QueryerContext, token string) (*Session, error) {
+	return getSessionContext(ctx, src, sq.Eq{"token": token})
+}
+
+func getSession(src sqlx.Queryer, cond sq.Sqlizer) (*Session, error) {
+	return getSessionContext(context.Background(), utils.QueryerContext(src), cond)
+}
+
+func getSessionContext(ctx context.Context, src sqlx.QueryerContext, cond sq.Sqlizer) (*Session, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(TableName).
+		Where(cond).
+		Limit(1).
+		ToSql()
+
+	result := make([]*Session, 0, 1)
+	err := sqlx.SelectContext(ctx, src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	if len(result) > 0 {
+		return result[0], nil
+	}
+	return nil, nil
+}
+
+// ValidCond session 有效的sql条件，与 Session.Valid 一致
+// column 为 expired_at 列，联表查询时需要带上表名
+func ValidCond(column string, now int64) sq.Sqlizer {
+	return sq.GtOrEq{column: now}
+}
+
+// ExtendSession 将session的过期时间延长至 newExpiry
+// 已过期的session、代登录的session不会被延长，过期时间也只会向后延长
+func ExtendSession(tx sqlx.Execer, token string, newExpiry int64) error {
+	sql, args, _ := sq.Update(TableName).
+		Set("expired_at", newExpiry).
+		Where(sq.And{
+			sq.Eq{"token": token},
+			sq.Eq{"impersonator_id": nil},
+			ValidCond("expired_at", clk.Now().Unix()),
+			sq.Lt{"expired_at": newExpiry},
+		}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// ListSessionsByOwner 分页获取用户当前有效（未过期）的session（page从0开始，最新创建的在前）
+// per 为0时不分页，返回全部（例如导出用户数据）
+func ListSessionsByOwner(src sqlx.Queryer, ownerID int64, page, per uint64) ([]*Session, error) {
+	query := sq.Select(columns...).
+		From(TableName).
+		Where(activeSessionsOf(ownerID)).
+		// created_at 可能相同，加上id保证分页的顺序稳定
+		OrderBy("created_at DESC", "id DESC")
+	if per > 0 {
+		query = query.Limit(per).Offset(page * per)
+	}
+	sql, args, _ := query.ToSql()
+
+	result := make([]*Session, 0)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return result, nil
+}
+
+// CountActiveSessions 未过期的session数量
+func CountActiveSessions(src sqlx.Queryer, now int64) (int64, error) {
+	sql, args, _ := sq.Select("COUNT(*)").
+		From(TableName).
+		Where(ValidCond("expired_at", now)).
+		ToSql()
+
+	var count int64
+	err := src.QueryRowx(sql, args...).Scan(&count)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	return count, nil
+}
+
+// CountActiveSessionsByOwner 用户当前有效（未过期）的session数量，与 ListSessionsByOwner 的条件一致
+func CountActiveSessionsByOwner(src sqlx.Queryer, ownerID int64) (int64, error) {
+	sql, args, _ := sq.Select("COUNT(*)").
+		From(TableName).
+		Where(activeSessionsOf(ownerID)).
+		ToSql()
+
+	var count int64
+	err := src.QueryRowx(sql, args...).Scan(&count)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	return count, nil
+}
+
+func activeSessionsOf(ownerID int64) sq.Sqlizer {
+	return sq.And{
+		sq.Eq{"owner_id": ownerID},
+		ValidCond("expired_at", clk.Now().Unix()),
+	}
+}
+
+// CountSessionsByOwner 用户的session数量（包括已过期、尚未被清理的session）
+func CountSessionsByOwner(src sqlx.Queryer, ownerID int64) (int64, error) {
+	return countSessionsByOwner(src, ownerID, "")
+}
+
+func countSessionsByOwner(src sqlx.Queryer, ownerID int64, suffix string) (int64, error) {
+	sql, args, _ := sq.Select("COUNT(*)").
+		From(TableName).
+		Where(sq.Eq{"owner_id": ownerID}).
+		Suffix(suffix).
+		ToSql()
+
+	var count int64
+	err := src.QueryRowx(sql, args...).Scan(&count)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	return count, nil
+}
+
+// DeleteOldestSessions 只保留用户最新的 keep 个session，删除其余（按 created_at 最早的优先），返回删除的数量
+// 计数时锁定该用户的session（FOR UPDATE），必须在事务中调用，避免并发登录时超出数量
+func DeleteOldestSessions(tx sqlx.Ext, ownerID int64, keep int) (int64, error) {
+	count, err := countSessionsByOwner(tx, ownerID, "FOR UPDATE")
+	if err != nil {
+		return 0, err
+	}
+	if count <= int64(keep) {
+		return 0, nil
+	}
+
+	sql, args, _ := sq.Delete(TableName).
+		Where(sq.Eq{"owner_id": ownerID}).
+		OrderBy("created_at ASC", "id ASC").
+		Limit(uint64(count - int64(keep))).
+		ToSql()
+
+	ret, err := tx.Exec(sql, args...)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	n, err := ret.RowsAffected()
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	return n, nil
+}
+
+// DeleteExpiredSessionsBatch 单次删除的最大行数，避免一次删除过多行导致长时间锁表
+const DeleteExpiredSessionsBatch = 1000
+
+// DeleteExpiredSessions 删除 expired_at < before 的session（最多 DeleteExpiredSessionsBatch 行），返回删除的数量
+// 返回值等于 DeleteExpiredSessionsBatch 时，可能还有未删除的行
+func DeleteExpiredSessions(tx sqlx.Execer, before int64) (int64, error) {
+	sql, args, _ := sq.Delete(TableName).
+		Where(sq.Lt{"expired_at": before}).
+		OrderBy("expired_at").
+		Limit(DeleteExpiredSessionsBatch).
+		ToSql()
+
+	ret, err := tx.Exec(sql, args...)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	n, err := ret.RowsAffected()
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	return n, nil
+}
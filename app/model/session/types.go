@@ -1,6 +1,8 @@
 package session
 
 import (
+	"time"
+
 	"github.com/growerlab/backend/app/model/base"
 	"github.com/jmoiron/sqlx"
 )
@@ -9,9 +11,51 @@ type Session struct {
 	ID        int64  `db:"id"`
 	OwnerID   int64  `db:"owner_id"`
 	Token     string `db:"token"`
-	ClientIP  string `db:"client_ip"` // 未来可能用来检验token是否被劫持
+	ClientIP  string `db:"client_ip"` // BindIP 时用来检验token是否被劫持
 	CreatedAt int64  `db:"created_at"`
 	ExpiredAt int64  `db:"expired_at"`
+	BindIP    bool   `db:"bind_ip"` // 只允许在登录时的网络中使用（见 SameNetwork）
+
+	ImpersonatorID *int64 `db:"impersonator_id"` // 非空时为管理员代登录的session，不允许延长
+
+	UserAgent *string `db:"user_agent"` // 登录时的User-Agent，旧数据为空
+}
+
+// Valid session 在 now 时是否有效：expired_at >= now（expired_at 当秒仍然有效）
+// 与sql中的条件 ValidCond 保持一致；注销的session会被直接删除，所以不需要单独判断
+func (s *Session) Valid(now int64) bool {
+	return s.ExpiredAt >= now
+}
+
+// Expired 是否已过期，即 !Valid(now)
+func (s *Session) Expired(now int64) bool {
+	return !s.Valid(now)
+}
+
+// Remaining 剩余有效期，已过期时为0
+func (s *Session) Remaining(now time.Time) time.Duration {
+	if s.Expired(now.Unix()) {
+		return 0
+	}
+	return time.Duration(s.ExpiredAt-now.Unix()) * time.Second
+}
+
+// Lifetime 创建时的有效期（created_at 至 expired_at，不含之后的延长）
+func (s *Session) Lifetime() time.Duration {
+	return time.Duration(s.ExpiredAt-s.CreatedAt) * time.Second
+}
+
+// Impersonated 是否为管理员代登录（impersonate）的session
+func (s *Session) Impersonated() bool {
+	return s.ImpersonatorID != nil
+}
+
+// Agent 登录时的User-Agent，没有记录时为空
+func (s *Session) Agent() string {
+	if s.UserAgent == nil {
+		return ""
+	}
+	return *s.UserAgent
 }
 
 type model struct {
@@ -0,0 +1,34 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionExpiry(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	s := &Session{CreatedAt: now.Unix() - 3600, ExpiredAt: now.Unix() + 60}
+
+	assert.True(t, s.Valid(now.Unix()))
+	assert.True(t, s.Valid(s.ExpiredAt)) // 边界：expired_at == now 仍然有效
+	assert.False(t, s.Valid(s.ExpiredAt+1))
+	assert.False(t, s.Expired(now.Unix()))
+	assert.False(t, s.Expired(s.ExpiredAt)) // expired_at 当秒仍然有效
+	assert.True(t, s.Expired(s.ExpiredAt+1))
+	assert.Equal(t, time.Minute, s.Remaining(now))
+	assert.Equal(t, time.Duration(0), s.Remaining(now.Add(time.Hour)))
+	assert.Equal(t, 61*time.Minute, s.Lifetime())
+}
+
+func TestValidCond(t *testing.T) {
+	// sql的边界与 Session.Valid 一致（>=）
+	sql, args, _ := sq.Select("id").From(TableName).Where(ValidCond("expired_at", 100)).ToSql()
+	assert.Equal(t, "SELECT id FROM session WHERE expired_at >= ?", sql)
+	assert.Equal(t, []interface{}{int64(100)}, args)
+
+	sql, _, _ = ValidCond(TableName+".expired_at", 100).ToSql()
+	assert.Equal(t, "session.expired_at >= ?", sql)
+}
@@ -0,0 +1,147 @@
+package sshkey
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	"github.com/jmoiron/sqlx"
+)
+
+const TableName = "ssh_key"
+
+var columns = []string{
+	"id",
+	"user_id",
+	"title",
+	"public_key",
+	"fingerprint",
+	"created_at",
+	"last_used_at",
+}
+
+func AddSSHKey(tx sqlx.Execer, key *SSHKey) error {
+	key.CreatedAt = time.Now().Unix()
+
+	sql, args, _ := sq.Insert(TableName).
+		Columns(columns[1:]...).
+		Values(
+			key.UserID,
+			key.Title,
+			key.PublicKey,
+			key.Fingerprint,
+			key.CreatedAt,
+			nil,
+		).ToSql()
+
+	ret, err := tx.Exec(sql, args...)
+	if err != nil {
+		// 并发添加同一个公钥时，由唯一约束拒绝
+		if k, dup := db.DuplicateKey(err); dup && k == "unq_fingerprint" {
+			return errors.AlreadyExistsError(errors.SSHKey, errors.AlreadyExists)
+		}
+		return errors.SQLError(err)
+	}
+	key.ID, err = ret.LastInsertId()
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+func GetSSHKey(src sqlx.Queryer, id int64) (*SSHKey, error) {
+	return getSSHKey(src, sq.Eq{"id": id})
+}
+
+func GetSSHKeyByFingerprint(src sqlx.Queryer, fingerprint string) (*SSHKey, error) {
+	return getSSHKey(src, sq.Eq{"fingerprint": fingerprint})
+}
+
+// ListSSHKeysByUser 分页获取用户的ssh公钥（page从0开始，最新添加的在前）
+// per 为0时不分页，返回全部（例如导出用户数据）
+func ListSSHKeysByUser(src sqlx.Queryer, userID int64, page, per uint64) ([]*SSHKey, error) {
+	query := sq.Select(columns...).
+		From(TableName).
+		Where(sq.Eq{"user_id": userID}).
+		// created_at 可能相同，加上id保证分页的顺序稳定
+		OrderBy("created_at DESC", "id DESC")
+	if per > 0 {
+		query = query.Limit(per).Offset(page * per)
+	}
+	sql, args, _ := query.ToSql()
+
+	result := make([]*SSHKey, 0)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return result, nil
+}
+
+// CountSSHKeysByUser 用户的ssh公钥数量
+func CountSSHKeysByUser(src sqlx.Queryer, userID int64) (int64, error) {
+	sql, args, _ := sq.Select("COUNT(*)").
+		From(TableName).
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+
+	var count int64
+	err := src.QueryRowx(sql, args...).Scan(&count)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	return count, nil
+}
+
+func DeleteSSHKey(tx sqlx.Execer, id int64) error {
+	sql, args, _ := sq.Delete(TableName).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// UpdateLastUsed 记录key最近一次被用于认证的时间
+func UpdateLastUsed(tx sqlx.Execer, id int64) error {
+	sql, args, _ := sq.Update(TableName).
+		Set("last_used_at", time.Now().Unix()).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+func getSSHKey(src sqlx.Queryer, cond sq.Sqlizer) (*SSHKey, error) {
+	keys, err := listSSHKeysByCond(src, cond)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) > 0 {
+		return keys[0], nil
+	}
+	return nil, nil
+}
+
+func listSSHKeysByCond(src sqlx.Queryer, cond sq.Sqlizer) ([]*SSHKey, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(TableName).
+		Where(cond).
+		OrderBy("id ASC").
+		ToSql()
+
+	result := make([]*SSHKey, 0)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return result, nil
+}
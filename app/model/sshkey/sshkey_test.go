@@ -0,0 +1,28 @@
+package sshkey
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingExecer 执行sql时返回 err
+type failingExecer struct {
+	err error
+}
+
+func (e *failingExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return nil, e.err
+}
+
+func TestAddSSHKeyDuplicate(t *testing.T) {
+	dup := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'SHA256:x' for key 'ssh_key.unq_fingerprint'"}
+	err := AddSSHKey(&failingExecer{err: dup}, &SSHKey{Fingerprint: "SHA256:x"})
+	assert.Equal(t, errors.AlreadyExistsError(errors.SSHKey, errors.AlreadyExists).Error(), err.Error())
+
+	err = AddSSHKey(&failingExecer{err: &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}}, &SSHKey{})
+	assert.Equal(t, errors.CodeSQL, errors.ToResult(err).Code)
+}
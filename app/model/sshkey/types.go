@@ -0,0 +1,11 @@
+package sshkey
+
+type SSHKey struct {
+	ID          int64  `db:"id"`
+	UserID      int64  `db:"user_id"`
+	Title       string `db:"title"`
+	PublicKey   string `db:"public_key"`  // authorized_keys 格式（不含注释）
+	Fingerprint string `db:"fingerprint"` // SHA256:xxx，全局唯一
+	CreatedAt   int64  `db:"created_at"`
+	LastUsedAt  *int64 `db:"last_used_at"`
+}
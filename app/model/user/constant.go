@@ -4,48 +4,6 @@ import (
 	sq "github.com/Masterminds/squirrel"
 )
 
-// 不允许用户注册的关键字
-//
-var InvalidUsernameList = []string{
-	"user",
-	"create",
-	"update",
-	"post",
-	"get",
-	"admin",
-	"username",
-	"udmin",
-	"settings",
-	"setting",
-	"profile",
-	"dashboard",
-	"organizations",
-	"repositories",
-	"repository",
-	"git",
-	"gist",
-	"team",
-	"pulls",
-	"issues",
-	"explore",
-	"blog",
-	"home",
-	"new",
-	"project",
-	"projects",
-	"help",
-	"signin",
-	"signout",
-}
-
-var InvalidUsernameSet = make(map[string]struct{})
-
-func init() {
-	for i := range InvalidUsernameList {
-		InvalidUsernameSet[InvalidUsernameList[i]] = struct{}{}
-	}
-}
-
 // sq statues
 var (
 	NormalUser          = sq.Eq{"deleted_at": nil}
@@ -53,3 +11,17 @@ var (
 	InactivateUser      = sq.Eq{"verified_at": nil}
 	DeletedUser         = sq.NotEq{"deleted_at": nil}
 )
+
+// NormalUserOf 同 NormalUser，但字段带上表名（或别名），用于join查询，保证与非join的查询条件一致
+func NormalUserOf(table string) sq.Eq {
+	cond := make(sq.Eq, len(NormalUser))
+	for k, v := range NormalUser {
+		cond[table+"."+k] = v
+	}
+	return cond
+}
+
+// ActiveUserOf NormalUserOf 且未被停用，用于认证（session token、ssh公钥）
+func ActiveUserOf(table string) sq.And {
+	return sq.And{NormalUserOf(table), sq.Eq{table + ".suspended_at": nil}}
+}
@@ -0,0 +1,25 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalUserOf(t *testing.T) {
+	sql, args, err := NormalUserOf(tableNameMark).ToSql()
+	assert.Nil(t, err)
+	assert.Equal(t, "`user`.deleted_at IS NULL", sql)
+	assert.Empty(t, args)
+	assert.Len(t, NormalUserOf("u"), len(NormalUser))
+
+	sql, _, _ = ActiveUserOf("u").ToSql()
+	assert.Equal(t, "(u.deleted_at IS NULL AND u.suspended_at IS NULL)", sql)
+}
+
+func TestIPEq(t *testing.T) {
+	sql, args, err := ipEq("10.0.0.1").ToSql()
+	assert.Nil(t, err)
+	assert.Equal(t, "(register_ip = ? OR last_login_ip = ?)", sql)
+	assert.Equal(t, []interface{}{"10.0.0.1", "10.0.0.1"}, args)
+}
@@ -0,0 +1,155 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	"github.com/growerlab/backend/app/model/utils"
+	"github.com/growerlab/backend/app/utils/secret"
+	"github.com/jmoiron/sqlx"
+)
+
+var emailTableName = "user_email"
+var emailColumns = []string{
+	"id",
+	"user_id",
+	"email",
+	"is_primary",
+	"token",
+	"created_at",
+	"verified_at",
+	"expired_at",
+}
+
+// AddUserEmail 只保存 e.Token 的hash
+func AddUserEmail(tx sqlx.Queryer, e *UserEmail) error {
+	e.CreatedAt = time.Now().Unix()
+	if len(e.Token) > 0 {
+		e.TokenHash = secret.HashToken(e.Token)
+	}
+
+	sql, args, _ := sq.Insert(emailTableName).
+		Columns(emailColumns[1:]...).
+		Values(
+			e.UserID,
+			e.Email,
+			e.IsPrimary,
+			e.TokenHash,
+			e.CreatedAt,
+			e.VerifiedAt,
+			e.ExpiredAt,
+		).
+		Suffix(utils.SqlReturning("id")).
+		ToSql()
+
+	err := tx.QueryRowx(sql, args...).Scan(&e.ID)
+	if err != nil {
+		if _, dup := db.DuplicateKey(err); dup {
+			return errors.AlreadyExistsError(errors.User, errors.Email)
+		}
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+func GetUserEmail(src sqlx.Queryer, id int64) (*UserEmail, error) {
+	return getUserEmail(src, sq.Eq{"id": id})
+}
+
+// GetUserEmailByToken 通过（用户提交的）token获取，比较的是token的hash
+func GetUserEmailByToken(src sqlx.Queryer, token string) (*UserEmail, error) {
+	if len(token) == 0 {
+		return nil, nil
+	}
+	return getUserEmail(src, sq.Eq{"token": secret.HashToken(token)})
+}
+
+// GetUserEmailByAddress 不区分大小写，包含未验证的邮箱
+func GetUserEmailByAddress(src sqlx.Queryer, email string) (*UserEmail, error) {
+	return getUserEmail(src, sq.Expr("LOWER(email) = ?", strings.ToLower(email)))
+}
+
+func ListUserEmails(src sqlx.Queryer, userID int64) ([]*UserEmail, error) {
+	return listUserEmails(src, sq.Eq{"user_id": userID})
+}
+
+// VerifyUserEmail 将邮箱标记为已验证，并使token失效
+func VerifyUserEmail(tx sqlx.Execer, id int64) error {
+	sql, args, _ := sq.Update(emailTableName).
+		Set("verified_at", time.Now().Unix()).
+		Set("token", "").
+		Set("expired_at", nil).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// SetPrimaryUserEmail 将 id 设置为用户的主邮箱，用户的其他邮箱取消主邮箱标记
+// 调用者需要同时修改 user.email（见 UpdateEmail）
+func SetPrimaryUserEmail(tx sqlx.Execer, userID, id int64) error {
+	sql, args, _ := sq.Update(emailTableName).
+		Set("is_primary", sq.Expr("id = ?", id)).
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+func DeleteUserEmail(tx sqlx.Execer, id int64) error {
+	sql, args, _ := sq.Delete(emailTableName).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// verifiedEmailEq 用户在 user_email 中有已验证的该邮箱（不区分大小写）
+func verifiedEmailEq(email string) sq.Sqlizer {
+	return sq.Expr(
+		fmt.Sprintf("id IN (SELECT user_id FROM %s WHERE LOWER(email) = ? AND verified_at IS NOT NULL)", emailTableName),
+		strings.ToLower(email),
+	)
+}
+
+func getUserEmail(src sqlx.Queryer, cond sq.Sqlizer) (*UserEmail, error) {
+	emails, err := listUserEmails(src, cond)
+	if err != nil {
+		return nil, err
+	}
+	if len(emails) > 0 {
+		return emails[0], nil
+	}
+	return nil, nil
+}
+
+func listUserEmails(src sqlx.Queryer, cond sq.Sqlizer) ([]*UserEmail, error) {
+	sql, args, _ := sq.Select(emailColumns...).
+		From(emailTableName).
+		Where(cond).
+		OrderBy("id ASC").
+		ToSql()
+
+	result := make([]*UserEmail, 0)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,27 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserEmailVerifyExpired(t *testing.T) {
+	expiredAt := int64(100)
+	e := &UserEmail{ExpiredAt: &expiredAt}
+	assert.False(t, e.VerifyExpired(100))
+	assert.True(t, e.VerifyExpired(101))
+
+	// 已验证或不需要验证的邮箱不会过期
+	verifiedAt := int64(50)
+	assert.False(t, (&UserEmail{ExpiredAt: &expiredAt, VerifiedAt: &verifiedAt}).VerifyExpired(101))
+	assert.False(t, (&UserEmail{}).VerifyExpired(101))
+}
+
+func TestVerifyUserEmail(t *testing.T) {
+	r := &execRecorder{}
+	assert.Nil(t, VerifyUserEmail(r, 3))
+	assert.Contains(t, r.query, "token = ?")
+	assert.Contains(t, r.query, "expired_at = ?")
+	assert.Equal(t, []interface{}{"", nil, int64(3)}, r.args[1:])
+}
@@ -0,0 +1,67 @@
+package user
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+// UserFilter 用户列表的筛选条件，字段为nil时不筛选
+type UserFilter struct {
+	Verified     *bool  // true 仅已验证邮箱的用户；false 仅未验证的用户
+	IsAdmin      *bool  // true 仅管理员；false 仅非管理员
+	CreatedFrom  *int64 // created_at >= CreatedFrom
+	CreatedUntil *int64 // created_at < CreatedUntil
+}
+
+// cond 只为设置了的字段生成条件，均未设置时返回nil；NormalUser 由 ListUsersFiltered、CountUsersFiltered 附加
+func (f *UserFilter) cond() sq.Sqlizer {
+	where := sq.And{}
+	if f.Verified != nil {
+		if *f.Verified {
+			where = append(where, sq.NotEq{"verified_at": nil})
+		} else {
+			where = append(where, InactivateUser)
+		}
+	}
+	if f.IsAdmin != nil {
+		where = append(where, sq.Eq{"is_admin": *f.IsAdmin})
+	}
+	if f.CreatedFrom != nil {
+		where = append(where, sq.GtOrEq{"created_at": *f.CreatedFrom})
+	}
+	if f.CreatedUntil != nil {
+		where = append(where, sq.Lt{"created_at": *f.CreatedUntil})
+	}
+	if len(where) == 0 {
+		return nil
+	}
+	return where
+}
+
+// ListUsersFiltered 按条件分页获取用户（page从0开始，按id排序），始终附加 NormalUser 条件
+func ListUsersFiltered(src sqlx.Queryer, filter UserFilter, page, per uint64) ([]*User, error) {
+	where := sq.And{NormalUser}
+	if cond := filter.cond(); cond != nil {
+		where = append(where, cond)
+	}
+	sql, args, _ := sq.Select(columns...).
+		From(tableNameMark).
+		Where(where).
+		OrderBy("id").
+		Limit(per).
+		Offset(page * per).
+		ToSql()
+
+	users := make([]*User, 0)
+	err := sqlx.Select(src, &users, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return users, nil
+}
+
+// CountUsersFiltered 与 ListUsersFiltered 条件相同的用户数
+func CountUsersFiltered(src sqlx.Queryer, filter UserFilter) (int64, error) {
+	return CountUsersByCond(src, filter.cond())
+}
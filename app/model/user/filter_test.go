@@ -0,0 +1,27 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserFilterCond(t *testing.T) {
+	assert.Nil(t, (&UserFilter{}).cond())
+
+	verified, admin := false, true
+	from, until := int64(100), int64(200)
+	sql, args, err := (&UserFilter{
+		Verified:     &verified,
+		IsAdmin:      &admin,
+		CreatedFrom:  &from,
+		CreatedUntil: &until,
+	}).cond().ToSql()
+	assert.Nil(t, err)
+	assert.Equal(t, "(verified_at IS NULL AND is_admin = ? AND created_at >= ? AND created_at < ?)", sql)
+	assert.Equal(t, []interface{}{true, int64(100), int64(200)}, args)
+
+	verified = true
+	sql, _, _ = (&UserFilter{Verified: &verified}).cond().ToSql()
+	assert.Equal(t, "(verified_at IS NOT NULL)", sql)
+}
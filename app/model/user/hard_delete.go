@@ -0,0 +1,96 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+// DeletedRows 硬删除用户时，某个表中（将要）删除的行数
+type DeletedRows struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
+type cascadeTarget struct {
+	table string
+	cond  func(userID int64) sq.Sqlizer
+}
+
+func byColumn(column string) func(int64) sq.Sqlizer {
+	return func(userID int64) sq.Sqlizer {
+		return sq.Eq{column: userID}
+	}
+}
+
+// byUserNamespace 用户自己的命名空间（user.namespace_id），不包括用户作为owner的组织
+func byUserNamespace(column string) func(int64) sq.Sqlizer {
+	return func(userID int64) sq.Sqlizer {
+		return sq.Expr(fmt.Sprintf("%s = (SELECT namespace_id FROM %s WHERE id = ?)", column, tableNameMark), userID)
+	}
+}
+
+// cascadeTargets 硬删除用户时需要一并删除的数据，按顺序删除，user 必须是最后一个
+// namespace_transfer 只记录用户id，作为组织的转让记录保留
+var cascadeTargets = []cascadeTarget{
+	{"session", byColumn("owner_id")},
+	{"ssh_key", byColumn("user_id")},
+	{"personal_access_token", byColumn("user_id")},
+	{"oauth_identity", byColumn("user_id")},
+	{"recovery_code", byColumn("user_id")},
+	{emailTableName, byColumn("user_id")},
+	{historyTableName, byColumn("user_id")},
+	{"email_change", byColumn("user_id")},
+	{"activate_code", byColumn("user_id")},
+	{"password_reset", byColumn("user_id")},
+	{"login_audit", byColumn("user_id")},
+	{"namespace_member", byColumn("user_id")},
+	{"namespace_redirect", byUserNamespace("namespace_id")},
+	{"permission", byUserNamespace("namespace_id")},
+	{"namespace", byUserNamespace("id")},
+	{tableNameMark, byColumn("id")},
+}
+
+// CountHardDeleteUser 统计 HardDeleteUser 将要删除的数据，不做任何修改（dry-run）
+func CountHardDeleteUser(src sqlx.Queryer, userID int64) ([]*DeletedRows, error) {
+	result := make([]*DeletedRows, 0, len(cascadeTargets))
+	for _, t := range cascadeTargets {
+		sql, args, _ := sq.Select("COUNT(*)").
+			From(t.table).
+			Where(t.cond(userID)).
+			ToSql()
+
+		var n int64
+		err := sqlx.Get(src, &n, sql, args...)
+		if err != nil {
+			return nil, errors.SQLError(err)
+		}
+		result = append(result, &DeletedRows{Table: strings.Trim(t.table, "`"), Rows: n})
+	}
+	return result, nil
+}
+
+// HardDeleteUser 永久删除用户及其关联的数据（session、SSH key、token、邮箱、用户的命名空间等）
+// 需在事务中调用，任意一步失败时由调用者回滚；调用者需要先确认用户没有仓库、不是组织的owner
+func HardDeleteUser(tx sqlx.Execer, userID int64) ([]*DeletedRows, error) {
+	result := make([]*DeletedRows, 0, len(cascadeTargets))
+	for _, t := range cascadeTargets {
+		sql, args, _ := sq.Delete(t.table).
+			Where(t.cond(userID)).
+			ToSql()
+
+		res, err := tx.Exec(sql, args...)
+		if err != nil {
+			return nil, errors.SQLError(err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, errors.SQLError(err)
+		}
+		result = append(result, &DeletedRows{Table: strings.Trim(t.table, "`"), Rows: n})
+	}
+	return result, nil
+}
@@ -0,0 +1,24 @@
+package user
+
+import (
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCascadeTargets(t *testing.T) {
+	// user 必须最后删除，byUserNamespace 依赖 user.namespace_id
+	last := cascadeTargets[len(cascadeTargets)-1]
+	assert.Equal(t, tableNameMark, last.table)
+
+	seen := make(map[string]bool)
+	for _, target := range cascadeTargets {
+		assert.False(t, seen[target.table], target.table)
+		seen[target.table] = true
+	}
+
+	sql, args, _ := sq.Delete("namespace").Where(byUserNamespace("id")(7)).ToSql()
+	assert.Equal(t, "DELETE FROM namespace WHERE id = (SELECT namespace_id FROM `user` WHERE id = ?)", sql)
+	assert.Equal(t, []interface{}{int64(7)}, args)
+}
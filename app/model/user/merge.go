@@ -0,0 +1,161 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/namespace"
+	"github.com/jmoiron/sqlx"
+)
+
+// DuplicateAccounts 归一化邮箱相同的多个（未删除的）用户，例如邮箱归一化之前通过 +tag 注册的账号
+type DuplicateAccounts struct {
+	NormalizedEmail string  `json:"normalized_email"`
+	Users           []*User `json:"-"`
+}
+
+// FindDuplicateAccounts 按 normalized_email 分组，返回包含多个用户的分组（按归一化邮箱排序，组内按id排序）
+// 归一化之前注册、normalized_email 为空的用户不参与分组
+func FindDuplicateAccounts(src sqlx.Queryer) ([]*DuplicateAccounts, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(tableNameMark).
+		Where(sq.And{
+			NormalUser,
+			sq.Expr(fmt.Sprintf(
+				"normalized_email IN (SELECT normalized_email FROM %s WHERE deleted_at IS NULL AND normalized_email <> '' GROUP BY normalized_email HAVING COUNT(*) > 1)",
+				tableNameMark)),
+		}).
+		OrderBy("normalized_email", "id").
+		ToSql()
+
+	users := make([]*User, 0)
+	err := sqlx.Select(src, &users, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return groupByNormalizedEmail(users), nil
+}
+
+// groupByNormalizedEmail users 需要已按 normalized_email 排序，只保留包含多个用户的分组
+func groupByNormalizedEmail(users []*User) []*DuplicateAccounts {
+	result := make([]*DuplicateAccounts, 0)
+	var current *DuplicateAccounts
+	for _, u := range users {
+		if len(u.NormalizedEmail) == 0 {
+			continue
+		}
+		if current == nil || current.NormalizedEmail != u.NormalizedEmail {
+			current = &DuplicateAccounts{NormalizedEmail: u.NormalizedEmail}
+			result = append(result, current)
+		}
+		current.Users = append(current.Users, u)
+	}
+	groups := result[:0]
+	for _, g := range result {
+		if len(g.Users) > 1 {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// MergedRows 合并用户时，某个表中转移到保留账号的行数
+type MergedRows struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
+type mergeTarget struct {
+	table string
+	// cond 被合并用户（mergeID）的数据；set 转移到保留用户（keepID）时更新的字段
+	cond func(keepID, mergeID int64) sq.Sqlizer
+	set  func(keepID int64) map[string]interface{}
+}
+
+func setColumn(column string) func(int64) map[string]interface{} {
+	return func(keepID int64) map[string]interface{} {
+		return map[string]interface{}{column: keepID}
+	}
+}
+
+func mergeByColumn(column string) func(int64, int64) sq.Sqlizer {
+	return func(_, mergeID int64) sq.Sqlizer {
+		return sq.Eq{column: mergeID}
+	}
+}
+
+// setUserNamespace 将 column 改为保留用户的命名空间
+func setUserNamespace(column string) func(int64) map[string]interface{} {
+	return func(keepID int64) map[string]interface{} {
+		return map[string]interface{}{
+			column: sq.Expr(fmt.Sprintf("(SELECT namespace_id FROM %s WHERE id = ?)", tableNameMark), keepID),
+		}
+	}
+}
+
+// mergeTargets 合并用户时转移到保留账号的数据，按顺序执行
+// 登录记录、两步验证的恢复码、改名记录等仍然属于被合并的用户；被合并用户的主邮箱作为保留用户的次要邮箱
+var mergeTargets = []mergeTarget{
+	{"session", mergeByColumn("owner_id"), setColumn("owner_id")},
+	{"ssh_key", mergeByColumn("user_id"), setColumn("user_id")},
+	{"personal_access_token", mergeByColumn("user_id"), setColumn("user_id")},
+	// 保留用户已绑定同一个第三方时，被合并用户的第三方账号不转移（唯一约束 user_id, provider）
+	{"oauth_identity", func(keepID, mergeID int64) sq.Sqlizer {
+		return sq.And{
+			sq.Eq{"user_id": mergeID},
+			sq.Expr("provider NOT IN (SELECT provider FROM (SELECT provider FROM oauth_identity WHERE user_id = ?) AS kept)", keepID),
+		}
+	}, setColumn("user_id")},
+	{emailTableName, mergeByColumn("user_id"), func(keepID int64) map[string]interface{} {
+		return map[string]interface{}{"user_id": keepID, "is_primary": false}
+	}},
+	// 两个用户都是同一个组织的成员时，保留 keepID 原有的成员身份（唯一约束 namespace_id, user_id）
+	{"namespace_member", func(keepID, mergeID int64) sq.Sqlizer {
+		return sq.And{
+			sq.Eq{"user_id": mergeID},
+			sq.Expr("namespace_id NOT IN (SELECT namespace_id FROM (SELECT namespace_id FROM namespace_member WHERE user_id = ?) AS kept)", keepID),
+		}
+	}, setColumn("user_id")},
+	{"namespace", func(_, mergeID int64) sq.Sqlizer {
+		return sq.Eq{"owner_id": mergeID, "type": int(namespace.TypeOrg)} // 用户自己的命名空间不转移
+	}, setColumn("owner_id")},
+	// 仓库及其权限转移到保留用户的命名空间，调用者需要先确认两个用户不同时拥有仓库
+	{"permission", func(_, mergeID int64) sq.Sqlizer {
+		return byUserNamespace("namespace_id")(mergeID)
+	}, setUserNamespace("namespace_id")},
+	{"repository", func(_, mergeID int64) sq.Sqlizer {
+		return byUserNamespace("namespace_id")(mergeID)
+	}, setUserNamespace("namespace_id")},
+	{"repository", mergeByColumn("owner_id"), setColumn("owner_id")},
+}
+
+// MergeAccounts 将 mergeID 的session、SSH key、token、第三方账号、邮箱、组织、仓库等转移到 keepID，并软删除 mergeID
+// 需在事务中调用，任意一步失败时由调用者回滚；调用者负责检查两个用户可以合并（例如不同时拥有仓库）
+func MergeAccounts(tx sqlx.Execer, keepID, mergeID int64) ([]*MergedRows, error) {
+	if keepID == mergeID {
+		return nil, errors.Errorf("can not merge user %d into itself", keepID)
+	}
+	result := make([]*MergedRows, 0, len(mergeTargets))
+	for _, t := range mergeTargets {
+		sql, args, _ := sq.Update(t.table).
+			SetMap(t.set(keepID)).
+			Where(t.cond(keepID, mergeID)).
+			ToSql()
+
+		res, err := tx.Exec(sql, args...)
+		if err != nil {
+			return nil, errors.SQLError(err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, errors.SQLError(err)
+		}
+		result = append(result, &MergedRows{Table: strings.Trim(t.table, "`"), Rows: n})
+	}
+	if err := SoftDeleteUser(tx, mergeID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
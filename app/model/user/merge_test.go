@@ -0,0 +1,48 @@
+package user
+
+import (
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupByNormalizedEmail(t *testing.T) {
+	users := []*User{
+		{ID: 1, NormalizedEmail: ""},
+		{ID: 2, NormalizedEmail: "a@gmail.com"},
+		{ID: 5, NormalizedEmail: "a@gmail.com"},
+		{ID: 3, NormalizedEmail: "b@example.com"},
+		{ID: 4, NormalizedEmail: "c@example.com"},
+		{ID: 6, NormalizedEmail: "c@example.com"},
+		{ID: 7, NormalizedEmail: "c@example.com"},
+	}
+	groups := groupByNormalizedEmail(users)
+	assert.Len(t, groups, 2)
+	assert.Equal(t, "a@gmail.com", groups[0].NormalizedEmail)
+	assert.Len(t, groups[0].Users, 2)
+	assert.Equal(t, "c@example.com", groups[1].NormalizedEmail)
+	assert.Len(t, groups[1].Users, 3)
+
+	assert.Empty(t, groupByNormalizedEmail(nil))
+}
+
+func TestMergeTargets(t *testing.T) {
+	var member mergeTarget
+	for _, target := range mergeTargets {
+		if target.table == "namespace_member" {
+			member = target
+		}
+	}
+	sql, args, _ := sq.Update(member.table).SetMap(member.set(1)).Where(member.cond(1, 2)).ToSql()
+	assert.Equal(t, "UPDATE namespace_member SET user_id = ? WHERE (user_id = ? AND namespace_id NOT IN (SELECT namespace_id FROM (SELECT namespace_id FROM namespace_member WHERE user_id = ?) AS kept))", sql)
+	assert.Equal(t, []interface{}{int64(1), int64(2), int64(1)}, args)
+
+	repo := mergeTargets[len(mergeTargets)-2]
+	sql, args, _ = sq.Update(repo.table).SetMap(repo.set(1)).Where(repo.cond(1, 2)).ToSql()
+	assert.Equal(t, "UPDATE repository SET namespace_id = (SELECT namespace_id FROM `user` WHERE id = ?) WHERE namespace_id = (SELECT namespace_id FROM `user` WHERE id = ?)", sql)
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, args)
+
+	_, err := MergeAccounts(nil, 1, 1)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,77 @@
+package user
+
+import (
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+// 管理后台的角色
+const (
+	RoleSuperAdmin = "super_admin" // 拥有所有角色的权限
+	RoleModerator  = "moderator"   // 停用、恢复用户
+	RoleSupport    = "support"     // 查看用户、代登录、恢复已注销的账号
+)
+
+var validRoles = map[string]struct{}{
+	RoleSuperAdmin: {},
+	RoleModerator:  {},
+	RoleSupport:    {},
+}
+
+// ValidRole 是否为已定义的角色
+func ValidRole(role string) bool {
+	_, ok := validRoles[role]
+	return ok
+}
+
+func (u *User) RoleList() []string {
+	if len(u.Roles) == 0 {
+		return []string{}
+	}
+	return strings.Split(u.Roles, ",")
+}
+
+// HasRole 是否拥有 roles 中的任意一个角色，super_admin 视为拥有所有角色
+func (u *User) HasRole(roles ...string) bool {
+	for _, owned := range u.RoleList() {
+		if owned == RoleSuperAdmin {
+			return true
+		}
+		for _, r := range roles {
+			if owned == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsStaff 是否拥有任意管理后台的角色
+func (u *User) IsStaff() bool {
+	return len(u.Roles) > 0
+}
+
+// SetRoles 设置用户的角色（覆盖原有的角色），is_admin 与是否拥有 super_admin 保持一致
+// roles 需要由调用方通过 ValidRole 检查
+func SetRoles(tx sqlx.Execer, userID int64, roles []string) error {
+	isAdmin := false
+	uniq := make([]string, 0, len(roles))
+	seen := make(map[string]struct{}, len(roles))
+	for _, r := range roles {
+		if _, ok := seen[r]; ok {
+			continue
+		}
+		seen[r] = struct{}{}
+		uniq = append(uniq, r)
+		if r == RoleSuperAdmin {
+			isAdmin = true
+		}
+	}
+	valueMap := map[string]interface{}{
+		"roles":    strings.Join(uniq, ","),
+		"is_admin": isAdmin,
+	}
+	return update(tx, sq.Eq{"id": userID}, valueMap)
+}
@@ -0,0 +1,28 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasRole(t *testing.T) {
+	none := &User{}
+	assert.False(t, none.IsStaff())
+	assert.False(t, none.HasRole(RoleSupport))
+	assert.Equal(t, []string{}, none.RoleList())
+
+	support := &User{Roles: RoleSupport}
+	assert.True(t, support.IsStaff())
+	assert.True(t, support.HasRole(RoleModerator, RoleSupport))
+	assert.False(t, support.HasRole(RoleModerator))
+	assert.False(t, support.HasRole(RoleSuperAdmin))
+
+	// super_admin 拥有所有角色
+	super := &User{Roles: RoleModerator + "," + RoleSuperAdmin}
+	assert.True(t, super.HasRole(RoleSupport))
+	assert.True(t, super.HasRole())
+
+	assert.True(t, ValidRole(RoleModerator))
+	assert.False(t, ValidRole("admin"))
+}
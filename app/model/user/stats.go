@@ -0,0 +1,65 @@
+package user
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+const secondsPerDay = 24 * 60 * 60
+
+// createdBetween created_at 在 [startTs, endTs) 内，相邻的区间不会重复统计
+func createdBetween(startTs, endTs int64) UserFilter {
+	return UserFilter{CreatedFrom: &startTs, CreatedUntil: &endTs}
+}
+
+// ListUsersCreatedBetween 在 [startTs, endTs) 内注册的用户（page从0开始，按id排序），用于统计分析
+func ListUsersCreatedBetween(src sqlx.Queryer, startTs, endTs int64, page, per uint64) ([]*User, error) {
+	return ListUsersFiltered(src, createdBetween(startTs, endTs), page, per)
+}
+
+// CountUsersCreatedBetween 与 ListUsersCreatedBetween 条件相同的用户数
+func CountUsersCreatedBetween(src sqlx.Queryer, startTs, endTs int64) (int64, error) {
+	return CountUsersFiltered(src, createdBetween(startTs, endTs))
+}
+
+// DayCount 一天（UTC）的注册数，Day 为当天0点的时间戳
+type DayCount struct {
+	Day   int64 `db:"day" json:"day"`
+	Count int64 `db:"count" json:"count"`
+}
+
+// CountUsersCreatedByDay 在 [startTs, endTs) 内每天（按UTC日期）注册的用户数，用于注册趋势图
+// 结果按日期升序，没有注册的日期计为0；startTs 不是0点时，第一天只统计 startTs 之后的部分
+func CountUsersCreatedByDay(src sqlx.Queryer, startTs, endTs int64) ([]*DayCount, error) {
+	filter := createdBetween(startTs, endTs)
+	sql, args, _ := sq.Select("created_at - created_at % 86400 AS day", "COUNT(*) AS count").
+		From(tableNameMark).
+		Where(sq.And{NormalUser, filter.cond()}).
+		GroupBy("day").
+		ToSql()
+
+	counts := make([]*DayCount, 0)
+	err := sqlx.Select(src, &counts, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return fillDays(startTs, endTs, counts), nil
+}
+
+// fillDays 补齐 [startTs, endTs) 内没有数据的日期
+func fillDays(startTs, endTs int64, counts []*DayCount) []*DayCount {
+	if endTs <= startTs {
+		return []*DayCount{}
+	}
+	byDay := make(map[int64]int64, len(counts))
+	for _, c := range counts {
+		byDay[c.Day] = c.Count
+	}
+	first := startTs - startTs%secondsPerDay
+	result := make([]*DayCount, 0, (endTs-first+secondsPerDay-1)/secondsPerDay)
+	for day := first; day < endTs; day += secondsPerDay {
+		result = append(result, &DayCount{Day: day, Count: byDay[day]})
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+package user
+
+import (
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatedBetween(t *testing.T) {
+	// 包含起点、不包含终点
+	filter := createdBetween(100, 200)
+	sql, args, _ := sq.Select("id").From("user").Where(filter.cond()).ToSql()
+	assert.Equal(t, "SELECT id FROM user WHERE (created_at >= ? AND created_at < ?)", sql)
+	assert.Equal(t, []interface{}{int64(100), int64(200)}, args)
+}
+
+func TestFillDays(t *testing.T) {
+	const day = 86400
+	start := int64(10 * day)
+
+	counts := fillDays(start, start+3*day, []*DayCount{{Day: start + day, Count: 5}})
+	assert.Equal(t, []*DayCount{
+		{Day: start, Count: 0},
+		{Day: start + day, Count: 5},
+		{Day: start + 2*day, Count: 0},
+	}, counts)
+
+	// 起点不是0点时，从当天0点开始；终点不包含
+	counts = fillDays(start+3600, start+day, []*DayCount{{Day: start, Count: 2}})
+	assert.Equal(t, []*DayCount{{Day: start, Count: 2}}, counts)
+	assert.Len(t, fillDays(start, start+day+1, nil), 2)
+
+	assert.Empty(t, fillDays(start, start, nil))
+}
@@ -0,0 +1,131 @@
+package user
+
+import (
+	"time"
+
+	"github.com/growerlab/backend/app/model/namespace"
+	"github.com/growerlab/backend/app/model/session"
+	"github.com/growerlab/backend/app/utils/cache"
+)
+
+const (
+	DefaultTokenCacheTTL = 60 * time.Second
+	TokenCacheCapacity   = 10000
+)
+
+// CachedToken token对应的session和用户
+type CachedToken struct {
+	Session *session.Session
+	User    *User
+}
+
+// TokenCache 缓存 token => 用户（GetUserByUserTokenFromIP 是每个登录请求都会执行的查询）
+// 多实例部署时可替换为基于redis的实现，否则session注销后，其他实例在TTL内仍可能使用缓存
+type TokenCache interface {
+	Get(token string) (*CachedToken, bool)
+	Set(token string, t *CachedToken)
+	Delete(token string)
+	DeleteByUser(userID int64)
+}
+
+// UserTokenCache 为nil时不使用缓存，缓存时间由配置决定（见 ConfigureTokenCache）
+var UserTokenCache TokenCache = NewMemoryTokenCache(TokenCacheCapacity, DefaultTokenCacheTTL)
+
+// ConfigureTokenCache 按配置的缓存时间重新创建进程内的 UserTokenCache，ttl<=0 时不缓存（启动时调用）
+func ConfigureTokenCache(ttl time.Duration) {
+	if ttl <= 0 {
+		UserTokenCache = nil
+		return
+	}
+	UserTokenCache = NewMemoryTokenCache(TokenCacheCapacity, ttl)
+}
+
+// InvalidateToken 注销session后调用
+func InvalidateToken(token string) {
+	if UserTokenCache != nil {
+		UserTokenCache.Delete(token)
+	}
+}
+
+// InvalidateUserTokens 用户的session被注销，或者用户信息（状态、密码、用户名等）被修改后调用
+func InvalidateUserTokens(userID int64) {
+	if UserTokenCache != nil {
+		UserTokenCache.DeleteByUser(userID)
+	}
+}
+
+var _ TokenCache = (*MemoryTokenCache)(nil)
+
+// MemoryTokenCache 进程内的LRU实现
+type MemoryTokenCache struct {
+	lru *cache.LRU
+}
+
+func NewMemoryTokenCache(capacity int, ttl time.Duration) *MemoryTokenCache {
+	return &MemoryTokenCache{lru: cache.NewLRU(capacity, ttl)}
+}
+
+func (m *MemoryTokenCache) Get(token string) (*CachedToken, bool) {
+	v, ok := m.lru.Get(token)
+	if !ok {
+		return nil, false
+	}
+	return v.(*CachedToken), true
+}
+
+func (m *MemoryTokenCache) Set(token string, t *CachedToken) {
+	m.lru.Set(token, t)
+}
+
+func (m *MemoryTokenCache) Delete(token string) {
+	m.lru.Delete(token)
+}
+
+func (m *MemoryTokenCache) DeleteByUser(userID int64) {
+	m.lru.DeleteFunc(func(key string, value interface{}) bool {
+		return value.(*CachedToken).User.ID == userID
+	})
+}
+
+// getCachedToken 缓存中的session已过期（即使缓存的TTL未到）或IP不匹配时，不使用缓存
+// 返回用户的副本，避免调用方修改缓存中的数据
+func getCachedToken(userToken, clientIP string, now int64) (*User, bool) {
+	if UserTokenCache == nil {
+		return nil, false
+	}
+	t, ok := UserTokenCache.Get(userToken)
+	if !ok {
+		return nil, false
+	}
+	if !t.Session.Valid(now) {
+		UserTokenCache.Delete(userToken)
+		return nil, false
+	}
+	if t.Session.BindIP && !session.SameNetwork(t.Session.ClientIP, clientIP) {
+		return nil, false
+	}
+	u := *t.User
+	return &u, true
+}
+
+func setCachedToken(userToken string, sess *session.Session, user *User) {
+	if UserTokenCache == nil {
+		return
+	}
+	u := *user
+	UserTokenCache.Set(userToken, &CachedToken{Session: sess, User: &u})
+}
+
+// cacheNamespace 将用户的命名空间补充到已缓存的token中（缓存时没有使用 FillNamespace）
+func cacheNamespace(userToken string, ns *namespace.Namespace) {
+	if UserTokenCache == nil || ns == nil {
+		return
+	}
+	t, ok := UserTokenCache.Get(userToken)
+	if !ok {
+		return
+	}
+	u := *t.User
+	u.ns = ns
+	UserTokenCache.Set(userToken, &CachedToken{Session: t.Session, User: &u})
+}
@@ -0,0 +1,88 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/growerlab/backend/app/model/namespace"
+	"github.com/growerlab/backend/app/model/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedTokenHonorsSessionExpiry(t *testing.T) {
+	old := UserTokenCache
+	defer func() { UserTokenCache = old }()
+	UserTokenCache = NewMemoryTokenCache(10, time.Minute)
+
+	sess := &session.Session{OwnerID: 1, Token: "t", ExpiredAt: 100}
+	setCachedToken("t", sess, &User{ID: 1, Username: "moli"})
+
+	u, ok := getCachedToken("t", "", 100)
+	assert.True(t, ok)
+	assert.Equal(t, "moli", u.Username)
+
+	// 缓存的TTL未到，但session已过期
+	_, ok = getCachedToken("t", "", 101)
+	assert.False(t, ok)
+	_, ok = UserTokenCache.Get("t")
+	assert.False(t, ok)
+}
+
+func TestCachedTokenBindIP(t *testing.T) {
+	old := UserTokenCache
+	defer func() { UserTokenCache = old }()
+	UserTokenCache = NewMemoryTokenCache(10, time.Minute)
+
+	sess := &session.Session{OwnerID: 1, Token: "t", ExpiredAt: 100, BindIP: true, ClientIP: "10.0.0.1"}
+	setCachedToken("t", sess, &User{ID: 1})
+
+	_, ok := getCachedToken("t", "10.0.0.2", 0)
+	assert.True(t, ok)
+	_, ok = getCachedToken("t", "10.0.1.1", 0)
+	assert.False(t, ok)
+}
+
+func TestInvalidateUserTokens(t *testing.T) {
+	old := UserTokenCache
+	defer func() { UserTokenCache = old }()
+	UserTokenCache = NewMemoryTokenCache(10, time.Minute)
+
+	setCachedToken("a", &session.Session{ExpiredAt: 100}, &User{ID: 1})
+	setCachedToken("b", &session.Session{ExpiredAt: 100}, &User{ID: 1})
+	setCachedToken("c", &session.Session{ExpiredAt: 100}, &User{ID: 2})
+
+	InvalidateUserTokens(1)
+	for token, want := range map[string]bool{"a": false, "b": false, "c": true} {
+		_, ok := getCachedToken(token, "", 0)
+		assert.Equal(t, want, ok, token)
+	}
+
+	// 返回的是副本
+	u, _ := getCachedToken("c", "", 0)
+	u.Username = "changed"
+	u, _ = getCachedToken("c", "", 0)
+	assert.Equal(t, "", u.Username)
+}
+
+func TestCacheNamespace(t *testing.T) {
+	old := UserTokenCache
+	defer func() { UserTokenCache = old }()
+	UserTokenCache = NewMemoryTokenCache(10, time.Minute)
+
+	sess := &session.Session{OwnerID: 1, ExpiredAt: 100}
+	setCachedToken("t", sess, &User{ID: 1, NamespaceID: 5})
+	u, _ := getCachedToken("t", "", 0)
+	assert.Nil(t, u.ns)
+
+	ns := &namespace.Namespace{ID: 5, Path: "moli"}
+	cacheNamespace("t", ns)
+	u, ok := getCachedToken("t", "", 0)
+	assert.True(t, ok)
+	// 已获取命名空间，Namespace() 不再查询数据库
+	assert.Equal(t, "moli", u.Namespace().Path)
+
+	// 不存在的token不会被加入缓存
+	cacheNamespace("missing", ns)
+	_, ok = UserTokenCache.Get("missing")
+	assert.False(t, ok)
+}
@@ -20,19 +20,119 @@ type User struct {
 	RegisterIP        string  `db:"register_ip"`
 	IsAdmin           bool    `db:"is_admin"`
 	NamespaceID       int64   `db:"namespace_id"`
+	FailedLoginCount  int     `db:"failed_login_count"` // 连续登录失败次数
+	LockedUntil       *int64  `db:"locked_until"`       // 账号锁定截止时间
+	SuspendedAt       *int64  `db:"suspended_at"`       // 被管理员停用的时间
+	TOTPSecret        *string `db:"totp_secret"`        // 加密后的TOTP密钥
+	TOTPEnabledAt     *int64  `db:"totp_enabled_at"`    // 启用两步验证的时间
+	NormalizedEmail   string  `db:"normalized_email"`   // 归一化后的邮箱，仅用于唯一性检查
+	Version           int64   `db:"version"`            // 乐观锁版本号，见 UpdateUserVersioned
+	Roles             string  `db:"roles"`              // 管理后台的角色，以逗号分隔，见 HasRole
+
+	LastUsernameChangeAt *int64 `db:"last_username_change_at"` // 最近一次修改用户名的时间
+	PasswordChangedAt    *int64 `db:"password_changed_at"`     // 最近一次修改密码的时间，之前创建的session无效
+	FirstFailedAt        *int64 `db:"first_failed_at"`         // 本轮连续登录失败中第一次失败的时间，见 UpdateLoginFailed
+	TOTPLastStep         *int64 `db:"totp_last_step"`          // 最近一次通过验证的TOTP时间步，不早于它的code不能再次使用，见 UseTOTPStep
 
 	ns *namespace.Namespace // cached namespace
 }
 
+// PublicProfile 返回给其他用户的用户信息
+// 不包含私有邮箱（email）、登录/注册IP、是否管理员等仅本人或管理员可见的信息
+type PublicProfile struct {
+	Username      string `json:"username"`
+	Name          string `json:"name"`
+	PublicEmail   string `json:"public_email"`
+	NamespacePath string `json:"namespace_path"`
+	CreatedAt     int64  `json:"created_at"`
+}
+
+// UsernameHistory 用户曾经使用过的用户名（用于旧地址的跳转）
+type UsernameHistory struct {
+	ID        int64  `db:"id"`
+	UserID    int64  `db:"user_id"`
+	Username  string `db:"username"`
+	CreatedAt int64  `db:"created_at"`
+}
+
+// Quarantined 旧用户名在修改后 days 天内是否仍保留给原用户（其他用户不能使用）
+func (h *UsernameHistory) Quarantined(days int, now int64) bool {
+	if days <= 0 {
+		return false
+	}
+	return now < h.CreatedAt+int64(days)*24*60*60
+}
+
+// UserEmail 用户的邮箱
+// user.email 仍然是主邮箱；迁移之前注册的用户在 user_email 中可能没有对应的记录
+type UserEmail struct {
+	ID         int64  `db:"id"`
+	UserID     int64  `db:"user_id"`
+	Email      string `db:"email"`
+	IsPrimary  bool   `db:"is_primary"`
+	Token      string `db:"-"`     // 只在创建时有值，用于生成验证链接
+	TokenHash  string `db:"token"` // sha256(token)，验证后清空
+	CreatedAt  int64  `db:"created_at"`
+	VerifiedAt *int64 `db:"verified_at"`
+	ExpiredAt  *int64 `db:"expired_at"` // 验证链接的有效期，不需要验证的邮箱为nil
+}
+
+// VerifyExpired 验证链接在 now 时是否已过期（已验证的邮箱不会过期）
+func (e *UserEmail) VerifyExpired(now int64) bool {
+	return e.VerifiedAt == nil && e.ExpiredAt != nil && *e.ExpiredAt < now
+}
+
 // TODO N+1 问题
 func (u *User) Namespace() *namespace.Namespace {
 	if u.ns != nil {
 		return u.ns
 	}
-	u.ns, _ = namespace.GetNamespaceByOwnerID(db.DB, u.ID)
+	// 用户可能同时是组织命名空间的 owner_id，所以这里按 namespace_id 获取
+	u.ns, _ = namespace.GetNamespace(db.DB, u.NamespaceID)
 	return u.ns
 }
 
+// ToPublicProfile 用于响应中涉及其他用户的场景
+func (u *User) ToPublicProfile() *PublicProfile {
+	p := &PublicProfile{
+		Username:    u.Username,
+		Name:        u.Name,
+		PublicEmail: u.PublicEmail,
+		CreatedAt:   u.CreatedAt,
+	}
+	if ns := u.Namespace(); ns != nil {
+		p.NamespacePath = ns.Path
+	}
+	return p
+}
+
 func (u *User) Verified() bool {
 	return u.VerifiedAt != nil && *u.VerifiedAt > 0
 }
+
+// Suspended 账号是否已被管理员停用
+func (u *User) Suspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// HasPassword 是否设置了密码，通过第三方账号注册的用户没有密码
+func (u *User) HasPassword() bool {
+	return len(u.EncryptedPassword) > 0
+}
+
+// TOTPEnabled 是否已启用两步验证
+func (u *User) TOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil && u.TOTPSecret != nil
+}
+
+// AcceptsSession createdAt 时创建的session是否仍可用于认证：在最近一次修改密码之后（含同一秒）创建
+// 修改密码的同一秒内新建的session（例如修改密码时为当前设备重新签发的session）仍然有效
+// 与sql中的条件 PasswordChangedCond 保持一致
+func (u *User) AcceptsSession(createdAt int64) bool {
+	return u.PasswordChangedAt == nil || createdAt >= *u.PasswordChangedAt
+}
+
+// Locked 账号在 now 时是否处于锁定状态
+func (u *User) Locked(now int64) bool {
+	return u.LockedUntil != nil && *u.LockedUntil > now
+}
@@ -0,0 +1,68 @@
+package user
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/growerlab/backend/app/model/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToPublicProfile(t *testing.T) {
+	lastLoginIP := "10.0.0.2"
+	u := &User{
+		Email:       "private@example.com",
+		Username:    "moli",
+		Name:        "Moli",
+		PublicEmail: "public@example.com",
+		CreatedAt:   100,
+		LastLoginIP: &lastLoginIP,
+		RegisterIP:  "10.0.0.1",
+		IsAdmin:     true,
+		ns:          &namespace.Namespace{Path: "moli"},
+	}
+
+	p := u.ToPublicProfile()
+	assert.Equal(t, &PublicProfile{
+		Username:      "moli",
+		Name:          "Moli",
+		PublicEmail:   "public@example.com",
+		NamespacePath: "moli",
+		CreatedAt:     100,
+	}, p)
+
+	b, err := json.Marshal(p)
+	assert.Nil(t, err)
+	for _, private := range []string{"private@example.com", "10.0.0.1", "10.0.0.2", "admin"} {
+		assert.NotContains(t, string(b), private)
+	}
+}
+
+func TestUsernameHistoryQuarantined(t *testing.T) {
+	h := &UsernameHistory{CreatedAt: 1000}
+	day := int64(24 * 60 * 60)
+
+	assert.True(t, h.Quarantined(1, 1000))
+	assert.True(t, h.Quarantined(1, 1000+day-1))
+	assert.False(t, h.Quarantined(1, 1000+day))
+	assert.False(t, h.Quarantined(0, 1000))
+}
+
+func TestAcceptsSession(t *testing.T) {
+	u := &User{}
+	assert.True(t, u.AcceptsSession(100), "never changed")
+
+	changedAt := int64(1000)
+	u.PasswordChangedAt = &changedAt
+	assert.False(t, u.AcceptsSession(999))
+	// 与修改密码同一秒创建的session仍然有效
+	assert.True(t, u.AcceptsSession(1000))
+	assert.True(t, u.AcceptsSession(1001))
+}
+
+func TestPasswordChangedCond(t *testing.T) {
+	sql, args, err := PasswordChangedCond("`user`", "session").ToSql()
+	assert.Nil(t, err)
+	assert.Empty(t, args)
+	assert.Equal(t, "(`user`.password_changed_at IS NULL OR session.created_at >= `user`.password_changed_at)", sql)
+}
@@ -1,14 +1,21 @@
 package user
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/accesstoken"
+	"github.com/growerlab/backend/app/model/db"
 	"github.com/growerlab/backend/app/model/namespace"
 	"github.com/growerlab/backend/app/model/session"
+	"github.com/growerlab/backend/app/model/sshkey"
 	"github.com/growerlab/backend/app/model/utils"
+	emailutil "github.com/growerlab/backend/app/utils/email"
+	"github.com/growerlab/backend/app/utils/secret"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -30,75 +37,342 @@ var columns = []string{
 	"register_ip",
 	"is_admin",
 	"namespace_id",
+	"failed_login_count",
+	"locked_until",
+	"suspended_at",
+	"totp_secret",
+	"totp_enabled_at",
+	"normalized_email",
+	"version",
+	"roles",
+	"last_username_change_at",
+	"password_changed_at",
+	"first_failed_at",
+	"totp_last_step",
 }
 
 func AddUser(tx sqlx.Queryer, user *User) error {
+	return AddUserContext(context.Background(), utils.QueryerContext(tx), user)
+}
+
+func AddUserContext(ctx context.Context, tx sqlx.QueryerContext, user *User) error {
 	sql, args, _ := sq.Insert(tableNameMark).
 		Columns(columns[1:]...).
-		Values(
-			user.Email,
-			user.EncryptedPassword,
-			user.Username,
-			user.Name,
-			user.PublicEmail,
-			user.CreatedAt,
-			nil,
-			nil,
-			nil,
-			nil,
-			user.RegisterIP,
-			user.IsAdmin,
-			user.NamespaceID,
-		).
+		Values(insertValues(user)...).
 		Suffix(utils.SqlReturning("id")).
 		ToSql()
 
-	err := tx.QueryRowx(sql, args...).Scan(&user.ID)
+	err := tx.QueryRowxContext(ctx, sql, args...).Scan(&user.ID)
+	if err != nil {
+		return duplicateError(errors.SQLError(err))
+	}
+	return nil
+}
+
+// CreateUserWithNamespace 创建用户及其个人命名空间（path为用户名）
+// 先创建命名空间（owner暂为0），再添加用户（带namespace_id），最后将命名空间的owner改为新用户
+// 需要在事务中调用，以便任一步失败时整体回滚
+func CreateUserWithNamespace(tx sqlx.Ext, user *User) error {
+	return CreateUserWithNamespaceContext(context.Background(), tx, user)
+}
+
+func CreateUserWithNamespaceContext(ctx context.Context, tx sqlx.Ext, user *User) error {
+	ns := &namespace.Namespace{
+		Path: user.Username,
+		Type: int(namespace.TypeUser),
+	}
+	err := namespace.AddNamespace(tx, ns)
 	if err != nil {
+		return err
+	}
+
+	user.NamespaceID = ns.ID
+	err = AddUserContext(ctx, utils.QueryerContext(tx), user)
+	if err != nil {
+		return err
+	}
+
+	err = namespace.UpdateOwner(tx, ns.ID, 0, user.ID)
+	if err != nil {
+		return err
+	}
+	ns.OwnerID = user.ID
+	user.ns = ns
+	return nil
+}
+
+// AddUsers 批量添加用户（一条多行INSERT），按顺序将id赋值给 users
+// 同一批次内邮箱或用户名重复（不区分大小写）时，不会执行sql；
+// 与已有用户冲突时，由唯一约束报错，调用方需要在事务中执行以便整体回滚
+func AddUsers(tx sqlx.Queryer, users []*User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	if err := checkBatchDuplicates(users); err != nil {
+		return err
+	}
+
+	builder := sq.Insert(tableNameMark).Columns(columns[1:]...)
+	for _, user := range users {
+		builder = builder.Values(insertValues(user)...)
+	}
+	sql, args, _ := builder.Suffix(utils.SqlReturning("id")).ToSql()
+
+	rows, err := tx.Queryx(sql, args...)
+	if err != nil {
+		return duplicateError(errors.SQLError(err))
+	}
+	defer rows.Close()
+
+	i := 0
+	for rows.Next() {
+		if i >= len(users) {
+			break
+		}
+		if err = rows.Scan(&users[i].ID); err != nil {
+			return errors.SQLError(err)
+		}
+		i++
+	}
+	if err = rows.Err(); err != nil {
 		return errors.SQLError(err)
 	}
+	if i != len(users) {
+		return errors.SQLError(fmt.Errorf("add users: expected %d ids, got %d", len(users), i))
+	}
 	return nil
 }
 
+func checkBatchDuplicates(users []*User) error {
+	emails := make(map[string]struct{}, len(users))
+	usernames := make(map[string]struct{}, len(users))
+	for _, user := range users {
+		email := emailutil.Normalize(user.Email)
+		if _, ok := emails[email]; ok {
+			return errors.P(errors.User, errors.Email, errors.AlreadyExists)
+		}
+		emails[email] = struct{}{}
+
+		username := strings.ToLower(user.Username)
+		if _, ok := usernames[username]; ok {
+			return errors.P(errors.User, errors.Username, errors.AlreadyExists)
+		}
+		usernames[username] = struct{}{}
+	}
+	return nil
+}
+
+// insertValues 与 columns[1:] 一一对应
+func insertValues(user *User) []interface{} {
+	user.NormalizedEmail = emailutil.Normalize(user.Email)
+	return []interface{}{
+		user.Email,
+		user.EncryptedPassword,
+		user.Username,
+		user.Name,
+		user.PublicEmail,
+		user.CreatedAt,
+		nil,
+		user.VerifiedAt,
+		nil,
+		nil,
+		user.RegisterIP,
+		user.IsAdmin,
+		user.NamespaceID,
+		0,
+		nil,
+		nil,
+		nil,
+		nil,
+		user.NormalizedEmail,
+		0,
+		user.Roles,
+		nil,
+		nil,
+		nil,
+		nil,
+	}
+}
+
+// ExistsEmailOrUsername 用户名或邮箱是否已被占用（为空的不检查），需要区分是哪个字段时使用 ExistsUsername、ExistsEmail
 func ExistsEmailOrUsername(src sqlx.Queryer, username, email string) (bool, error) {
+	return existsEmailOrUsername(src, username, email, false)
+}
+
+// ExistsEmailOrUsernameIncludingDeleted 同 ExistsEmailOrUsername，但包含已软删除的用户
+// 软删除的用户仍然占用着邮箱和用户名（数据库的唯一约束）
+func ExistsEmailOrUsernameIncludingDeleted(src sqlx.Queryer, username, email string) (bool, error) {
+	return existsEmailOrUsername(src, username, email, true)
+}
+
+// ExistsUsername 用户名是否已被占用（不区分大小写）
+func ExistsUsername(src sqlx.Queryer, username string) (bool, error) {
+	return exists(src, usernameEq(username), false)
+}
+
+// ExistsUsernameIncludingDeleted 同 ExistsUsername，但包含已软删除的用户
+func ExistsUsernameIncludingDeleted(src sqlx.Queryer, username string) (bool, error) {
+	return exists(src, usernameEq(username), true)
+}
+
+// ExistsEmail 邮箱是否已被占用，见 emailTaken
+func ExistsEmail(src sqlx.Queryer, email string) (bool, error) {
+	return exists(src, emailTaken(email), false)
+}
+
+// ExistsEmailIncludingDeleted 同 ExistsEmail，但包含已软删除的用户
+func ExistsEmailIncludingDeleted(src sqlx.Queryer, email string) (bool, error) {
+	return exists(src, emailTaken(email), true)
+}
+
+func existsEmailOrUsername(src sqlx.Queryer, username, email string, includeDeleted bool) (bool, error) {
 	if len(username) > 0 {
-		user, err := getUser(src, sq.Eq{"username": username})
-		if err != nil {
-			return false, err
-		}
-		if user != nil {
-			return true, nil
+		taken, err := exists(src, usernameEq(username), includeDeleted)
+		if err != nil || taken {
+			return taken, err
 		}
 	}
 	if len(email) > 0 {
-		user, err := getUser(src, sq.Eq{"email": email})
-		if err != nil {
-			return false, err
-		}
-		if user != nil {
-			return true, nil
-		}
+		return exists(src, emailTaken(email), includeDeleted)
 	}
 	return false, nil
 }
 
+func exists(src sqlx.Queryer, cond sq.Sqlizer, includeDeleted bool) (bool, error) {
+	list := listUsersByCond
+	if includeDeleted {
+		list = selectUsers
+	}
+	users, err := list(src, columns, cond)
+	if err != nil {
+		return false, err
+	}
+	return len(users) > 0, nil
+}
+
 func GetUserByEmail(src sqlx.Queryer, email string) (*User, error) {
-	user, err := getUser(src, sq.Eq{"email": email})
+	return GetUserByEmailContext(context.Background(), utils.QueryerContext(src), email)
+}
+
+// GetUserByEmailContext 通过主邮箱（user.email）或已验证的其他邮箱（user_email）获取用户
+func GetUserByEmailContext(ctx context.Context, src sqlx.QueryerContext, email string) (*User, error) {
+	user, err := getUserContext(ctx, src, sq.Or{emailEq(email), verifiedEmailEq(email)})
+	return user, err
+}
+
+// GetUserByEmailOrUsername 通过登录标识获取用户（登录等同时接受邮箱和用户名的场景）
+// 用户名不允许包含@，所以包含@的一定是邮箱；两者均不区分大小写
+func GetUserByEmailOrUsername(src sqlx.Queryer, identifier string) (*User, error) {
+	if strings.Contains(identifier, "@") {
+		return GetUserByEmail(src, identifier)
+	}
+	return GetUserByUsername(src, identifier)
+}
+
+// GetUserByPublicEmail 通过公开邮箱获取用户（例如将commit的作者邮箱匹配到用户）
+// 只匹配 public_email，不会通过私有邮箱找到用户
+func GetUserByPublicEmail(src sqlx.Queryer, email string) (*User, error) {
+	if len(email) == 0 {
+		return nil, nil
+	}
+	user, err := getUser(src, sq.Expr("LOWER(public_email) = ?", strings.ToLower(email)))
+	return user, err
+}
+
+// GetInactivatedUserByEmail 未激活的用户
+func GetInactivatedUserByEmail(src sqlx.Queryer, email string) (*User, error) {
+	user, err := getUser(src, sq.And{emailEq(email), InactivateUser})
 	return user, err
 }
 
 func GetUserByUsername(src sqlx.Queryer, username string) (*User, error) {
-	user, err := getUser(src, sq.Eq{"username": username})
+	return GetUserByUsernameContext(context.Background(), utils.QueryerContext(src), username)
+}
+
+func GetUserByUsernameContext(ctx context.Context, src sqlx.QueryerContext, username string) (*User, error) {
+	user, err := getUserContext(ctx, src, usernameEq(username))
 	return user, err
 }
 
+// usernameEq、emailEq 用户名和邮箱均不区分大小写
+// 显式使用 LOWER()，避免依赖数据库列的排序规则（collation）
+func usernameEq(username string) sq.Sqlizer {
+	return sq.Expr("LOWER(username) = ?", strings.ToLower(username))
+}
+
+func emailEq(email string) sq.Sqlizer {
+	return sq.Expr("LOWER(email) = ?", strings.ToLower(email))
+}
+
+// emailTaken 唯一性检查：原始邮箱相同，或归一化后相同（例如 user+spam@gmail.com 与 user@gmail.com）
+// 归一化之前注册的用户 normalized_email 为空，只能通过原始邮箱匹配
+// 其他用户已验证的邮箱（user_email）同样视为已占用
+func emailTaken(email string) sq.Sqlizer {
+	return sq.Or{emailEq(email), sq.Eq{"normalized_email": emailutil.Normalize(email)}, verifiedEmailEq(email)}
+}
+
 func GetUser(src sqlx.Queryer, id int64) (*User, error) {
-	user, err := getUser(src, sq.Eq{"id": id})
+	return GetUserContext(context.Background(), utils.QueryerContext(src), id)
+}
+
+func GetUserContext(ctx context.Context, src sqlx.QueryerContext, id int64) (*User, error) {
+	user, err := getUserContext(ctx, src, sq.Eq{"id": id})
 	return user, err
 }
 
+// GetUserByNamespaceID 获取个人命名空间所属的用户
+// 组织的命名空间不会被任何用户的 namespace_id 引用，此时返回nil
+func GetUserByNamespaceID(src sqlx.Queryer, namespaceID int64) (*User, error) {
+	return getUser(src, sq.Eq{"namespace_id": namespaceID})
+}
+
+// GetUserIncludingDeleted 同 GetUser，但包含已软删除的用户（deleted_at 非空）
+// 仅用于管理员工具（排查问题、恢复账号），其他场景应使用 GetUser
+func GetUserIncludingDeleted(src sqlx.Queryer, id int64) (*User, error) {
+	users, err := selectUsers(src, columns, sq.Eq{"id": id})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) > 0 {
+		return users[0], nil
+	}
+	return nil, nil
+}
+
+// GetUsersByIDs 批量获取用户（一次查询），返回以用户id为key的map
+func GetUsersByIDs(src sqlx.Queryer, ids []int64) (map[int64]*User, error) {
+	result := make(map[int64]*User, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	// 去重
+	idSet := make(map[int64]struct{}, len(ids))
+	uniqIDs := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := idSet[id]; ok {
+			continue
+		}
+		idSet[id] = struct{}{}
+		uniqIDs = append(uniqIDs, id)
+	}
+
+	users, err := listUsersByCond(src, columns, sq.Eq{"id": uniqIDs})
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		result[u.ID] = u
+	}
+	return result, nil
+}
+
 func getUser(src sqlx.Queryer, cond sq.Sqlizer) (*User, error) {
-	users, err := listUsersByCond(src, columns, cond)
+	return getUserContext(context.Background(), utils.QueryerContext(src), cond)
+}
+
+func getUserContext(ctx context.Context, src sqlx.QueryerContext, cond sq.Sqlizer) (*User, error) {
+	users, err := listUsersByCondContext(ctx, src, columns, cond)
 	if err != nil {
 		return nil, err
 	}
@@ -109,22 +383,37 @@ func getUser(src sqlx.Queryer, cond sq.Sqlizer) (*User, error) {
 }
 
 func listUsersByCond(src sqlx.Queryer, tableColumns []string, cond sq.Sqlizer) ([]*User, error) {
+	return listUsersByCondContext(context.Background(), utils.QueryerContext(src), tableColumns, cond)
+}
+
+func listUsersByCondContext(ctx context.Context, src sqlx.QueryerContext, tableColumns []string, cond sq.Sqlizer) ([]*User, error) {
+	return selectUsersContext(ctx, src, tableColumns, sq.And{cond, NormalUser})
+}
+
+// selectUsers 不附加 NormalUser 条件
+func selectUsers(src sqlx.Queryer, tableColumns []string, cond sq.Sqlizer) ([]*User, error) {
+	return selectUsersContext(context.Background(), utils.QueryerContext(src), tableColumns, cond)
+}
+
+func selectUsersContext(ctx context.Context, src sqlx.QueryerContext, tableColumns []string, cond sq.Sqlizer) ([]*User, error) {
 	sql, args, _ := sq.Select(tableColumns...).
 		From(tableNameMark).
-		Where(sq.And{cond, NormalUser}).
+		Where(cond).
 		ToSql()
 
 	result := make([]*User, 0)
-	err := sqlx.Select(src, &result, sql, args...)
+	err := sqlx.SelectContext(ctx, src, &result, sql, args...)
 	if err != nil {
 		return nil, errors.SQLError(err)
 	}
 	return result, nil
 }
 
+// ActivateUser 激活用户（邮箱已验证），并默认公开主邮箱
 func ActivateUser(tx sqlx.Execer, userID int64) error {
 	sql, args, _ := sq.Update(tableNameMark).
 		Set("verified_at", time.Now().Unix()).
+		Set("public_email", sq.Expr("email")).
 		Where(sq.And{sq.Eq{"id": userID}, InactivateUser}).
 		ToSql()
 
@@ -135,6 +424,39 @@ func ActivateUser(tx sqlx.Execer, userID int64) error {
 	return nil
 }
 
+// SoftDeleteUser 软删除用户
+// 软删除后 NormalUser 条件将过滤掉该用户（GetUser、GetUserByEmail、登录等均无法再获取到）
+// 注意：用户名不会被释放，用户的 namespace.path 是唯一的，且仓库地址依赖它，保留它也便于恢复账号
+func SoftDeleteUser(tx sqlx.Execer, userID int64) error {
+	where := sq.And{sq.Eq{"id": userID}, NormalUser}
+	valueMap := map[string]interface{}{
+		"deleted_at": time.Now().Unix(),
+	}
+	return update(tx, where, valueMap)
+}
+
+// RestoreUser 恢复已软删除的用户（清除 deleted_at）
+// 调用者需要先确认邮箱、用户名没有被其他用户占用；用户未被删除（例如已被其他请求恢复）时返回 ErrConflict
+func RestoreUser(tx sqlx.Execer, userID int64) error {
+	sql, args, _ := sq.Update(tableNameMark).
+		Set("deleted_at", nil).
+		Where(sq.And{sq.Eq{"id": userID}, sq.NotEq{"deleted_at": nil}}).
+		ToSql()
+
+	result, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	if n == 0 {
+		return errors.Trace(ErrConflict)
+	}
+	return nil
+}
+
 func ListAllUsers(src sqlx.Queryer, page, per uint64) ([]*User, error) {
 	users := make([]*User, 0)
 
@@ -150,11 +472,333 @@ func ListAllUsers(src sqlx.Queryer, page, per uint64) ([]*User, error) {
 	return users, errors.SQLError(err)
 }
 
+// ListAllUsersWithNamespaces 同 ListAllUsers，并通过 WithNamespaces 批量获取用户的命名空间
+func ListAllUsersWithNamespaces(src sqlx.Queryer, page, per uint64) ([]*User, error) {
+	users, err := ListAllUsers(src, page, per)
+	if err != nil {
+		return nil, err
+	}
+	if err = WithNamespaces(src, users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CountUsers 用户总数，与 ListAllUsers 使用相同的 NormalUser 条件
+func CountUsers(src sqlx.Queryer) (int64, error) {
+	return CountUsersByCond(src, nil)
+}
+
+// CountUsersByCond 满足条件的用户数（始终附加 NormalUser 条件），cond 为nil时统计全部
+func CountUsersByCond(src sqlx.Queryer, cond sq.Sqlizer) (int64, error) {
+	where := sq.And{NormalUser}
+	if cond != nil {
+		where = append(where, cond)
+	}
+	sql, args, _ := sq.Select("COUNT(*)").
+		From(tableNameMark).
+		Where(where).
+		ToSql()
+
+	var count int64
+	err := src.QueryRowx(sql, args...).Scan(&count)
+	if err != nil {
+		return 0, errors.SQLError(err)
+	}
+	return count, nil
+}
+
+// ListUsersAfter 基于游标（用户id）的分页
+// afterID 为上一页最后一个用户的id，首页传0
+// 返回的用户按id升序排列，调用者可将最后一个用户的id作为下一页的游标
+func ListUsersAfter(src sqlx.Queryer, afterID int64, per uint64) ([]*User, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(tableNameMark).
+		Where(sq.And{sq.Gt{"id": afterID}, NormalUser}).
+		OrderBy("id ASC").
+		Limit(per).
+		ToSql()
+
+	users := make([]*User, 0)
+	err := sqlx.Select(src, &users, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return users, nil
+}
+
+// SearchUsers 按用户名或名称的前缀搜索用户（不区分大小写）
+// 排序：用户名完全匹配 > 用户名前缀匹配（按用户名排序） > 名称前缀匹配
+func SearchUsers(src sqlx.Queryer, query string, limit uint64) ([]*User, error) {
+	return searchUsers(src, query, limit, false)
+}
+
+// SearchUsersWithEmail 同 SearchUsers，同时匹配包含 query 的邮箱（例如 @example.com）
+// 邮箱是私有信息，只能用于管理员
+func SearchUsersWithEmail(src sqlx.Queryer, query string, limit uint64) ([]*User, error) {
+	return searchUsers(src, query, limit, true)
+}
+
+func searchUsers(src sqlx.Queryer, query string, limit uint64, withEmail bool) ([]*User, error) {
+	query = strings.ToLower(query)
+	prefix := utils.EscapeLike(query) + "%"
+
+	match := sq.Or{
+		sq.Expr("LOWER(username) LIKE ?", prefix),
+		sq.Expr("LOWER(name) LIKE ?", prefix),
+	}
+	if withEmail {
+		match = append(match, sq.Expr("LOWER(email) LIKE ?", "%"+utils.EscapeLike(query)+"%"))
+	}
+
+	sql, args, _ := sq.Select(columns...).
+		From(tableNameMark).
+		Where(sq.And{match, NormalUser}).
+		// squirrel 的 OrderBy 不支持参数，所以排序和分页放在 Suffix 中
+		Suffix("ORDER BY CASE WHEN LOWER(username) = ? THEN 0 WHEN LOWER(username) LIKE ? THEN 1 ELSE 2 END, username ASC LIMIT ?",
+			query, prefix, limit).
+		ToSql()
+
+	users := make([]*User, 0)
+	err := sqlx.Select(src, &users, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return users, nil
+}
+
+// ipEq 注册IP或最后登录IP为 ip
+func ipEq(ip string) sq.Sqlizer {
+	return sq.Or{sq.Eq{"register_ip": ip}, sq.Eq{"last_login_ip": ip}}
+}
+
+// FindByIP 注册IP或最后登录IP为 ip 的用户（page从0开始，按id排序），用于管理员排查滥用
+// 共享的NAT出口IP可能对应大量用户，所以必须分页
+func FindByIP(src sqlx.Queryer, ip string, page, per uint64) ([]*User, error) {
+	sql, args, _ := sq.Select(columns...).
+		From(tableNameMark).
+		Where(sq.And{ipEq(ip), NormalUser}).
+		OrderBy("id").
+		Limit(per).
+		Offset(page * per).
+		ToSql()
+
+	users := make([]*User, 0)
+	err := sqlx.Select(src, &users, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	return users, nil
+}
+
+// CountByIP 与 FindByIP 条件相同的用户数
+func CountByIP(src sqlx.Queryer, ip string) (int64, error) {
+	return CountUsersByCond(src, ipEq(ip))
+}
+
+// SetActive 停用/恢复用户（管理员操作）
+// 停用使用单独的 suspended_at 字段，不影响 verified_at 的邮箱验证语义
+func SetActive(tx sqlx.Execer, userID int64, active bool) error {
+	where := sq.Eq{"id": userID}
+	valueMap := map[string]interface{}{
+		"suspended_at": nil,
+	}
+	if !active {
+		valueMap["suspended_at"] = time.Now().Unix()
+	}
+	return update(tx, where, valueMap)
+}
+
+// SetTOTPSecret 保存（加密后的）TOTP密钥，此时尚未启用，需要 EnableTOTP 确认
+// 已启用TOTP的用户不会被修改
+func SetTOTPSecret(tx sqlx.Execer, userID int64, encryptedSecret string) error {
+	where := sq.And{sq.Eq{"id": userID}, sq.Eq{"totp_enabled_at": nil}}
+	valueMap := map[string]interface{}{
+		"totp_secret": encryptedSecret,
+	}
+	return update(tx, where, valueMap)
+}
+
+func EnableTOTP(tx sqlx.Execer, userID int64) error {
+	where := sq.And{sq.Eq{"id": userID}, sq.NotEq{"totp_secret": nil}}
+	valueMap := map[string]interface{}{
+		"totp_enabled_at": time.Now().Unix(),
+	}
+	return update(tx, where, valueMap)
+}
+
+// UseTOTPStep 记录已使用的TOTP时间步，step 不晚于上次记录的时间步时返回false（code被重放）
+// 比较和更新在同一条sql中完成，并发提交同一个code时只有一个能成功
+func UseTOTPStep(tx sqlx.Execer, userID, step int64) (bool, error) {
+	sql, args, _ := sq.Update(tableNameMark).
+		Set("totp_last_step", step).
+		Where(sq.And{
+			sq.Eq{"id": userID},
+			sq.Or{sq.Eq{"totp_last_step": nil}, sq.Lt{"totp_last_step": step}},
+		}).
+		ToSql()
+
+	result, err := tx.Exec(sql, args...)
+	if err != nil {
+		return false, errors.SQLError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.SQLError(err)
+	}
+	return n > 0, nil
+}
+
 func UpdateLogin(tx sqlx.Execer, userID int64, clientIP string) error {
 	where := sq.Eq{"id": userID}
 	valueMap := map[string]interface{}{
-		"last_login_at": time.Now().Unix(),
-		"last_login_ip": clientIP,
+		"last_login_at":      time.Now().Unix(),
+		"last_login_ip":      clientIP,
+		"failed_login_count": 0,
+		"first_failed_at":    nil,
+		"locked_until":       nil,
+	}
+	return update(tx, where, valueMap)
+}
+
+// UpdateLoginFailed 记录一次登录失败
+// 第一次失败早于 windowStart 时重新计数；窗口内连续失败次数达到 maxAttempts 时，将账号锁定至 lockedUntil，并重新计数
+// 是否锁定由数据库根据当前的计数决定（而不是读取到的用户），并发的登录失败不会超出次数上限
+func UpdateLoginFailed(tx sqlx.Execer, userID int64, maxAttempts int, now, windowStart, lockedUntil int64) error {
+	// 本次失败之后的计数（超出窗口时从1开始）
+	const count = "IF(first_failed_at IS NULL OR first_failed_at < ?, 1, failed_login_count + 1)"
+
+	// MySQL按顺序执行SET，之后的表达式读取的是已修改的值：
+	// locked_until、failed_login_count 使用修改前的计数，first_failed_at 根据修改后的计数决定（0为已锁定，1为新的窗口）
+	sql, args, _ := sq.Update(tableNameMark).
+		Set("locked_until", sq.Expr("IF("+count+" >= ?, ?, locked_until)", windowStart, maxAttempts, lockedUntil)).
+		Set("failed_login_count", sq.Expr("IF("+count+" >= ?, 0, "+count+")", windowStart, maxAttempts, windowStart)).
+		Set("first_failed_at", sq.Expr("CASE failed_login_count WHEN 0 THEN NULL WHEN 1 THEN ? ELSE first_failed_at END", now)).
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// UpdatePassword 用户修改（或重置）密码，同时记录修改时间
+// 修改时间之前创建的session不再有效（见 PasswordChangedCond）
+func UpdatePassword(tx sqlx.Execer, userID int64, encryptedPassword string) error {
+	where := sq.Eq{"id": userID}
+	valueMap := map[string]interface{}{
+		"encrypted_password":  encryptedPassword,
+		"password_changed_at": clk.Now().Unix(),
+	}
+	return update(tx, where, valueMap)
+}
+
+// RehashPassword 使用新的算法或参数重新生成同一个密码的hash，不影响已有的session
+func RehashPassword(tx sqlx.Execer, userID int64, encryptedPassword string) error {
+	where := sq.Eq{"id": userID}
+	valueMap := map[string]interface{}{
+		"encrypted_password": encryptedPassword,
+	}
+	return update(tx, where, valueMap)
+}
+
+// SetInitialPassword 没有密码的用户（通过第三方账号注册）首次设置密码，不影响已有的session
+// 用户已设置了密码时不做修改
+func SetInitialPassword(tx sqlx.Execer, userID int64, encryptedPassword string) error {
+	where := sq.Eq{"id": userID, "encrypted_password": ""}
+	valueMap := map[string]interface{}{
+		"encrypted_password": encryptedPassword,
+	}
+	return update(tx, where, valueMap)
+}
+
+// UpdateEmail 更新用户的主邮箱
+// 仅在新邮箱验证通过后调用，所以同时更新 verified_at
+// version 为读取用户时的版本号，期间用户被其他请求修改时返回 ErrConflict
+func UpdateEmail(tx sqlx.Execer, userID, version int64, email string) error {
+	valueMap := map[string]interface{}{
+		"email":            email,
+		"normalized_email": emailutil.Normalize(email),
+		"verified_at":      time.Now().Unix(),
+	}
+	return UpdateUserVersioned(tx, userID, version, valueMap)
+}
+
+// UpdateUsername 修改用户名，并记录修改时间（用于限制修改的频率），version 同 UpdateEmail
+func UpdateUsername(tx sqlx.Execer, userID, version int64, username string) error {
+	valueMap := map[string]interface{}{
+		"username":                username,
+		"last_username_change_at": clk.Now().Unix(),
+	}
+	return UpdateUserVersioned(tx, userID, version, valueMap)
+}
+
+// ResetUsernameChangeCooldown 清除用户名的修改时间，允许用户立即再次修改（管理员操作）
+func ResetUsernameChangeCooldown(tx sqlx.Execer, userID int64) error {
+	where := sq.And{sq.Eq{"id": userID}, NormalUser}
+	valueMap := map[string]interface{}{
+		"last_username_change_at": nil,
+	}
+	return update(tx, where, valueMap)
+}
+
+// duplicateError 将违反 email、username 唯一约束的错误转换为 AlreadyExists（reason 区分 Email、Username）
+// 并发注册时，两个请求可能都通过了 ExistsEmailOrUsername 的检查，由唯一约束保证只有一个成功
+func duplicateError(err error) error {
+	key, ok := db.DuplicateKey(err)
+	if !ok {
+		return err
+	}
+	switch key {
+	case "unq_email":
+		return errors.AlreadyExistsError(errors.User, errors.Email)
+	case "unq_username":
+		return errors.AlreadyExistsError(errors.User, errors.Username)
+	default:
+		return errors.AlreadyExistsError(errors.User, errors.AlreadyExists)
+	}
+}
+
+// ErrConflict 乐观锁冲突，见 UpdateUserVersioned
+var ErrConflict = errors.New("user: version conflict")
+
+// UpdateUserVersioned 仅当数据库中的 version 与传入的一致时更新，并将 version 加1
+// 没有更新任何行（用户不存在或已被其他请求修改）时返回 ErrConflict
+// 用于修改邮箱、用户名，避免并发修改时后写入的覆盖先写入的
+func UpdateUserVersioned(tx sqlx.Execer, userID, version int64, valueMap map[string]interface{}) error {
+	setMap := make(map[string]interface{}, len(valueMap)+1)
+	for k, v := range valueMap {
+		setMap[k] = v
+	}
+	setMap["version"] = sq.Expr("version + 1")
+
+	sql, args, _ := sq.Update(tableNameMark).
+		SetMap(setMap).
+		Where(sq.And{sq.Eq{"id": userID}, sq.Eq{"version": version}}).
+		ToSql()
+
+	result, err := tx.Exec(sql, args...)
+	if err != nil {
+		return duplicateError(errors.SQLError(err))
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	if n == 0 {
+		return errors.Trace(ErrConflict)
+	}
+	return nil
+}
+
+// UpdateProfile 修改用户的昵称和公开邮箱（可为空）
+func UpdateProfile(tx sqlx.Execer, userID int64, name, publicEmail string) error {
+	where := sq.Eq{"id": userID}
+	valueMap := map[string]interface{}{
+		"name":         name,
+		"public_email": publicEmail,
 	}
 	return update(tx, where, valueMap)
 }
@@ -180,18 +824,79 @@ func update(tx sqlx.Execer, cond sq.Sqlizer, valueMap map[string]interface{}) er
 	return nil
 }
 
+// GetUserByUserToken 通过session token获取用户，session已过期、用户已注销或被停用时返回nil
 func GetUserByUserToken(src sqlx.Queryer, userToken string) (*User, error) {
+	return GetUserByUserTokenContext(context.Background(), utils.QueryerContext(src), userToken)
+}
+
+func GetUserByUserTokenContext(ctx context.Context, src sqlx.QueryerContext, userToken string) (*User, error) {
 	sessTableName := session.TableName
 	joinColumns := utils.SqlColumnsComplementTable(tableNameMark, columns...)
+	validSQL, validArgs, _ := session.ValidCond(sessTableName+".expired_at", clk.Now().Unix()).ToSql()
 	sql, args, _ := sq.Select(joinColumns...).
 		From(tableNameMark).
-		Join(fmt.Sprintf("%s ON %s.token = ? AND %s.expired_at >= ?", sessTableName, sessTableName, sessTableName),
-			userToken, time.Now().Unix()).
+		Join(fmt.Sprintf("%s ON %s.token = ? AND %s", sessTableName, sessTableName, validSQL),
+			append([]interface{}{userToken}, validArgs...)...).
 		Where(fmt.Sprintf("%s.id = %s.owner_id", tableNameMark, sessTableName)).
+		// 已注销或被停用的用户，即使session仍未过期也不能认证
+		Where(ActiveUserOf(tableNameMark)).
+		// 修改密码之前创建的session，即使没有被删除也不能认证
+		Where(PasswordChangedCond(tableNameMark, sessTableName)).
 		ToSql()
 
 	users := make([]*User, 0, 1)
 
+	err := sqlx.SelectContext(ctx, src, &users, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	if len(users) > 0 {
+		return users[0], nil
+	}
+	return nil, nil
+}
+
+// PasswordChangedCond session 在用户最近一次修改密码之后（含同一秒）创建的sql条件，与 User.AcceptsSession 一致
+// 从未修改过密码（password_changed_at 为空）的用户不限制
+func PasswordChangedCond(userTable, sessTable string) sq.Sqlizer {
+	return sq.Expr(fmt.Sprintf("(%s.password_changed_at IS NULL OR %s.created_at >= %s.password_changed_at)",
+		userTable, sessTable, userTable))
+}
+
+// GetUserByAccessToken 通过个人访问令牌获取用户
+// 令牌已撤销、已过期或不包含 scopes 中的任意一个权限时，返回nil
+func GetUserByAccessToken(src sqlx.Queryer, token string, scopes ...string) (*User, *accesstoken.AccessToken, error) {
+	t, err := accesstoken.GetAccessTokenByHash(src, secret.HashToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	if t == nil || !t.Valid(clk.Now().Unix()) || !t.HasScopes(scopes...) {
+		return nil, nil, nil
+	}
+
+	user, err := GetUser(src, t.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil || user.Suspended() {
+		return nil, nil, nil
+	}
+	return user, t, nil
+}
+
+// GetUserBySSHKeyFingerprint 通过ssh公钥的指纹获取用户（用于git ssh服务认证）
+func GetUserBySSHKeyFingerprint(src sqlx.Queryer, fingerprint string) (*User, error) {
+	keyTableName := sshkey.TableName
+	joinColumns := utils.SqlColumnsComplementTable(tableNameMark, columns...)
+	sql, args, _ := sq.Select(joinColumns...).
+		From(tableNameMark).
+		Join(fmt.Sprintf("%s ON %s.fingerprint = ?", keyTableName, keyTableName), fingerprint).
+		Where(fmt.Sprintf("%s.id = %s.user_id", tableNameMark, keyTableName)).
+		// 已删除或被停用的用户不能通过ssh认证
+		Where(ActiveUserOf(tableNameMark)).
+		ToSql()
+
+	users := make([]*User, 0, 1)
 	err := sqlx.Select(src, &users, sql, args...)
 	if err != nil {
 		return nil, errors.SQLError(err)
@@ -202,35 +907,116 @@ func GetUserByUserToken(src sqlx.Queryer, userToken string) (*User, error) {
 	return nil, nil
 }
 
+// TokenOption 通过token获取用户（GetUserByUserTokenFromIP）时的可选项
+type TokenOption func(*tokenOptions)
+
+type tokenOptions struct {
+	namespace bool
+}
+
+// FillNamespace 同时获取用户的命名空间，之后调用 User.Namespace() 不再查询数据库（例如需要立即使用path的中间件）
+// 默认不获取，避免不需要命名空间的请求多一次查询；获取后与用户一起缓存
+func FillNamespace() TokenOption {
+	return func(o *tokenOptions) { o.namespace = true }
+}
+
+// GetUserByUserTokenFromIP 同 GetUserByUserToken，但对于绑定了IP的session，
+// 要求 clientIP 与登录时的IP属于同一网络，否则返回nil
+// 结果会被缓存一段时间（见 UserTokenCache、配置 session.token_cache_seconds），修改用户或注销session后需要调用 InvalidateUserTokens、InvalidateToken
+func GetUserByUserTokenFromIP(src sqlx.Queryer, userToken, clientIP string, opts ...TokenOption) (*User, error) {
+	return GetUserByUserTokenFromIPContext(context.Background(), utils.QueryerContext(src), userToken, clientIP, opts...)
+}
+
+func GetUserByUserTokenFromIPContext(
+	ctx context.Context,
+	src sqlx.QueryerContext,
+	userToken, clientIP string,
+	opts ...TokenOption,
+) (*User, error) {
+	var o tokenOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if user, ok := getCachedToken(userToken, clientIP, clk.Now().Unix()); ok {
+		if !o.namespace || user.ns != nil {
+			return user, nil
+		}
+		// 缓存时没有获取命名空间
+		if err := WithNamespacesContext(ctx, src, []*User{user}); err != nil {
+			return nil, err
+		}
+		cacheNamespace(userToken, user.ns)
+		return user, nil
+	}
+
+	sess, err := session.GetSessionByTokenContext(ctx, src, userToken)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		return nil, nil
+	}
+	if sess.BindIP && !session.SameNetwork(sess.ClientIP, clientIP) {
+		return nil, nil
+	}
+	user, err := GetUserByUserTokenContext(ctx, src, userToken)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, nil
+	}
+	if o.namespace {
+		if err = WithNamespacesContext(ctx, src, []*User{user}); err != nil {
+			return nil, err
+		}
+	}
+	setCachedToken(userToken, sess, user)
+	return user, nil
+}
+
+// ListAdminUsers 拥有任意管理后台角色的用户
 func ListAdminUsers(src sqlx.Queryer) ([]*User, error) {
 	where := sq.And{
-		sq.Eq{"is_admin": true},
+		sq.NotEq{"roles": ""},
 	}
 	users, err := listUsersByCond(src, columns, where)
 	if err != nil {
 		return nil, err
 	}
-	err = fillNamespaceInUsers(src, users)
+	err = WithNamespaces(src, users)
 	if err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
-func fillNamespaceInUsers(src sqlx.Queryer, users []*User) error {
-	userIDs := make([]int64, 0)
-	userMap := make(map[int64]*User)
+// WithNamespaces 一次查询获取 users 的命名空间并缓存到用户中，之后调用 User.Namespace() 不再查询数据库
+// 用于列表等需要每个用户命名空间（path）的场景，避免 N+1 查询
+func WithNamespaces(src sqlx.Queryer, users []*User) error {
+	return WithNamespacesContext(context.Background(), utils.QueryerContext(src), users)
+}
+
+func WithNamespacesContext(ctx context.Context, src sqlx.QueryerContext, users []*User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	userIDs := make([]int64, 0, len(users))
+	userMap := make(map[int64]*User, len(users))
 	for _, u := range users {
 		userIDs = append(userIDs, u.ID)
 		userMap[u.ID] = u
 	}
-	ns, err := namespace.ListNamespacesByOwner(src, namespace.TypeUser, userIDs...)
+	ns, err := namespace.ListNamespacesByOwnerContext(ctx, src, namespace.TypeUser, userIDs...)
 	if err != nil {
 		return err
 	}
 	// fill
 	for _, n := range ns {
-		userMap[n.OwnerID].ns = n
+		if u, ok := userMap[n.OwnerID]; ok && u.NamespaceID == n.ID {
+			u.ns = n
+		}
 	}
 	return nil
 }
@@ -0,0 +1,77 @@
+package user
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckBatchDuplicates(t *testing.T) {
+	users := []*User{
+		{Email: "a@example.com", Username: "alice"},
+		{Email: "b@example.com", Username: "bob"},
+	}
+	assert.Nil(t, checkBatchDuplicates(users))
+
+	dupEmail := append(users, &User{Email: "A@example.com", Username: "carol"})
+	assert.Equal(t, errors.P(errors.User, errors.Email, errors.AlreadyExists).Error(), checkBatchDuplicates(dupEmail).Error())
+
+	dupUsername := append(users, &User{Email: "c@example.com", Username: "Bob"})
+	assert.Equal(t, errors.P(errors.User, errors.Username, errors.AlreadyExists).Error(), checkBatchDuplicates(dupUsername).Error())
+}
+
+func TestDuplicateError(t *testing.T) {
+	dup := func(key string) error {
+		return errors.SQLError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'x' for key '" + key + "'"})
+	}
+	assert.Equal(t, errors.AlreadyExistsError(errors.User, errors.Email).Error(), duplicateError(dup("user.unq_email")).Error())
+	assert.Equal(t, errors.AlreadyExistsError(errors.User, errors.Username).Error(), duplicateError(dup("user.unq_username")).Error())
+	other := errors.SQLError(errors.New("other"))
+	assert.Equal(t, other, duplicateError(other))
+}
+
+// execRecorder 记录执行的sql和参数
+type execRecorder struct {
+	query  string
+	args   []interface{}
+	result sql.Result
+}
+
+func (r *execRecorder) Exec(query string, args ...interface{}) (sql.Result, error) {
+	r.query, r.args = query, args
+	return r.result, nil
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	r := &execRecorder{result: driver.RowsAffected(1)}
+	ok, err := UseTOTPStep(r, 7, 100)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Contains(t, r.query, "WHERE (id = ? AND (totp_last_step IS NULL OR totp_last_step < ?))")
+	assert.Equal(t, []interface{}{int64(100), int64(7), int64(100)}, r.args)
+
+	// 时间步不晚于已记录的值时不会更新任何行
+	r.result = driver.RowsAffected(0)
+	ok, err = UseTOTPStep(r, 7, 100)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestUpdateLoginFailed(t *testing.T) {
+	r := &execRecorder{}
+	assert.Nil(t, UpdateLoginFailed(r, 7, 5, 2000, 1100, 2900))
+
+	// 是否锁定由数据库中的计数决定；first_failed_at 必须在 failed_login_count 之后设置（读取修改后的计数）
+	set := r.query[strings.Index(r.query, "SET"):strings.Index(r.query, "WHERE")]
+	lockedAt := strings.Index(set, "locked_until =")
+	countAt := strings.Index(set, "failed_login_count =")
+	firstAt := strings.Index(set, "first_failed_at =")
+	assert.True(t, lockedAt >= 0 && lockedAt < countAt && countAt < firstAt, r.query)
+	assert.Equal(t, []interface{}{int64(1100), 5, int64(2900), int64(1100), 5, int64(1100), int64(2000), int64(7)}, r.args)
+}
@@ -0,0 +1,53 @@
+package user
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+var historyTableName = "username_history"
+var historyColumns = []string{
+	"id",
+	"user_id",
+	"username",
+	"created_at",
+}
+
+func AddUsernameHistory(tx sqlx.Execer, userID int64, oldUsername string) error {
+	sql, args, _ := sq.Insert(historyTableName).
+		Columns(historyColumns[1:]...).
+		Values(
+			userID,
+			oldUsername,
+			time.Now().Unix(),
+		).ToSql()
+
+	_, err := tx.Exec(sql, args...)
+	if err != nil {
+		return errors.SQLError(err)
+	}
+	return nil
+}
+
+// GetLatestUsernameHistory 最近一次使用过该用户名的记录（用于旧地址跳转）
+func GetLatestUsernameHistory(src sqlx.Queryer, username string) (*UsernameHistory, error) {
+	sql, args, _ := sq.Select(historyColumns...).
+		From(historyTableName).
+		Where(usernameEq(username)).
+		OrderBy("created_at DESC").
+		Limit(1).
+		ToSql()
+
+	result := make([]*UsernameHistory, 0, 1)
+	err := sqlx.Select(src, &result, sql, args...)
+	if err != nil {
+		return nil, errors.SQLError(err)
+	}
+	if len(result) > 0 {
+		return result[0], nil
+	}
+	return nil, nil
+}
@@ -1,5 +1,13 @@
 package utils
 
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
 // 需要pgsql执行完sql后返回的字段
 // http://www.postgresql.org/docs/current/static/sql-insert.html
 // http://www.postgresql.org/docs/current/static/sql-update.html
@@ -22,3 +30,42 @@ func SqlColumnsComplementTable(table string, columns ...string) []string {
 	}
 	return result
 }
+
+// EscapeLike 转义 LIKE 中的通配符（% _）以及转义符本身，使用户输入按字面匹配
+// MySQL 中 LIKE 默认的转义符为 \
+func EscapeLike(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '%', '_':
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// QueryerContext 将 sqlx.Queryer 转换为 sqlx.QueryerContext
+// 供不带 context 的旧函数调用对应的 *Context 版本；src 不支持 context 时，忽略 ctx
+func QueryerContext(src sqlx.Queryer) sqlx.QueryerContext {
+	if q, ok := src.(sqlx.QueryerContext); ok {
+		return q
+	}
+	return &queryerContext{src}
+}
+
+type queryerContext struct {
+	sqlx.Queryer
+}
+
+func (q *queryerContext) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return q.Query(query, args...)
+}
+
+func (q *queryerContext) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	return q.Queryx(query, args...)
+}
+
+func (q *queryerContext) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	return q.QueryRowx(query, args...)
+}
@@ -8,32 +8,161 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/growerlab/backend/app/common/notify"
 	"github.com/growerlab/backend/app/controller"
+	"github.com/growerlab/backend/app/model/accesstoken"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	userModel "github.com/growerlab/backend/app/model/user"
 	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/growerlab/backend/app/utils/logger"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func Run(addr string) error {
 	engine := gin.Default()
 
-	engine.Use(controller.CORSForLocal)
+	engine.Use(controller.RequestID, controller.Locale, controller.ErrorHandler, controller.CORSForLocal)
 
-	apiV1 := engine.Group("/api/v1", controller.LimitGETRequestBody)
+	// 健康检查（不经过session等中间件）
+	engine.GET("/healthz", controller.Liveness)
+	engine.GET("/readyz", controller.Readiness)
+
+	apiV1 := engine.Group("/api/v1", controller.LimitGETRequestBody, controller.RefreshSession)
 	repositories := apiV1.Group("/repositories")
 	{
-		repositories.POST("/:namespace/create", controller.CreateRepository)
+		repositories.POST("/:namespace/create", controller.AuthRequiredWithScopes(accesstoken.ScopeRepoWrite), controller.RequireVerified(), controller.CreateRepository)
 		repositories.GET("/:namespace/list", controller.Repositories)
 		repositories.GET("/:namespace/detail/:name", controller.Repository)
 	}
 
-	auth := apiV1.Group("/auth")
+	orgs := apiV1.Group("/orgs", controller.AuthRequired())
+	{
+		orgs.POST("/create", controller.CreateOrganization)
+		orgs.POST("/members/add", controller.AddOrganizationMember)
+		orgs.POST("/members/remove", controller.RemoveOrganizationMember)
+		orgs.GET("/:path/members", controller.OrganizationMembers)
+	}
+
+	users := apiV1.Group("/users", controller.AuthRequiredWithScopes(accesstoken.ScopeUser))
+	{
+		users.GET("/search", controller.SearchUsers)
+	}
+
+	namespaces := apiV1.Group("/namespaces")
+	{
+		namespaces.GET("/check", controller.CheckNamespaceAvailable)
+		namespaces.GET("/reserved", controller.ReservedNamespaces)
+		namespaces.POST("/rename", controller.AuthRequired(), controller.RenameNamespace)
+		namespaces.POST("/transfer", controller.AuthRequired(), controller.TransferNamespace)
+	}
+
+	profiles := apiV1.Group("/profiles")
+	{
+		profiles.GET("/:username", controller.UserProfile)
+	}
+
+	// 具体的角色由各个service检查
+	admin := apiV1.Group("/admin", controller.AuthRequired(),
+		controller.RequireRole(userModel.RoleModerator, userModel.RoleSupport))
+	{
+		admin.GET("/users", controller.AdminListUsers)
+		admin.GET("/users_by_ip", controller.AdminFindUsersByIP)
+		admin.GET("/duplicate_users", controller.AdminFindDuplicateAccounts)
+		admin.GET("/users/:id", controller.AdminGetUser)
+		admin.POST("/users/import", controller.AdminImportUsers)
+		admin.POST("/users/deactivate", controller.DeactivateUser)
+		admin.POST("/users/reactivate", controller.ReactivateUser)
+		admin.POST("/users/impersonate", controller.ImpersonateUser)
+		admin.POST("/users/restore", controller.RestoreUser)
+		admin.POST("/users/hard_delete", controller.HardDeleteUser)
+		admin.POST("/users/roles", controller.SetUserRoles)
+		admin.POST("/users/merge", controller.MergeAccounts)
+		admin.POST("/users/username_cooldown/reset", controller.AdminResetUsernameCooldown)
+	}
+
+	sshKeys := apiV1.Group("/ssh_keys", controller.AuthRequired(), controller.RejectImpersonated())
+	{
+		sshKeys.GET("", controller.ListSSHKeys)
+		sshKeys.POST("/add", controller.AddSSHKey)
+		sshKeys.POST("/delete", controller.DeleteSSHKey)
+	}
+
+	authBodyLimit := controller.LimitRequestBody(conf.GetConf().GetBodyLimit().Auth)
+	auth := apiV1.Group("/auth", authBodyLimit)
 	{
 		auth.POST("/register", controller.RegisterUser)
 		auth.POST("/activate", controller.ActivateUser)
+		auth.POST("/activate/resend", controller.ResendActivation)
 		auth.POST("/login", controller.LoginUser)
+		auth.GET("/oauth/:provider/authorize", controller.OAuthAuthorize)
+		auth.POST("/oauth/:provider/callback", controller.OAuthCallback)
+		auth.POST("/logout", controller.LogoutUser)
+		auth.POST("/password/reset", controller.RequestPasswordReset)
+		auth.POST("/password/reset/confirm", controller.ConfirmPasswordReset)
+		auth.POST("/email/change/confirm", controller.ConfirmEmailChange)
+		auth.POST("/email/change/revert", controller.RevertEmailChange)
+		auth.POST("/emails/verify", controller.VerifyEmail)
+	}
+
+	// 需要登录，管理员代登录的session不能访问
+	account := apiV1.Group("/auth", authBodyLimit, controller.AuthRequired(), controller.RejectImpersonated())
+	{
+		account.POST("/logout_all", controller.LogoutAllUser)
+		account.POST("/password/change", controller.ChangePassword)
+		account.POST("/password/set", controller.SetPassword)
+		account.GET("/oauth", controller.ListOAuthIdentities)
+		account.POST("/oauth/:provider/link", controller.LinkOAuthIdentity)
+		account.POST("/oauth/:provider/unlink", controller.UnlinkOAuthIdentity)
+		account.POST("/account/delete", controller.DeleteAccount)
+		account.POST("/username/change", controller.ChangeUsername)
+		account.POST("/email/change", controller.ChangeEmail)
+		account.POST("/profile/update", controller.UpdateProfile)
+		account.GET("/emails", controller.ListEmails)
+		account.POST("/emails/add", controller.AddEmail)
+		account.POST("/emails/primary", controller.SetPrimaryEmail)
+		account.POST("/emails/delete", controller.DeleteEmail)
+		account.POST("/totp/enroll", controller.EnrollTOTP)
+		account.POST("/totp/confirm", controller.ConfirmTOTP)
+		account.GET("/access_tokens", controller.ListAccessTokens)
+		account.POST("/access_tokens/create", controller.CreateAccessToken)
+		account.POST("/access_tokens/revoke", controller.RevokeAccessToken)
+		account.GET("/login_history", controller.ListLoginHistory)
+		account.GET("/sessions", controller.ListMySessions)
+		account.POST("/sessions/revoke", controller.RevokeSession)
+		account.GET("/export", controller.ExportUserData)
 	}
 
+	// 命名空间的path不能与路由冲突
+	for _, r := range engine.Routes() {
+		nsModel.ReserveRoutes(r.Path)
+	}
+
+	if m := conf.GetConf().Metrics; m.Enabled() {
+		go runMetricsServer(m.Addr)
+	}
 	return runServer(addr, engine)
 }
 
+// runMetricsServer prometheus 指标使用单独的（内部）端口，不对外暴露
+func runMetricsServer(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		WriteTimeout: 60 * time.Second,
+		ReadTimeout:  60 * time.Second,
+	}
+
+	notify.Subscribe(func() {
+		timeoutCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		server.Shutdown(timeoutCtx)
+	})
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Error("metrics server: %v", err)
+	}
+}
+
 func runServer(addr string, engine *gin.Engine) error {
 	server := &http.Server{
 		Addr:         addr,
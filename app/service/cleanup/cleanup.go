@@ -0,0 +1,118 @@
+package cleanup
+
+import (
+	"time"
+
+	"github.com/growerlab/backend/app/common/notify"
+	"github.com/growerlab/backend/app/model/activate"
+	"github.com/growerlab/backend/app/model/db"
+	"github.com/growerlab/backend/app/model/idempotency"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	"github.com/growerlab/backend/app/utils/logger"
+)
+
+// Interval 定时清理的间隔
+var Interval = time.Hour
+
+// batchPause 每批删除之间的间隔，让出锁给正常的请求
+const batchPause = 100 * time.Millisecond
+
+// ExpiredSessions 分批删除已过期的session，返回删除的总数（可由定时任务调用）
+// 仅用于清理存储空间，GetUserByUserToken 本身会忽略已过期的session
+func ExpiredSessions() (int64, error) {
+	before := time.Now().Unix()
+	var total int64
+	for {
+		n, err := sessionModel.DeleteExpiredSessions(db.DB, before)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < sessionModel.DeleteExpiredSessionsBatch {
+			return total, nil
+		}
+		time.Sleep(batchPause)
+	}
+}
+
+// ActivationCodeRetention 激活码过期后保留的时间，期间使用过期的链接时提示已过期（而不是不存在）
+const ActivationCodeRetention = 7 * 24 * time.Hour
+
+// ExpiredActivationCodes 分批删除过期超过 ActivationCodeRetention 的激活码，返回删除的总数
+func ExpiredActivationCodes() (int64, error) {
+	before := time.Now().Add(-ActivationCodeRetention).Unix()
+	var total int64
+	for {
+		n, err := activate.DeleteExpiredCodes(db.DB, before)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < activate.DeleteExpiredCodesBatch {
+			return total, nil
+		}
+		time.Sleep(batchPause)
+	}
+}
+
+// ExpiredIdempotencyKeys 分批删除已过期的幂等键，返回删除的总数
+func ExpiredIdempotencyKeys() (int64, error) {
+	before := time.Now().Unix()
+	var total int64
+	for {
+		n, err := idempotency.DeleteExpiredKeys(db.DB, before)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < idempotency.DeleteExpiredKeysBatch {
+			return total, nil
+		}
+		time.Sleep(batchPause)
+	}
+}
+
+// Start 启动定时清理，进程退出时停止
+func Start() error {
+	ticker := time.NewTicker(Interval)
+	done := make(chan struct{})
+	notify.Subscribe(func() {
+		ticker.Stop()
+		close(done)
+	})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				run()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func run() {
+	n, err := ExpiredSessions()
+	if err != nil {
+		logger.Error("cleanup expired sessions: %+v", err)
+	} else {
+		logger.Info("cleanup expired sessions: %d deleted", n)
+	}
+
+	n, err = ExpiredActivationCodes()
+	if err != nil {
+		logger.Error("cleanup expired activation codes: %+v", err)
+	} else {
+		logger.Info("cleanup expired activation codes: %d deleted", n)
+	}
+
+	n, err = ExpiredIdempotencyKeys()
+	if err != nil {
+		logger.Error("cleanup expired idempotency keys: %+v", err)
+	} else {
+		logger.Info("cleanup expired idempotency keys: %d deleted", n)
+	}
+}
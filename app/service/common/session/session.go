@@ -1,15 +1,27 @@
 package session
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/growerlab/backend/app/common/env"
+	"github.com/growerlab/backend/app/common/errors"
 	"github.com/growerlab/backend/app/model/db"
 	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/clientip"
+	"github.com/growerlab/backend/app/utils/conf"
 	"github.com/growerlab/backend/app/utils/logger"
 )
 
 const (
+	// AuthUserToken token的header名称，cookie名称见 CookieName
 	AuthUserToken = "auth-user-token"
+
+	authorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+
+	// ctxCurrentUserKey AuthRequired 中间件解析出的当前用户，保存在gin.Context中
+	ctxCurrentUserKey = "growerlab-current-user"
 )
 
 type Session struct {
@@ -24,12 +36,18 @@ func New(c *gin.Context) *Session {
 	var userToken = GetUserToken(c)
 	var err error
 
-	if len(userToken) > 0 {
-		user, err = userModel.GetUserByUserToken(db.DB, userToken)
+	if cached, ok := c.Get(ctxCurrentUserKey); ok {
+		// 已由中间件解析过，不再重复查询
+		user = cached.(*userModel.User)
+	} else if len(userToken) > 0 {
+		user, err = userModel.GetUserByUserTokenFromIPContext(c.Request.Context(), db.DB, userToken, clientip.FromRequest(c.Request))
 		if err != nil {
-			logger.Error("get user by user token failed, user token: %s, err: %s", userToken, err.Error())
+			logger.Ctx(c).Error("get user by user token failed: %s", err.Error())
 			return nil
 		}
+		if user != nil {
+			logger.AddCtxFields(c, logger.Fields{logger.FieldUserID: user.ID})
+		}
 	}
 
 	e.Set(env.VarUserToken, userToken)
@@ -69,15 +87,46 @@ func (s *Session) IsGuest() bool {
 	return len(token) == 0
 }
 
+// SetCurrentUser 保存当前请求的用户（由 AuthRequired 中间件调用）
+func SetCurrentUser(ctx *gin.Context, user *userModel.User) {
+	ctx.Set(ctxCurrentUserKey, user)
+}
+
+// CurrentUser 读取 AuthRequired 中间件解析出的当前用户
+// 未经过该中间件或未登录时返回 Unauthorize 错误
+// 中间件使用了 userModel.FillNamespace() 时，User.Namespace() 直接返回已获取的命名空间
+func CurrentUser(ctx *gin.Context) (*userModel.User, error) {
+	v, ok := ctx.Get(ctxCurrentUserKey)
+	if !ok {
+		return nil, errors.Unauthorize()
+	}
+	user, ok := v.(*userModel.User)
+	if !ok || user == nil {
+		return nil, errors.Unauthorize()
+	}
+	return user, nil
+}
+
 func GetUserToken(ctx *gin.Context) string {
-	token := getValueFromHeaderOrCookie(AuthUserToken, ctx)
-	return token
+	return extractToken(ctx)
 }
 
-func getValueFromHeaderOrCookie(k string, ctx *gin.Context) string {
-	v := ctx.GetHeader(k)
+// extractToken 获取当前请求的token，优先级：
+// 1. Authorization: Bearer <token>（CLI、API等非浏览器客户端）
+// 2. auth-user-token header 或 cookie（名称由配置决定）
+func extractToken(ctx *gin.Context) string {
+	authorization := ctx.GetHeader(authorizationHeader)
+	if len(authorization) > len(bearerPrefix) && strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+		return strings.TrimSpace(authorization[len(bearerPrefix):])
+	}
+	v := ctx.GetHeader(AuthUserToken)
 	if len(v) < 5 {
-		v, _ = ctx.Cookie(k)
+		v, _ = ctx.Cookie(CookieName())
 	}
 	return v
 }
+
+// CookieName 登录token的cookie名称，下发、读取、清除cookie都必须使用这里
+func CookieName() string {
+	return conf.GetConf().GetCookie().Name
+}
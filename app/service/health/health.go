@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Status 健康检查的结果（供负载均衡、监控解析）
+type Status struct {
+	Status string            `json:"status"`
+	Checks map[string]*Check `json:"checks,omitempty"`
+}
+
+type Check struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// OK 所有检查项均通过
+func (s *Status) OK() bool {
+	return s.Status == StatusOK
+}
+
+// Liveness 进程存活即可，不检查依赖（避免数据库故障时编排系统不断重启进程）
+func Liveness() *Status {
+	return &Status{Status: StatusOK}
+}
+
+// Readiness 检查依赖是否可用，不可用时不应将流量路由到当前实例
+func Readiness(ctx context.Context) *Status {
+	status := &Status{
+		Status: StatusOK,
+		Checks: map[string]*Check{
+			"database":   check(ctx, db.Ping),
+			"migrations": check(ctx, checkMigrations),
+		},
+	}
+	for _, c := range status.Checks {
+		if c.Status != StatusOK {
+			status.Status = StatusUnavailable
+		}
+	}
+	return status
+}
+
+func check(ctx context.Context, fn func(ctx context.Context) error) *Check {
+	start := time.Now()
+	err := fn(ctx)
+	c := &Check{
+		Status:    StatusOK,
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		c.Status = StatusUnavailable
+		c.Error = err.Error()
+	}
+	return c
+}
+
+// checkMigrations 存在未执行的数据库迁移时，当前代码依赖的表结构可能还不存在
+func checkMigrations(ctx context.Context) error {
+	versions, err := db.PendingMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	if len(versions) > 0 {
+		return errors.Errorf("pending migrations: %s", strings.Join(versions, ","))
+	}
+	return nil
+}
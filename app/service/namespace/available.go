@@ -0,0 +1,127 @@
+package namespace
+
+import (
+	"strings"
+	"time"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/common/validate"
+	"github.com/growerlab/backend/app/model/db"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/jmoiron/sqlx"
+)
+
+// 用户名、组织名共用命名空间的path，注册、创建组织、修改用户名都通过这里检查，保证规则一致
+
+type AvailabilityResult struct {
+	Path      string `json:"path"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"` // 不可用的原因，例如 InvalidLength、Invalid、Reserved、AlreadyExists、Deleted
+}
+
+type ReservedResult struct {
+	Paths []string `json:"paths"`
+}
+
+// InitReserved 将配置中的 account.reserved_namespaces 加入保留字
+func InitReserved() error {
+	nsModel.AddReserved(conf.GetConf().GetAccount().ReservedNamespaces...)
+	return nil
+}
+
+// ReservedPaths 不允许用作用户名、组织名的path（小写），供前端提交前检查
+func ReservedPaths() *ReservedResult {
+	return &ReservedResult{Paths: nsModel.ReservedList()}
+}
+
+// CheckNamespaceAvailable 检查 path 能否用作新的用户名或组织名（供前端提交前检查）
+func CheckNamespaceAvailable(path string) (*AvailabilityResult, error) {
+	path = strings.TrimSpace(path)
+	reason, err := Check(db.DB, path)
+	if err != nil {
+		return nil, err
+	}
+	return &AvailabilityResult{
+		Path:      path,
+		Available: len(reason) == 0,
+		Reason:    reason,
+	}, nil
+}
+
+// Check 检查 path 的格式以及是否已被占用，可用时返回空字符串，否则返回原因
+func Check(src sqlx.Queryer, path string) (reason string, err error) {
+	if reason = CheckFormat(path); len(reason) > 0 {
+		return reason, nil
+	}
+	return CheckTaken(src, path)
+}
+
+// CheckFormat 检查 path 的格式（允许的字符、长度、保留字），不查询数据库
+// 规则见 validate.Username，保留字见 nsModel.IsReserved
+func CheckFormat(path string) (reason string) {
+	return validate.UsernameReason(path)
+}
+
+// CheckTaken 检查 path 是否已被命名空间或用户名（包括已注销的用户）占用，以及是否为保留期内的旧用户名
+func CheckTaken(src sqlx.Queryer, path string) (reason string, err error) {
+	return CheckTakenBy(src, path, 0, 0)
+}
+
+// CheckTakenBy 同 CheckTaken，但 userID 自己保留期内的旧用户名、namespaceID 自己保留期内的旧path视为可用（改回原来的名字）
+func CheckTakenBy(src sqlx.Queryer, path string, userID, namespaceID int64) (reason string, err error) {
+	exists, err := nsModel.PathExists(src, path)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return errors.AlreadyExists, nil
+	}
+	exists, err = userModel.ExistsUsername(src, path)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return errors.AlreadyExists, nil
+	}
+	exists, err = userModel.ExistsUsernameIncludingDeleted(src, path)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return errors.Deleted, nil
+	}
+	// 旧用户名在保留期内不释放，避免旧地址（跳转）被其他用户占用、冒充原用户
+	history, err := userModel.GetLatestUsernameHistory(src, path)
+	if err != nil {
+		return "", err
+	}
+	quarantineDays := conf.GetConf().GetAccount().UsernameQuarantineDays
+	if history != nil && history.UserID != userID &&
+		history.Quarantined(quarantineDays, time.Now().Unix()) {
+		return errors.Reserved, nil
+	}
+	// 组织改名没有用户名历史，同样在保留期内不释放旧path（旧地址仍跳转到改名后的命名空间）
+	redirect, err := nsModel.GetRedirectByPath(src, path)
+	if err != nil {
+		return "", err
+	}
+	if redirect != nil && redirect.NamespaceID != namespaceID &&
+		redirect.Quarantined(quarantineDays, time.Now().Unix()) {
+		return errors.Reserved, nil
+	}
+	return "", nil
+}
+
+// ReasonError 将 Check 返回的原因转换为 model、field 对应的错误
+func ReasonError(model, field, reason string) error {
+	switch reason {
+	case "":
+		return nil
+	case errors.AlreadyExists, errors.Deleted:
+		return errors.AlreadyExistsError(model, reason)
+	default:
+		return errors.P(model, field, reason)
+	}
+}
@@ -0,0 +1,29 @@
+package namespace
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/common/validate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckFormat(t *testing.T) {
+	assert.Equal(t, "", CheckFormat("moli"))
+	assert.Equal(t, errors.InvalidLength, CheckFormat("abc"))
+	assert.Equal(t, errors.InvalidLength, CheckFormat(strings.Repeat("a", validate.Username.MaxLen+1)))
+	assert.Equal(t, errors.Invalid, CheckFormat("mo li"))
+	assert.Equal(t, errors.Reserved, CheckFormat("admin"))
+	assert.Equal(t, errors.Invalid, CheckFormat("Moli"))
+}
+
+func TestReasonError(t *testing.T) {
+	assert.Nil(t, ReasonError(errors.User, errors.Username, ""))
+	assert.Equal(t,
+		errors.P(errors.User, errors.Username, errors.Invalid).Error(),
+		ReasonError(errors.User, errors.Username, errors.Invalid).Error())
+	assert.Equal(t,
+		errors.P(errors.User, errors.Username, errors.Reserved).Error(),
+		ReasonError(errors.User, errors.Username, errors.Reserved).Error())
+}
@@ -0,0 +1,168 @@
+package namespace
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/jmoiron/sqlx"
+)
+
+type RenamePayload struct {
+	NamespaceID int64  `json:"namespace_id"`
+	Path        string `json:"path"`
+}
+
+// Rename 修改命名空间的path（用户名或组织名）
+// 1. 用户的命名空间只能由用户本人修改，同时修改用户名并记录用户名历史（与修改用户名相同，受冷却期限制）
+// 2. 组织的命名空间只能由组织的owner修改
+// 3. 记录旧path的跳转，旧地址返回301而不是404
+// 仓库通过 namespace_id 关联命名空间，仓库地址（PathGroup）随之改变，不需要逐个修改
+func Rename(ctx *gin.Context, namespaceID int64, newPath string) error {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return errors.Unauthorize()
+	}
+	currentUser := sess.User()
+
+	newPath = strings.TrimSpace(newPath)
+	if err := ReasonError(errors.Namespace, errors.Path, CheckFormat(newPath)); err != nil {
+		return err
+	}
+
+	var renamedUser bool
+	err := db.Transact(func(tx sqlx.Ext) error {
+		ns, err := nsModel.GetNamespace(tx, namespaceID)
+		if err != nil {
+			return err
+		}
+		if ns == nil {
+			return errors.NotFoundError(errors.Namespace)
+		}
+		if ns.Path == newPath {
+			return errors.P(errors.Namespace, errors.Path, errors.Unchanged)
+		}
+
+		// 自己保留期内的旧名字可以改回（用户名历史只属于用户，组织只使用跳转记录）
+		var takenBy int64
+		switch nsModel.NamespaceType(ns.Type) {
+		case nsModel.TypeUser:
+			if ns.OwnerID != currentUser.ID || ns.ID != currentUser.NamespaceID {
+				return errors.AccessDenied(errors.Namespace, errors.NoPermission)
+			}
+			if err = CheckUsernameCooldown(currentUser, time.Now()); err != nil {
+				return err
+			}
+			takenBy = currentUser.ID
+		case nsModel.TypeOrg:
+			m, err := nsModel.GetMember(tx, ns.ID, currentUser.ID)
+			if err != nil {
+				return err
+			}
+			if m == nil || m.Role != int(nsModel.RoleOwner) {
+				return errors.AccessDenied(errors.Organization, errors.NoPermission)
+			}
+		default:
+			return errors.AccessDenied(errors.Namespace, errors.NoPermission)
+		}
+
+		// 仅修改大小写时，不需要检查重复
+		if !strings.EqualFold(ns.Path, newPath) {
+			reason, err := CheckTakenBy(tx, newPath, takenBy, ns.ID)
+			if err != nil {
+				return err
+			}
+			if err = ReasonError(errors.Namespace, errors.Path, reason); err != nil {
+				return err
+			}
+		}
+
+		if nsModel.NamespaceType(ns.Type) == nsModel.TypeUser {
+			// 用户名与用户的namespace.path必须保持一致
+			err = userModel.UpdateUsername(tx, currentUser.ID, currentUser.Version, newPath)
+			if err != nil {
+				return err
+			}
+			err = userModel.AddUsernameHistory(tx, currentUser.ID, currentUser.Username)
+			if err != nil {
+				return err
+			}
+			renamedUser = true
+		}
+		return RenameTx(tx, ns, newPath)
+	})
+	if errors.Cause(err) == userModel.ErrConflict {
+		return errors.ConflictError(errors.User)
+	}
+	if err != nil {
+		return err
+	}
+	if renamedUser {
+		userModel.InvalidateUserTokens(currentUser.ID)
+	}
+	return nil
+}
+
+// CheckUsernameCooldown 距离上次修改用户名（个人命名空间的path）不足 account.username_cooldown_days 时返回 CooldownError
+// 修改用户名、修改个人命名空间的path都需要检查
+func CheckUsernameCooldown(user *userModel.User, now time.Time) error {
+	cooldown := time.Duration(conf.GetConf().GetAccount().UsernameCooldownDays) * 24 * time.Hour
+	if until, ok := usernameCooldownUntil(user, cooldown, now); ok {
+		return errors.CooldownError(errors.User, until)
+	}
+	return nil
+}
+
+// usernameCooldownUntil 距离上次修改用户名不足 cooldown 时，返回允许再次修改的时间戳
+func usernameCooldownUntil(user *userModel.User, cooldown time.Duration, now time.Time) (int64, bool) {
+	if cooldown <= 0 || user.LastUsernameChangeAt == nil {
+		return 0, false
+	}
+	until := *user.LastUsernameChangeAt + int64(cooldown/time.Second)
+	if now.Unix() >= until {
+		return 0, false
+	}
+	return until, true
+}
+
+// RenameTx 修改命名空间的path并记录旧path的跳转，不检查权限和path是否可用
+func RenameTx(tx sqlx.Execer, ns *nsModel.Namespace, newPath string) error {
+	err := nsModel.UpdatePath(tx, ns.ID, newPath)
+	if err != nil {
+		return err
+	}
+	return nsModel.AddRedirect(tx, ns.ID, ns.Path)
+}
+
+// Resolve 按path获取命名空间
+// path 是改名前的旧path时，返回 MovedError（location 为新的path）；都不存在时返回 NotFound
+func Resolve(src sqlx.Queryer, path string) (*nsModel.Namespace, error) {
+	ns, err := nsModel.GetNamespaceByPath(src, path)
+	if err != nil {
+		return nil, err
+	}
+	if ns != nil {
+		return ns, nil
+	}
+
+	redirect, err := nsModel.GetRedirectByPath(src, path)
+	if err != nil {
+		return nil, err
+	}
+	if redirect != nil {
+		target, err := nsModel.GetNamespace(src, redirect.NamespaceID)
+		if err != nil {
+			return nil, err
+		}
+		if target != nil {
+			return nil, errors.MovedError(errors.Namespace, target.Path)
+		}
+	}
+	return nil, errors.NotFoundError(errors.Namespace)
+}
@@ -0,0 +1,32 @@
+package namespace
+
+import (
+	"testing"
+	"time"
+
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsernameCooldownUntil(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	cooldown := 30 * 24 * time.Hour
+
+	// 从未修改过
+	_, ok := usernameCooldownUntil(&userModel.User{}, cooldown, now)
+	assert.False(t, ok)
+
+	changed := now.Add(-24 * time.Hour).Unix()
+	u := &userModel.User{LastUsernameChangeAt: &changed}
+	until, ok := usernameCooldownUntil(u, cooldown, now)
+	assert.True(t, ok)
+	assert.Equal(t, changed+int64(cooldown/time.Second), until)
+
+	// 冷却期结束的当秒允许修改
+	_, ok = usernameCooldownUntil(u, cooldown, time.Unix(until, 0))
+	assert.False(t, ok)
+
+	// 未配置冷却期
+	_, ok = usernameCooldownUntil(u, 0, now)
+	assert.False(t, ok)
+}
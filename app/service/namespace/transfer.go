@@ -0,0 +1,102 @@
+package namespace
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/growerlab/backend/app/utils/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type TransferPayload struct {
+	NamespaceID int64 `json:"namespace_id"`
+	UserID      int64 `json:"user_id"` // 新的owner
+}
+
+// TransferNamespace 将组织命名空间转让给组织的另一位成员
+// 1. 组织的owner（RoleOwner 成员，与组织的其他操作一致）可以转让，用户的命名空间不能转让
+// 2. 新owner必须已经是组织的成员，转让后成为 RoleOwner，并记录为命名空间的 owner_id
+// 3. 原owner保留 RoleOwner 角色，需要时再自行退出或降级，组织始终至少有一位owner
+// 4. 记录转让日志
+func TransferNamespace(ctx *gin.Context, namespaceID, newOwnerUserID int64) error {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return errors.Unauthorize()
+	}
+	currentUser := sess.User()
+	if newOwnerUserID == currentUser.ID {
+		return errors.P(errors.Namespace, errors.ID, errors.Self)
+	}
+
+	err := db.Transact(func(tx sqlx.Ext) error {
+		ns, err := nsModel.GetNamespace(tx, namespaceID)
+		if err != nil {
+			return err
+		}
+		if ns == nil {
+			return errors.NotFoundError(errors.Namespace)
+		}
+		if nsModel.NamespaceType(ns.Type) != nsModel.TypeOrg {
+			return errors.AccessDenied(errors.Namespace, errors.NoPermission)
+		}
+		// 在事务中锁定owner，而不是依赖 owner_id（记录的owner可能已经退出组织）
+		owners, err := nsModel.ListOwnersForUpdate(tx, ns.ID)
+		if err != nil {
+			return err
+		}
+		if !isOwner(owners, currentUser.ID) {
+			return errors.AccessDenied(errors.Organization, errors.NoPermission)
+		}
+		if ns.OwnerID == newOwnerUserID {
+			return errors.AlreadyExistsError(errors.Member, errors.AlreadyExists)
+		}
+
+		m, err := nsModel.GetMember(tx, ns.ID, newOwnerUserID)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			return errors.NotFoundError(errors.Member)
+		}
+		if m.Role != int(nsModel.RoleOwner) {
+			err = nsModel.UpdateMemberRole(tx, ns.ID, newOwnerUserID, nsModel.RoleOwner)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = nsModel.UpdateOwner(tx, ns.ID, ns.OwnerID, newOwnerUserID)
+		if err != nil {
+			return err
+		}
+		return nsModel.AddTransfer(tx, &nsModel.Transfer{
+			NamespaceID: ns.ID,
+			FromUserID:  currentUser.ID,
+			ToUserID:    newOwnerUserID,
+		})
+	})
+	if errors.Cause(err) == nsModel.ErrOwnerChanged {
+		return errors.ConflictError(errors.Namespace)
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Ctx(ctx).
+		With("namespace_id", namespaceID).
+		With("from_user_id", currentUser.ID).
+		With("to_user_id", newOwnerUserID).
+		Info("namespace transferred")
+	return nil
+}
+
+func isOwner(owners []*nsModel.Member, userID int64) bool {
+	for _, o := range owners {
+		if o.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
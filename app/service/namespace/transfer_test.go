@@ -0,0 +1,16 @@
+package namespace
+
+import (
+	"testing"
+
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsOwner(t *testing.T) {
+	owners := []*nsModel.Member{{UserID: 1}, {UserID: 2}}
+	assert.True(t, isOwner(owners, 1))
+	assert.True(t, isOwner(owners, 2))
+	assert.False(t, isOwner(owners, 3))
+	assert.False(t, isOwner(nil, 1))
+}
@@ -0,0 +1,246 @@
+package org
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/jmoiron/sqlx"
+)
+
+type AddMemberPayload struct {
+	Path     string `json:"path"`
+	Username string `json:"username"`
+	Role     int    `json:"role"`
+}
+
+type RemoveMemberPayload struct {
+	Path     string `json:"path"`
+	Username string `json:"username"`
+}
+
+type MemberInfo struct {
+	Username  string `json:"username"`
+	Name      string `json:"name"`
+	Role      int    `json:"role"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// AddMember 添加组织成员（仅owner可操作）
+// 用户已经是成员时，更新为新的角色
+func AddMember(ctx *gin.Context, path, username string, role nsModel.MemberRole) error {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return errors.Unauthorize()
+	}
+	currentUser := sess.User()
+	if !role.Valid() {
+		return errors.P(errors.Member, errors.Role, errors.Invalid)
+	}
+
+	err := db.Transact(func(tx sqlx.Ext) error {
+		ns, err := getOrganization(tx, path)
+		if err != nil {
+			return err
+		}
+		if err := mustBeOwner(tx, ns.ID, currentUser.ID); err != nil {
+			return err
+		}
+
+		u, err := userModel.GetUserByUsername(tx, username)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return errors.NotFoundError(errors.User)
+		}
+
+		m, err := nsModel.GetMember(tx, ns.ID, u.ID)
+		if err != nil {
+			return err
+		}
+		if m != nil {
+			if m.Role == int(role) {
+				return errors.AlreadyExistsError(errors.Member, errors.AlreadyExists)
+			}
+			// 降级owner时，同样不能移除最后一位owner
+			if m.Role == int(nsModel.RoleOwner) {
+				if err := ensureNotLastOwner(tx, ns.ID); err != nil {
+					return err
+				}
+				if err := handOverRecordedOwner(tx, ns, u.ID); err != nil {
+					return err
+				}
+			}
+			err = nsModel.DeleteMember(tx, ns.ID, u.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nsModel.AddMember(tx, &nsModel.Member{
+			NamespaceID: ns.ID,
+			UserID:      u.ID,
+			Role:        int(role),
+		})
+	})
+	return err
+}
+
+// RemoveMember 移除组织成员
+// owner 可以移除任意成员，普通成员只能移除自己（退出组织）；组织的最后一位owner不能被移除
+// 移除的是命名空间记录的owner（owner_id）时，owner_id 转给剩余的另一位owner
+func RemoveMember(ctx *gin.Context, path, username string) error {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return errors.Unauthorize()
+	}
+	currentUser := sess.User()
+
+	err := db.Transact(func(tx sqlx.Ext) error {
+		ns, err := getOrganization(tx, path)
+		if err != nil {
+			return err
+		}
+
+		u, err := userModel.GetUserByUsername(tx, username)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return errors.NotFoundError(errors.User)
+		}
+		if u.ID != currentUser.ID {
+			if err := mustBeOwner(tx, ns.ID, currentUser.ID); err != nil {
+				return err
+			}
+		}
+
+		m, err := nsModel.GetMember(tx, ns.ID, u.ID)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			return errors.NotFoundError(errors.Member)
+		}
+		if m.Role == int(nsModel.RoleOwner) {
+			if err := ensureNotLastOwner(tx, ns.ID); err != nil {
+				return err
+			}
+		}
+		if err := handOverRecordedOwner(tx, ns, u.ID); err != nil {
+			return err
+		}
+		return nsModel.DeleteMember(tx, ns.ID, u.ID)
+	})
+	return err
+}
+
+// ListMembers 组织的成员列表（仅成员可见）
+func ListMembers(ctx *gin.Context, path string) ([]*MemberInfo, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	currentUser := sess.User()
+
+	ns, err := getOrganization(db.DB, path)
+	if err != nil {
+		return nil, err
+	}
+	self, err := nsModel.GetMember(db.DB, ns.ID, currentUser.ID)
+	if err != nil {
+		return nil, err
+	}
+	if self == nil {
+		return nil, errors.AccessDenied(errors.Organization, errors.NoPermission)
+	}
+
+	members, err := nsModel.ListMembers(db.DB, ns.ID)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]int64, 0, len(members))
+	for _, m := range members {
+		userIDs = append(userIDs, m.UserID)
+	}
+	users, err := userModel.GetUsersByIDs(db.DB, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*MemberInfo, 0, len(members))
+	for _, m := range members {
+		u, ok := users[m.UserID]
+		if !ok {
+			// 已注销的用户
+			continue
+		}
+		result = append(result, &MemberInfo{
+			Username:  u.Username,
+			Name:      u.Name,
+			Role:      m.Role,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return result, nil
+}
+
+func mustBeOwner(src sqlx.Queryer, namespaceID, userID int64) error {
+	m, err := nsModel.GetMember(src, namespaceID, userID)
+	if err != nil {
+		return err
+	}
+	if m == nil || m.Role != int(nsModel.RoleOwner) {
+		return errors.AccessDenied(errors.Organization, errors.NoPermission)
+	}
+	return nil
+}
+
+// ensureNotLastOwner 组织至少需要保留一位owner
+func ensureNotLastOwner(tx sqlx.Queryer, namespaceID int64) error {
+	owners, err := nsModel.ListOwnersForUpdate(tx, namespaceID)
+	if err != nil {
+		return err
+	}
+	if len(owners) <= 1 {
+		return errors.P(errors.Member, errors.Role, errors.LastOwner)
+	}
+	return nil
+}
+
+// handOverRecordedOwner 移除或降级的成员是命名空间记录的owner（owner_id）时，在同一事务中将 owner_id 转给剩余的另一位owner
+// 否则 owner_id 将指向已不是owner的用户
+func handOverRecordedOwner(tx sqlx.Ext, ns *nsModel.Namespace, leavingUserID int64) error {
+	if ns.OwnerID != leavingUserID {
+		return nil
+	}
+	owners, err := nsModel.ListOwnersForUpdate(tx, ns.ID)
+	if err != nil {
+		return err
+	}
+	next := nextOwner(owners, leavingUserID)
+	if next == nil {
+		return errors.P(errors.Member, errors.Role, errors.LastOwner)
+	}
+	err = nsModel.UpdateOwner(tx, ns.ID, leavingUserID, next.UserID)
+	if errors.Cause(err) == nsModel.ErrOwnerChanged {
+		return errors.ConflictError(errors.Namespace)
+	}
+	return err
+}
+
+// nextOwner 除 excludeUserID 之外最早加入的owner
+func nextOwner(owners []*nsModel.Member, excludeUserID int64) *nsModel.Member {
+	var next *nsModel.Member
+	for _, o := range owners {
+		if o.UserID == excludeUserID {
+			continue
+		}
+		if next == nil || o.CreatedAt < next.CreatedAt || (o.CreatedAt == next.CreatedAt && o.ID < next.ID) {
+			next = o
+		}
+	}
+	return next
+}
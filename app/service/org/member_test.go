@@ -0,0 +1,22 @@
+package org
+
+import (
+	"testing"
+
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextOwner(t *testing.T) {
+	owners := []*nsModel.Member{
+		{ID: 1, UserID: 10, CreatedAt: 100},
+		{ID: 3, UserID: 30, CreatedAt: 200},
+		{ID: 2, UserID: 20, CreatedAt: 200},
+	}
+	assert.Equal(t, int64(20), nextOwner(owners, 10).UserID)
+	assert.Equal(t, int64(10), nextOwner(owners, 20).UserID)
+
+	// 没有其他owner
+	assert.Nil(t, nextOwner(owners[:1], 10))
+	assert.Nil(t, nextOwner(nil, 10))
+}
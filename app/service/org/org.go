@@ -0,0 +1,79 @@
+package org
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	"github.com/growerlab/backend/app/service/common/session"
+	nsSvc "github.com/growerlab/backend/app/service/namespace"
+	"github.com/jmoiron/sqlx"
+)
+
+type CreateOrganizationPayload struct {
+	Path string `json:"path"`
+}
+
+// CreateOrganization 创建组织
+// 组织的命名空间与用户的命名空间共用 path，所以 path 既不能与已有的命名空间重复，也不能与用户名重复
+// 创建者成为组织的owner
+func CreateOrganization(ctx *gin.Context, path string) (*nsModel.Namespace, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	currentUser := sess.User()
+
+	path = strings.TrimSpace(path)
+	if err := validatePath(path); err != nil {
+		return nil, err
+	}
+
+	var ns *nsModel.Namespace
+	err := db.Transact(func(tx sqlx.Ext) error {
+		reason, err := nsSvc.CheckTaken(tx, path)
+		if err != nil {
+			return err
+		}
+		if err = nsSvc.ReasonError(errors.Namespace, errors.Path, reason); err != nil {
+			return err
+		}
+
+		ns = &nsModel.Namespace{
+			Path:    path,
+			OwnerID: currentUser.ID,
+			Type:    int(nsModel.TypeOrg),
+		}
+		err = nsModel.AddNamespace(tx, ns)
+		if err != nil {
+			return err
+		}
+		return nsModel.AddMember(tx, &nsModel.Member{
+			NamespaceID: ns.ID,
+			UserID:      currentUser.ID,
+			Role:        int(nsModel.RoleOwner),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+func validatePath(path string) error {
+	return nsSvc.ReasonError(errors.Organization, errors.Path, nsSvc.CheckFormat(path))
+}
+
+// getOrganization 获取组织的命名空间，不存在或不是组织时返回NotFound
+func getOrganization(src sqlx.Queryer, path string) (*nsModel.Namespace, error) {
+	ns, err := nsModel.GetNamespaceByPath(src, path)
+	if err != nil {
+		return nil, err
+	}
+	if ns == nil || ns.Type != int(nsModel.TypeOrg) {
+		return nil, errors.NotFoundError(errors.Organization)
+	}
+	return ns, nil
+}
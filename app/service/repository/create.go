@@ -77,16 +77,17 @@ func buildRepository(
 		status = false
 	}
 
+	repoUUID := uuid.UUIDv16()
 	repo = &repository.Repository{
 		NamespaceID: ns.ID,
-		UUID:        uuid.UUIDv16(),
+		UUID:        repoUUID,
 		Path:        req.Name,
 		Name:        req.Name,
 		OwnerID:     currentUser.ID,
 		Description: "",
 		CreatedAt:   time.Now().Unix(),
 		ServerID:    srv.ID,
-		ServerPath:  UsernameToFilePath(ns.Path, req.Name),
+		ServerPath:  RepositoryFilePath(repoUUID),
 		Public:      status,
 	}
 	return repo
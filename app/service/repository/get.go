@@ -5,9 +5,9 @@ import (
 	"github.com/growerlab/backend/app/common/errors"
 	"github.com/growerlab/backend/app/common/permission"
 	"github.com/growerlab/backend/app/model/db"
-	namespaceModel "github.com/growerlab/backend/app/model/namespace"
 	repositoryModel "github.com/growerlab/backend/app/model/repository"
 	"github.com/growerlab/backend/app/service/common/session"
+	nsSvc "github.com/growerlab/backend/app/service/namespace"
 )
 
 func GetRepository(c *gin.Context, namespace, path string) (*repositoryModel.Repository, error) {
@@ -20,13 +20,11 @@ func GetRepository(c *gin.Context, namespace, path string) (*repositoryModel.Rep
 
 	currentUserNSID := session.New(c).UserNamespace()
 
-	ns, err := namespaceModel.GetNamespaceByPath(db.DB, namespace)
+	// 命名空间改名后，旧path返回 MovedError
+	ns, err := nsSvc.Resolve(db.DB, namespace)
 	if err != nil {
 		return nil, err
 	}
-	if ns == nil {
-		return nil, errors.NotFoundError(errors.Namespace)
-	}
 
 	repo, err := repositoryModel.GetRepositoryByNsWithPath(db.DB, ns.ID, path)
 	if err != nil {
@@ -1,8 +1,7 @@
 package repository
 
-import "strings"
-
-func UsernameToFilePath(owner, repoName string) string {
-	p := []string{owner[:2], repoName[:2], owner, repoName + ".git"}
-	return strings.Join(p, "/")
+// RepositoryFilePath 仓库在服务器中的路径，只由仓库的uuid决定
+// 不使用命名空间的path、仓库名：改名后路径不变，旧名字被其他命名空间使用时也不会指向同一个目录
+func RepositoryFilePath(repoUUID string) string {
+	return repoUUID[:2] + "/" + repoUUID[2:4] + "/" + repoUUID + ".git"
 }
@@ -0,0 +1,138 @@
+package sshkey
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/base"
+	"github.com/growerlab/backend/app/model/db"
+	sshkeyModel "github.com/growerlab/backend/app/model/sshkey"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/asaskevich/govalidator.v9"
+)
+
+const TitleLenMax = 255
+
+type AddSSHKeyPayload struct {
+	Title     string `json:"title"`
+	PublicKey string `json:"public_key"`
+}
+
+type DeleteSSHKeyPayload struct {
+	ID int64 `json:"id"`
+}
+
+type SSHKeyInfo struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	Fingerprint string `json:"fingerprint"`
+	CreatedAt   int64  `json:"created_at"`
+	LastUsedAt  *int64 `json:"last_used_at"`
+}
+
+// AddSSHKey 添加ssh公钥
+// 指纹在所有用户之间必须唯一，否则ssh服务无法确定公钥属于哪个用户
+func AddSSHKey(ctx *gin.Context, title, publicKey string) (*SSHKeyInfo, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	user := sess.User()
+
+	pub, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(publicKey)))
+	if err != nil {
+		return nil, errors.P(errors.SSHKey, errors.PublicKey, errors.Invalid)
+	}
+	title = strings.TrimSpace(title)
+	if len(title) == 0 {
+		title = comment
+	}
+	if !govalidator.IsByteLength(title, 1, TitleLenMax) {
+		return nil, errors.P(errors.SSHKey, errors.Title, errors.InvalidLength)
+	}
+
+	key := &sshkeyModel.SSHKey{
+		UserID:      user.ID,
+		Title:       title,
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub))),
+		Fingerprint: ssh.FingerprintSHA256(pub),
+	}
+	err = db.Transact(func(tx sqlx.Ext) error {
+		exists, err := sshkeyModel.GetSSHKeyByFingerprint(tx, key.Fingerprint)
+		if err != nil {
+			return err
+		}
+		if exists != nil {
+			return errors.AlreadyExistsError(errors.SSHKey, errors.AlreadyExists)
+		}
+		return sshkeyModel.AddSSHKey(tx, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toSSHKeyInfo(key), nil
+}
+
+// ListSSHKeysMaxPer 每页最多返回的ssh公钥数量
+const ListSSHKeysMaxPer = 50
+
+// ListSSHKeys 分页获取当前用户的ssh公钥（page从0开始，最新添加的在前）
+func ListSSHKeys(ctx *gin.Context, page, per uint64) (*base.Page[*SSHKeyInfo], error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	if per == 0 || per > ListSSHKeysMaxPer {
+		per = ListSSHKeysMaxPer
+	}
+
+	userID := sess.User().ID
+	keys, err := base.Paginate(page, per,
+		func(page, per uint64) ([]*sshkeyModel.SSHKey, error) {
+			return sshkeyModel.ListSSHKeysByUser(db.DB, userID, page, per)
+		},
+		func() (int64, error) {
+			return sshkeyModel.CountSSHKeysByUser(db.DB, userID)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return base.MapPage(keys, toSSHKeyInfo), nil
+}
+
+// DeleteSSHKey 删除当前用户的ssh公钥
+func DeleteSSHKey(ctx *gin.Context, keyID int64) error {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return errors.Unauthorize()
+	}
+	user := sess.User()
+
+	err := db.Transact(func(tx sqlx.Ext) error {
+		key, err := sshkeyModel.GetSSHKey(tx, keyID)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return errors.NotFoundError(errors.SSHKey)
+		}
+		if key.UserID != user.ID {
+			return errors.AccessDenied(errors.SSHKey, errors.NoPermission)
+		}
+		return sshkeyModel.DeleteSSHKey(tx, key.ID)
+	})
+	return err
+}
+
+func toSSHKeyInfo(k *sshkeyModel.SSHKey) *SSHKeyInfo {
+	return &SSHKeyInfo{
+		ID:          k.ID,
+		Title:       k.Title,
+		Fingerprint: k.Fingerprint,
+		CreatedAt:   k.CreatedAt,
+		LastUsedAt:  k.LastUsedAt,
+	}
+}
@@ -0,0 +1,193 @@
+package user
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/accesstoken"
+	"github.com/growerlab/backend/app/model/db"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/growerlab/backend/app/utils/secret"
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/asaskevich/govalidator.v9"
+)
+
+const AccessTokenPrefix = "glp_"
+const AccessTokenNameLenMax = 255
+
+type CreateAccessTokenPayload struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int64    `json:"expires_in"` // 有效期，单位s，0表示永不过期
+}
+
+type RevokeAccessTokenPayload struct {
+	ID int64 `json:"id"`
+}
+
+type AccessTokenInfo struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  int64    `json:"created_at"`
+	ExpiredAt  *int64   `json:"expired_at"`
+	LastUsedAt *int64   `json:"last_used_at"`
+}
+
+type CreateAccessTokenResult struct {
+	AccessTokenInfo
+	Token string `json:"token"` // 仅在创建时返回一次
+}
+
+// CreateAccessToken 创建个人访问令牌
+// token 只在这里返回一次，数据库中只保存它的hash
+func CreateAccessToken(ctx *gin.Context, req *CreateAccessTokenPayload) (*CreateAccessTokenResult, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if !govalidator.IsByteLength(name, 1, AccessTokenNameLenMax) {
+		return nil, errors.P(errors.AccessToken, errors.Name, errors.InvalidLength)
+	}
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpiresIn < 0 {
+		return nil, errors.P(errors.AccessToken, errors.ExpiresIn, errors.Invalid)
+	}
+
+	token, err := buildAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	t := &accesstoken.AccessToken{
+		UserID:    sess.User().ID,
+		Name:      name,
+		TokenHash: secret.HashToken(token),
+		Scopes:    strings.Join(scopes, ","),
+	}
+	if req.ExpiresIn > 0 {
+		expiredAt := time.Now().Add(time.Duration(req.ExpiresIn) * time.Second).Unix()
+		t.ExpiredAt = &expiredAt
+	}
+	err = accesstoken.AddAccessToken(db.DB, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateAccessTokenResult{
+		AccessTokenInfo: *toAccessTokenInfo(t),
+		Token:           token,
+	}, nil
+}
+
+// ListAccessTokens 当前用户未撤销的令牌（不包含token本身）
+func ListAccessTokens(ctx *gin.Context) ([]*AccessTokenInfo, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+
+	tokens, err := accesstoken.ListAccessTokensByUser(db.DB, sess.User().ID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*AccessTokenInfo, 0, len(tokens))
+	for _, t := range tokens {
+		result = append(result, toAccessTokenInfo(t))
+	}
+	return result, nil
+}
+
+// RevokeAccessToken 撤销当前用户的令牌
+func RevokeAccessToken(ctx *gin.Context, id int64) error {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return errors.Unauthorize()
+	}
+
+	err := db.Transact(func(tx sqlx.Ext) error {
+		t, err := accesstoken.GetAccessToken(tx, id)
+		if err != nil {
+			return err
+		}
+		if t == nil || t.RevokedAt != nil {
+			return errors.NotFoundError(errors.AccessToken)
+		}
+		if t.UserID != sess.User().ID {
+			return errors.AccessDenied(errors.AccessToken, errors.NoPermission)
+		}
+		return accesstoken.RevokeAccessToken(tx, t.ID)
+	})
+	return err
+}
+
+// IsAccessToken token是否是个人访问令牌（而不是session token）
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+// AuthenticateAccessToken 使用个人访问令牌认证，并记录令牌的最后使用时间
+// 令牌无效或权限不足时返回nil
+func AuthenticateAccessToken(token string, scopes ...string) (*userModel.User, error) {
+	if !IsAccessToken(token) {
+		return nil, nil
+	}
+	user, t, err := userModel.GetUserByAccessToken(db.DB, token, scopes...)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	err = accesstoken.UpdateLastUsed(db.DB, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	set := make(map[string]struct{}, len(scopes))
+	for _, s := range scopes {
+		s = strings.TrimSpace(s)
+		if _, ok := accesstoken.ScopeSet[s]; !ok {
+			return nil, errors.P(errors.AccessToken, errors.Scopes, errors.Invalid)
+		}
+		set[s] = struct{}{}
+	}
+	if len(set) == 0 {
+		return nil, errors.P(errors.AccessToken, errors.Scopes, errors.Empty)
+	}
+	result := make([]string, 0, len(set))
+	for s := range set {
+		result = append(result, s)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func buildAccessToken() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Trace(err)
+	}
+	return AccessTokenPrefix + hex.EncodeToString(buf), nil
+}
+
+func toAccessTokenInfo(t *accesstoken.AccessToken) *AccessTokenInfo {
+	return &AccessTokenInfo{
+		ID:         t.ID,
+		Name:       t.Name,
+		Scopes:     t.ScopeList(),
+		CreatedAt:  t.CreatedAt,
+		ExpiredAt:  t.ExpiredAt,
+		LastUsedAt: t.LastUsedAt,
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 
 	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/common/events"
 	"github.com/growerlab/backend/app/model/activate"
 	"github.com/growerlab/backend/app/model/db"
 	"github.com/growerlab/backend/app/model/user"
@@ -17,7 +19,13 @@ import (
 	"gopkg.in/asaskevich/govalidator.v9"
 )
 
+// ActivateExpiredTime 激活链接默认的有效期，可通过 account.activation_ttl_hours 修改
 const ActivateExpiredTime = 24 * time.Hour
+const ActivateResendInterval = time.Minute // 重新发送激活邮件的最小间隔
+
+type ResendActivationPayload struct {
+	Email string `json:"email"`
+}
 
 // 激活用户
 func Activate(payload *ActivationCodePayload) (err error) {
@@ -25,11 +33,58 @@ func Activate(payload *ActivationCodePayload) (err error) {
 		return errors.P(errors.ActivationCode, errors.Code, errors.Invalid)
 	}
 
+	var userID int64
 	err = db.Transact(func(tx sqlx.Ext) error {
-		err = DoActivate(tx, payload.Code)
+		userID, err = DoActivate(tx, payload.Code)
 		return err
 	})
-	return
+	if err != nil {
+		return err
+	}
+
+	u, err := user.GetUser(db.DB, userID)
+	if err != nil {
+		return err
+	}
+	if u != nil {
+		publishUserEvent(events.UserActivated, u)
+	}
+	return nil
+}
+
+// ResendActivation 重新发送激活邮件
+// 为避免暴露账号的激活状态，以下情况均直接返回nil：
+// - 用户不存在或已激活
+// - 距离上次发送不足 ActivateResendInterval
+func ResendActivation(ctx *gin.Context, email string) error {
+	if !govalidator.IsEmail(email) {
+		return errors.P(errors.User, errors.Email, errors.Invalid)
+	}
+
+	err := db.Transact(func(tx sqlx.Ext) error {
+		u, err := user.GetInactivatedUserByEmail(tx, email)
+		if err != nil {
+			return err
+		}
+		if u == nil || u.Verified() {
+			return nil
+		}
+
+		last, err := activate.GetLatestCode(tx, u.ID)
+		if err != nil {
+			return err
+		}
+		if last != nil && time.Since(time.Unix(last.CreatedAt, 0)) < ActivateResendInterval {
+			return nil
+		}
+		// 只有最新发送的链接有效
+		err = activate.DeleteUnusedCodes(tx, u.ID)
+		if err != nil {
+			return err
+		}
+		return DoPreActivate(tx, u.ID)
+	})
+	return err
 }
 
 // 激活账号的前期准备
@@ -56,36 +111,40 @@ func DoPreActivate(tx sqlx.Ext, userID int64) error {
 
 // 验证用户邮箱激活码
 //
-func DoActivate(tx sqlx.Ext, code string) error {
+// 返回被激活的用户id
+func DoActivate(tx sqlx.Ext, code string) (int64, error) {
 	acode, err := activate.GetCode(tx, code)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if acode == nil {
-		return errors.NotFoundError(errors.ActivationCode)
+		return 0, errors.NotFoundError(errors.ActivationCode)
 	}
 	// 是否已使用过
 	if acode.UsedAt != nil {
-		return errors.P(errors.ActivationCode, errors.Code, errors.Used)
+		return 0, errors.P(errors.ActivationCode, errors.Code, errors.Used)
 	}
 	// 是否过期
 	// TODO 对于已经过期的激活码，应当在前端允许再次发送激活码（目前这块前后端还未开发）
-	if acode.ExpiredAt < time.Now().Unix() {
-		return errors.P(errors.ActivationCode, errors.Code, errors.Expired)
+	if activationExpired(acode, activateTTL(), time.Now()) {
+		return 0, errors.P(errors.ActivationCode, errors.Code, errors.Expired)
 	}
 	// 将code改成已使用
 	err = activate.ActivateCode(tx, code)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	// 激活用户状态
 	err = user.ActivateUser(tx, acode.UserID)
-	return err
+	return acode.UserID, err
 }
 
 func buildActivateURL(code string) string {
+	return buildWebsiteURL(fmt.Sprintf("activate_user/%s", code))
+}
+
+func buildWebsiteURL(partURL string) string {
 	baseURL := conf.GetConf().WebsiteURL
-	partURL := fmt.Sprintf("activate_user/%s", code)
 	if !strings.HasSuffix(baseURL, "/") {
 		baseURL = baseURL + "/"
 	}
@@ -96,6 +155,24 @@ func buildActivateCode(userID int64) *activate.ActivationCode {
 	code := new(activate.ActivationCode)
 	code.UserID = userID
 	code.Code = uuid.UUIDv16()
-	code.ExpiredAt = time.Now().Add(ActivateExpiredTime).Unix()
+	code.ExpiredAt = time.Now().Add(activateTTL()).Unix()
 	return code
 }
+
+// activateTTL 配置的激活链接有效期
+func activateTTL() time.Duration {
+	hours := conf.GetConf().GetAccount().ActivationTTLHours
+	if hours <= 0 {
+		return ActivateExpiredTime
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// activationExpired 超过创建时的 expired_at，或按当前配置的有效期已过期
+// 缩短有效期后，之前发送的链接同样按新的有效期失效
+func activationExpired(code *activate.ActivationCode, ttl time.Duration, now time.Time) bool {
+	if code.ExpiredAt < now.Unix() {
+		return true
+	}
+	return now.Sub(time.Unix(code.CreatedAt, 0)) > ttl
+}
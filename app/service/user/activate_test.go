@@ -0,0 +1,26 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/growerlab/backend/app/model/activate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivationExpired(t *testing.T) {
+	created := time.Unix(1600000000, 0)
+	code := &activate.ActivationCode{
+		CreatedAt: created.Unix(),
+		ExpiredAt: created.Add(24 * time.Hour).Unix(),
+	}
+
+	assert.False(t, activationExpired(code, 24*time.Hour, created.Add(time.Hour)))
+	assert.False(t, activationExpired(code, 24*time.Hour, created.Add(24*time.Hour)))
+	assert.True(t, activationExpired(code, 24*time.Hour, created.Add(24*time.Hour+time.Second)))
+
+	// 缩短有效期后，已发送的链接按新的有效期失效
+	assert.True(t, activationExpired(code, time.Hour, created.Add(2*time.Hour)))
+	// 延长有效期不会延长已发送的链接
+	assert.True(t, activationExpired(code, 48*time.Hour, created.Add(25*time.Hour)))
+}
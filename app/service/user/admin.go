@@ -0,0 +1,242 @@
+package user
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/base"
+	"github.com/growerlab/backend/app/model/db"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/jmoiron/sqlx"
+)
+
+type SetUserActivePayload struct {
+	UserID int64 `json:"user_id"`
+}
+
+// DeactivateUser 管理员（moderator）停用用户，并注销该用户的所有session
+// 管理员不能停用自己，避免无法登录
+func DeactivateUser(ctx *gin.Context, userID int64) error {
+	currentUser, err := currentAdmin(ctx, userModel.RoleModerator)
+	if err != nil {
+		return err
+	}
+	if currentUser.ID == userID {
+		return errors.AccessDenied(errors.User, errors.Self)
+	}
+
+	err = db.Transact(func(tx sqlx.Ext) error {
+		u, err := userModel.GetUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return errors.NotFoundError(errors.User)
+		}
+
+		err = userModel.SetActive(tx, u.ID, false)
+		if err != nil {
+			return err
+		}
+		_, err = sessionModel.DeleteSessionsByOwner(tx, u.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	userModel.InvalidateUserTokens(userID)
+	return nil
+}
+
+// ReactivateUser 管理员恢复被停用的用户
+func ReactivateUser(ctx *gin.Context, userID int64) error {
+	if _, err := currentAdmin(ctx, userModel.RoleModerator); err != nil {
+		return err
+	}
+
+	err := db.Transact(func(tx sqlx.Ext) error {
+		u, err := userModel.GetUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return errors.NotFoundError(errors.User)
+		}
+		return userModel.SetActive(tx, u.ID, true)
+	})
+	if err != nil {
+		return err
+	}
+	userModel.InvalidateUserTokens(userID)
+	return nil
+}
+
+type SetUserRolesPayload struct {
+	UserID int64    `json:"user_id"`
+	Roles  []string `json:"roles"`
+}
+
+// SetUserRoles 超级管理员设置用户的角色（覆盖原有的角色），roles 为空时取消所有角色
+// 不能修改自己的角色，避免误操作后失去超级管理员权限
+func SetUserRoles(ctx *gin.Context, payload *SetUserRolesPayload) error {
+	currentUser, err := currentAdmin(ctx, userModel.RoleSuperAdmin)
+	if err != nil {
+		return err
+	}
+	if currentUser.ID == payload.UserID {
+		return errors.AccessDenied(errors.User, errors.Self)
+	}
+	for _, r := range payload.Roles {
+		if !userModel.ValidRole(r) {
+			return errors.P(errors.User, errors.Role, errors.Invalid)
+		}
+	}
+
+	err = db.Transact(func(tx sqlx.Ext) error {
+		u, err := userModel.GetUser(tx, payload.UserID)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return errors.NotFoundError(errors.User)
+		}
+		return userModel.SetRoles(tx, u.ID, payload.Roles)
+	})
+	if err != nil {
+		return err
+	}
+	// 缓存中的用户包含角色，需要重新获取
+	userModel.InvalidateUserTokens(payload.UserID)
+	return nil
+}
+
+const AdminListUsersMaxPer = 100
+
+type AdminUserInfo struct {
+	ID          int64    `json:"id"`
+	Username    string   `json:"username"`
+	Name        string   `json:"name"`
+	Email       string   `json:"email"`
+	CreatedAt   int64    `json:"created_at"`
+	VerifiedAt  *int64   `json:"verified_at"`
+	SuspendedAt *int64   `json:"suspended_at"`
+	LastLoginAt *int64   `json:"last_login_at"`
+	LastLoginIP *string  `json:"last_login_ip"`
+	RegisterIP  string   `json:"register_ip"`
+	IsAdmin     bool     `json:"is_admin"` // 是否拥有 super_admin
+	Roles       []string `json:"roles"`
+	DeletedAt   *int64   `json:"deleted_at"`
+
+	NamespacePath string `json:"namespace_path"`
+}
+
+// AdminListUsers 管理员分页查看用户列表（page从0开始），同时返回满足筛选条件的用户总数
+func AdminListUsers(ctx *gin.Context, filter userModel.UserFilter, page, per uint64) (*base.Page[*AdminUserInfo], error) {
+	if _, err := currentAdmin(ctx, userModel.RoleModerator, userModel.RoleSupport); err != nil {
+		return nil, err
+	}
+	if per == 0 || per > AdminListUsersMaxPer {
+		per = AdminListUsersMaxPer
+	}
+
+	users, err := base.Paginate(page, per,
+		func(page, per uint64) ([]*userModel.User, error) {
+			users, err := userModel.ListUsersFiltered(db.Replica(), filter, page, per)
+			if err != nil {
+				return nil, err
+			}
+			return users, userModel.WithNamespaces(db.Replica(), users)
+		},
+		func() (int64, error) {
+			return userModel.CountUsersFiltered(db.Replica(), filter)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return base.MapPage(users, toAdminUserInfo), nil
+}
+
+// AdminFindUsersByIP 管理员按注册IP或最后登录IP查找用户（排查滥用），分页同 AdminListUsers
+func AdminFindUsersByIP(ctx *gin.Context, ip string, page, per uint64) (*base.Page[*AdminUserInfo], error) {
+	if _, err := currentAdmin(ctx, userModel.RoleModerator, userModel.RoleSupport); err != nil {
+		return nil, err
+	}
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return nil, errors.P(errors.User, errors.IP, errors.Invalid)
+	}
+	ip = parsed.String()
+	if per == 0 || per > AdminListUsersMaxPer {
+		per = AdminListUsersMaxPer
+	}
+
+	users, err := base.Paginate(page, per,
+		func(page, per uint64) ([]*userModel.User, error) {
+			users, err := userModel.FindByIP(db.Replica(), ip, page, per)
+			if err != nil {
+				return nil, err
+			}
+			return users, userModel.WithNamespaces(db.Replica(), users)
+		},
+		func() (int64, error) {
+			return userModel.CountByIP(db.Replica(), ip)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return base.MapPage(users, toAdminUserInfo), nil
+}
+
+// AdminGetUser 管理员查看用户详情，包含已注销（软删除）的用户
+func AdminGetUser(ctx *gin.Context, userID int64) (*AdminUserInfo, error) {
+	if _, err := currentAdmin(ctx, userModel.RoleModerator, userModel.RoleSupport); err != nil {
+		return nil, err
+	}
+	u, err := userModel.GetUserIncludingDeleted(db.Replica(), userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.NotFoundError(errors.User)
+	}
+	return toAdminUserInfo(u), nil
+}
+
+func toAdminUserInfo(u *userModel.User) *AdminUserInfo {
+	info := &AdminUserInfo{
+		ID:          u.ID,
+		Username:    u.Username,
+		Name:        u.Name,
+		Email:       u.Email,
+		CreatedAt:   u.CreatedAt,
+		VerifiedAt:  u.VerifiedAt,
+		SuspendedAt: u.SuspendedAt,
+		LastLoginAt: u.LastLoginAt,
+		LastLoginIP: u.LastLoginIP,
+		RegisterIP:  u.RegisterIP,
+		IsAdmin:     u.HasRole(userModel.RoleSuperAdmin),
+		Roles:       u.RoleList(),
+		DeletedAt:   u.DeletedAt,
+	}
+	// 列表中已通过 WithNamespaces 批量获取，不会逐个查询
+	if ns := u.Namespace(); ns != nil {
+		info.NamespacePath = ns.Path
+	}
+	return info
+}
+
+// currentAdmin 当前登录的用户，且必须拥有 roles 中的任意一个角色（super_admin 拥有所有角色）
+func currentAdmin(ctx *gin.Context, roles ...string) (*userModel.User, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	if !sess.User().HasRole(roles...) {
+		return nil, errors.AccessDenied(errors.User, errors.NoPermission)
+	}
+	return sess.User(), nil
+}
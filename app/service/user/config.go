@@ -0,0 +1,31 @@
+package user
+
+import (
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/growerlab/backend/app/utils/ratelimit"
+)
+
+// sessionConf session的有效期等配置，启动时由 InitConfig 注入，测试时通过 SetSessionConfig 替换
+var sessionConf = conf.DefaultSession()
+
+// SetSessionConfig 替换session的配置（测试用），返回恢复为原来配置的函数
+func SetSessionConfig(c *conf.Session) (restore func()) {
+	old := sessionConf
+	sessionConf = c
+	return func() { sessionConf = old }
+}
+
+// InitConfig 读取已校验的配置：session有效期、再次确认密码的失败限制、token的缓存时间、第三方登录
+// 需在 conf.LoadConfig 之后调用
+func InitConfig() error {
+	cfg := conf.GetConf()
+	sessionConf = cfg.GetSession()
+	if ReauthLimiter == nil {
+		maxFailures, window := cfg.GetLogin().ReauthLimit()
+		ReauthLimiter = ratelimit.NewMemoryLimiter(window, maxFailures)
+	}
+	userModel.ConfigureTokenCache(sessionConf.TokenCacheTTL())
+	oauthProviders = newOAuthProviders(cfg.OAuth)
+	return nil
+}
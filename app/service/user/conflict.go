@@ -0,0 +1,14 @@
+package user
+
+import (
+	"github.com/growerlab/backend/app/common/errors"
+	userModel "github.com/growerlab/backend/app/model/user"
+)
+
+// conflictError 将 userModel.ErrConflict 转换为客户端可重试的 Conflict 错误，其他错误原样返回
+func conflictError(err error) error {
+	if errors.Cause(err) == userModel.ErrConflict {
+		return errors.ConflictError(errors.User)
+	}
+	return err
+}
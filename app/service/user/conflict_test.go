@@ -0,0 +1,17 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/growerlab/backend/app/common/errors"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflictError(t *testing.T) {
+	assert.Nil(t, conflictError(nil))
+	err := conflictError(errors.Trace(userModel.ErrConflict))
+	assert.Equal(t, errors.ConflictError(errors.User).Error(), err.Error())
+	other := errors.NotFoundError(errors.User)
+	assert.Equal(t, other, conflictError(other))
+}
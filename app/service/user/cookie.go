@@ -0,0 +1,40 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/utils/conf"
+)
+
+// setTokenCookie 下发登录token的cookie，登录、刷新、退出都必须使用这里，保证cookie的属性一致
+// - HttpOnly：js无法读取token
+// - Secure：HTTPS请求（或配置了位于TLS反向代理之后）时，仅通过HTTPS传输
+// - SameSite=Lax：跨站的POST请求不携带cookie
+// - Name、Domain：来自配置（cookie），删除cookie时必须与下发时相同，否则浏览器不会删除
+// maxAge 为0时是浏览器会话cookie，小于0时删除cookie
+// gin(v1.4) 的 SetCookie 不支持 SameSite，所以这里直接使用 http.SetCookie
+func setTokenCookie(ctx *gin.Context, token string, maxAge int) {
+	cookie := conf.GetConf().GetCookie()
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     cookie.Name,
+		Value:    token,
+		MaxAge:   maxAge,
+		Path:     "/",
+		Domain:   cookie.Domain,
+		Secure:   secureCookie(ctx),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearCookie(ctx *gin.Context) {
+	setTokenCookie(ctx, "", -1)
+}
+
+func secureCookie(ctx *gin.Context) bool {
+	if ctx.Request.TLS != nil {
+		return true
+	}
+	return conf.GetConf().BehindTLSProxy
+}
@@ -0,0 +1,48 @@
+package user
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/common/events"
+	"github.com/growerlab/backend/app/model/db"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/jmoiron/sqlx"
+)
+
+type DeleteAccountPayload struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccount 用户注销自己的账号（软删除）
+// 需要再次确认密码，并注销该用户的所有session
+func DeleteAccount(ctx *gin.Context, password string) error {
+	if err := ReauthWithPassword(ctx, password); err != nil {
+		return err
+	}
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return errors.Unauthorize()
+	}
+	user := sess.User()
+
+	err := db.Transact(func(tx sqlx.Ext) error {
+		_, err := sessionModel.DeleteSessionsByOwner(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		err = userModel.SoftDeleteUser(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		clearCookie(ctx)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	userModel.InvalidateUserTokens(user.ID)
+	publishUserEvent(events.UserDeleted, user)
+	return nil
+}
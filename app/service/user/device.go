@@ -0,0 +1,54 @@
+package user
+
+import "strings"
+
+// 按顺序匹配，越具体的越靠前（例如 Edge、Opera 的UA中同样包含 Chrome、Safari）
+var browserSigns = []struct{ sign, name string }{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"git/", "Git"},
+	{"curl/", "curl"},
+}
+
+var osSigns = []struct{ sign, name string }{
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"Windows", "Windows"},
+	{"Linux", "Linux"},
+}
+
+// DeviceName 根据User-Agent得到便于展示的设备名，例如「Chrome on macOS」
+// 只做简单的关键字匹配，无法识别时返回空
+func DeviceName(userAgent string) string {
+	var browser, os string
+	for _, b := range browserSigns {
+		if strings.Contains(userAgent, b.sign) {
+			browser = b.name
+			break
+		}
+	}
+	for _, o := range osSigns {
+		if strings.Contains(userAgent, o.sign) {
+			os = o.name
+			break
+		}
+	}
+	switch {
+	case len(browser) > 0 && len(os) > 0:
+		return browser + " on " + os
+	case len(browser) > 0:
+		return browser
+	default:
+		return os
+	}
+}
@@ -0,0 +1,242 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/common/events"
+	"github.com/growerlab/backend/app/common/validate"
+	"github.com/growerlab/backend/app/model/db"
+	"github.com/growerlab/backend/app/model/emailchange"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/growerlab/backend/app/utils/logger"
+	"github.com/growerlab/backend/app/utils/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const EmailChangeExpiredTime = 24 * time.Hour
+
+// EmailChangeRevertExpiredTime 修改邮箱后，发送到旧邮箱的恢复链接的有效期
+const EmailChangeRevertExpiredTime = 7 * 24 * time.Hour
+
+type ChangeEmailPayload struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type ConfirmEmailChangePayload struct {
+	Token string `json:"token"`
+}
+
+type RevertEmailChangePayload struct {
+	Token string `json:"token"`
+}
+
+// ChangeEmail 申请修改邮箱
+// 新邮箱会先单独保存，并向新邮箱发送验证链接；验证通过前，用户仍使用旧邮箱登录
+func ChangeEmail(ctx *gin.Context, newEmail, password string) error {
+	newEmail = strings.TrimSpace(newEmail)
+	if err := validate.ValidateEmail(newEmail); err != nil {
+		return err
+	}
+
+	if err := ReauthWithPassword(ctx, password); err != nil {
+		return err
+	}
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return errors.Unauthorize()
+	}
+	user := sess.User()
+	if strings.EqualFold(user.Email, newEmail) {
+		return errors.P(errors.User, errors.Email, errors.Unchanged)
+	}
+
+	exists, err := userModel.ExistsEmailOrUsername(db.DB, "", newEmail)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.AlreadyExistsError(errors.User, errors.AlreadyExists)
+	}
+
+	c := buildEmailChange(user.ID, newEmail)
+	err = emailchange.AddEmailChange(db.DB, c)
+	if err != nil {
+		return err
+	}
+
+	confirmURL := buildWebsiteURL(fmt.Sprintf("confirm_email/%s", c.Token))
+	logger.Info("the confirm email url: %v", confirmURL)
+
+	// TODO 发送邮件
+	return nil
+}
+
+// ConfirmEmailChange 验证新邮箱，并将其设置为用户的主邮箱
+// 成功后向旧邮箱发送通知，附带在 EmailChangeRevertExpiredTime 内有效的恢复链接（见 RevertEmailChange）
+func ConfirmEmailChange(ctx *gin.Context, token string) error {
+	if len(token) == 0 {
+		return errors.P(errors.EmailChange, errors.Token, errors.Empty)
+	}
+
+	var user *userModel.User
+	var previousEmail, revertToken string
+	err := db.Transact(func(tx sqlx.Ext) error {
+		c, err := emailchange.GetEmailChange(tx, token)
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			return errors.NotFoundError(errors.EmailChange)
+		}
+		if c.UsedAt != nil {
+			return errors.P(errors.EmailChange, errors.Token, errors.Used)
+		}
+		if c.ExpiredAt < time.Now().Unix() {
+			return errors.P(errors.EmailChange, errors.Token, errors.Expired)
+		}
+
+		// 申请之后，该邮箱可能已被其他用户注册
+		exists, err := userModel.ExistsEmailOrUsername(tx, "", c.Email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return errors.AlreadyExistsError(errors.User, errors.AlreadyExists)
+		}
+
+		err = emailchange.UseEmailChange(tx, token)
+		if err != nil {
+			return err
+		}
+		u, err := userModel.GetUser(tx, c.UserID)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return errors.NotFoundError(errors.User)
+		}
+		user = u
+		err = userModel.UpdateEmail(tx, u.ID, u.Version, c.Email)
+		if err != nil {
+			return conflictError(err)
+		}
+		// 公开的是旧邮箱时，改为公开新邮箱，不再展示已不属于用户的地址
+		if strings.EqualFold(u.PublicEmail, u.Email) {
+			err = userModel.UpdateProfile(tx, u.ID, u.Name, c.Email)
+			if err != nil {
+				return err
+			}
+		}
+
+		previousEmail, revertToken = u.Email, uuid.UUID()
+		revertExpiredAt := time.Now().Add(EmailChangeRevertExpiredTime).Unix()
+		return emailchange.SetRevert(tx, c.ID, previousEmail, revertToken, revertExpiredAt)
+	})
+	if err != nil {
+		return err
+	}
+	userModel.InvalidateUserTokens(user.ID)
+	notifyEmailChanged(user, previousEmail, revertToken)
+	return nil
+}
+
+// notifyEmailChanged 通知旧邮箱：邮箱已被修改，如非本人操作可通过恢复链接找回
+func notifyEmailChanged(user *userModel.User, previousEmail, revertToken string) {
+	revertURL := buildWebsiteURL(fmt.Sprintf("revert_email/%s", revertToken))
+	logger.Info("the revert email url for %s: %v", previousEmail, revertURL)
+
+	// TODO 发送邮件
+	publishUserEvent(events.UserEmailChanged, user)
+}
+
+// RevertEmailChange 通过发送到旧邮箱的链接恢复旧邮箱，并注销该用户的所有session
+// 不需要登录：账号被盗时，盗用者可能已经修改了密码
+func RevertEmailChange(ctx *gin.Context, token string) error {
+	if len(token) == 0 {
+		return errors.P(errors.EmailChange, errors.Token, errors.Empty)
+	}
+
+	var userID int64
+	err := db.Transact(func(tx sqlx.Ext) error {
+		c, err := emailchange.GetEmailChangeByRevertToken(tx, token)
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			return errors.NotFoundError(errors.EmailChange)
+		}
+		if err = checkRevertable(c, time.Now().Unix()); err != nil {
+			return err
+		}
+
+		u, err := userModel.GetUser(tx, c.UserID)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return errors.NotFoundError(errors.User)
+		}
+		// 修改之后，旧邮箱可能已被其他用户使用
+		other, err := userModel.GetUserByEmail(tx, c.PreviousEmail)
+		if err != nil {
+			return err
+		}
+		if other != nil && other.ID != u.ID {
+			return errors.P(errors.User, errors.Email, errors.AlreadyExists)
+		}
+
+		err = emailchange.UseRevert(tx, c.ID)
+		if err != nil {
+			return err
+		}
+		err = userModel.UpdateEmail(tx, u.ID, u.Version, c.PreviousEmail)
+		if err != nil {
+			return conflictError(err)
+		}
+		if strings.EqualFold(u.PublicEmail, u.Email) {
+			err = userModel.UpdateProfile(tx, u.ID, u.Name, c.PreviousEmail)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = sessionModel.DeleteSessionsByOwner(tx, u.ID)
+		if err != nil {
+			return err
+		}
+		userID = u.ID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	userModel.InvalidateUserTokens(userID)
+	logger.Ctx(ctx).With("user_id", userID).Warn("email change reverted")
+	return nil
+}
+
+// checkRevertable 恢复链接只能使用一次，且必须在有效期内
+func checkRevertable(c *emailchange.EmailChange, now int64) error {
+	if c.RevertedAt != nil {
+		return errors.P(errors.EmailChange, errors.Token, errors.Used)
+	}
+	if c.RevertExpiredAt == nil || *c.RevertExpiredAt < now {
+		return errors.P(errors.EmailChange, errors.Token, errors.Expired)
+	}
+	return nil
+}
+
+func buildEmailChange(userID int64, email string) *emailchange.EmailChange {
+	return &emailchange.EmailChange{
+		UserID:    userID,
+		Email:     email,
+		Token:     uuid.UUID(),
+		ExpiredAt: time.Now().Add(EmailChangeExpiredTime).Unix(),
+	}
+}
@@ -0,0 +1,24 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/emailchange"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRevertable(t *testing.T) {
+	expiredAt := int64(100)
+	c := &emailchange.EmailChange{RevertExpiredAt: &expiredAt}
+	assert.Nil(t, checkRevertable(c, 99))
+	assert.Nil(t, checkRevertable(c, 100))
+
+	expired := errors.P(errors.EmailChange, errors.Token, errors.Expired).Error()
+	assert.Equal(t, expired, checkRevertable(c, 101).Error())
+	assert.Equal(t, expired, checkRevertable(&emailchange.EmailChange{}, 0).Error())
+
+	revertedAt := int64(50)
+	c.RevertedAt = &revertedAt
+	assert.Equal(t, errors.P(errors.EmailChange, errors.Token, errors.Used).Error(), checkRevertable(c, 60).Error())
+}
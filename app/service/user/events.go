@@ -0,0 +1,15 @@
+package user
+
+import (
+	"github.com/growerlab/backend/app/common/events"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/logger"
+)
+
+// publishUserEvent 必须在事务提交之后调用；推送失败只记录日志，不影响当前请求
+func publishUserEvent(eventType string, user *userModel.User) {
+	err := events.UserBus.Publish(events.NewUserEvent(eventType, user.ID, user.Username))
+	if err != nil {
+		logger.Error("publish user event %s: %+v\n", eventType, err)
+	}
+}
@@ -0,0 +1,172 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	"github.com/growerlab/backend/app/model/loginaudit"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	sshkeyModel "github.com/growerlab/backend/app/model/sshkey"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+)
+
+// ExportLoginHistoryLimit 导出的登录记录数量上限
+const ExportLoginHistoryLimit = 1000
+
+// UserDataExport 用户数据导出（只包含用户本人的数据）
+// 不包含密码、TOTP密钥、完整的session token等内部字段
+type UserDataExport struct {
+	ExportedAt   int64                `json:"exported_at"`
+	User         *UserDataExportUser  `json:"user"`
+	Namespace    *UserDataExportNS    `json:"namespace"`
+	Sessions     []*SessionInfo       `json:"sessions"`
+	LoginHistory []*LoginHistoryItem  `json:"login_history"`
+	SSHKeys      []*UserDataExportKey `json:"ssh_keys"`
+}
+
+type UserDataExportUser struct {
+	ID          int64   `json:"id"`
+	Email       string  `json:"email"`
+	Username    string  `json:"username"`
+	Name        string  `json:"name"`
+	PublicEmail string  `json:"public_email"`
+	CreatedAt   int64   `json:"created_at"`
+	VerifiedAt  *int64  `json:"verified_at"`
+	LastLoginAt *int64  `json:"last_login_at"`
+	LastLoginIP *string `json:"last_login_ip"`
+	RegisterIP  string  `json:"register_ip"`
+	TOTPEnabled bool    `json:"totp_enabled"`
+}
+
+type UserDataExportNS struct {
+	ID   int64  `json:"id"`
+	Path string `json:"path"`
+}
+
+type UserDataExportKey struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	PublicKey   string `json:"public_key"`
+	Fingerprint string `json:"fingerprint"`
+	CreatedAt   int64  `json:"created_at"`
+	LastUsedAt  *int64 `json:"last_used_at"`
+}
+
+// ExportUserData 导出当前用户的数据（JSON，key 按字典序排列）
+func ExportUserData(ctx *gin.Context) ([]byte, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	user := sess.User()
+
+	ns, err := nsModel.GetNamespace(db.DB, user.NamespaceID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := sessionModel.ListSessionsByOwner(db.DB, user.ID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	audits, err := loginaudit.ListLoginAuditsByUser(db.DB, user.ID, ExportLoginHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := sshkeyModel.ListSSHKeysByUser(db.DB, user.ID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	export := buildUserDataExport(user, ns, sessions, audits, keys, sess.Token())
+	export.ExportedAt = time.Now().Unix()
+	return marshalSorted(export)
+}
+
+func buildUserDataExport(
+	user *userModel.User,
+	ns *nsModel.Namespace,
+	sessions []*sessionModel.Session,
+	audits []*loginaudit.LoginAudit,
+	keys []*sshkeyModel.SSHKey,
+	currentToken string,
+) *UserDataExport {
+	export := &UserDataExport{
+		User: &UserDataExportUser{
+			ID:          user.ID,
+			Email:       user.Email,
+			Username:    user.Username,
+			Name:        user.Name,
+			PublicEmail: user.PublicEmail,
+			CreatedAt:   user.CreatedAt,
+			VerifiedAt:  user.VerifiedAt,
+			LastLoginAt: user.LastLoginAt,
+			LastLoginIP: user.LastLoginIP,
+			RegisterIP:  user.RegisterIP,
+			TOTPEnabled: user.TOTPEnabled(),
+		},
+		Sessions:     make([]*SessionInfo, 0, len(sessions)),
+		LoginHistory: make([]*LoginHistoryItem, 0, len(audits)),
+		SSHKeys:      make([]*UserDataExportKey, 0, len(keys)),
+	}
+	if ns != nil {
+		export.Namespace = &UserDataExportNS{ID: ns.ID, Path: ns.Path}
+	}
+	// 以下数据均按 owner/user id 查询，这里再过滤一次，确保不会混入其他用户的数据
+	for _, s := range sessions {
+		if s.OwnerID != user.ID {
+			continue
+		}
+		export.Sessions = append(export.Sessions, newSessionInfo(s, currentToken))
+	}
+	for _, a := range audits {
+		if a.UserID != user.ID {
+			continue
+		}
+		export.LoginHistory = append(export.LoginHistory, &LoginHistoryItem{
+			ClientIP:  a.ClientIP,
+			UserAgent: a.UserAgent,
+			Success:   a.Success,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+	for _, k := range keys {
+		if k.UserID != user.ID {
+			continue
+		}
+		export.SSHKeys = append(export.SSHKeys, &UserDataExportKey{
+			ID:          k.ID,
+			Title:       k.Title,
+			PublicKey:   k.PublicKey,
+			Fingerprint: k.Fingerprint,
+			CreatedAt:   k.CreatedAt,
+			LastUsedAt:  k.LastUsedAt,
+		})
+	}
+	return export
+}
+
+// marshalSorted 序列化为 key 按字典序排列的JSON
+// encoding/json 对 map 的 key 排序，所以先转换为 map 再序列化；UseNumber 避免 int64 精度丢失
+func marshalSorted(v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var generic interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err = dec.Decode(&generic); err != nil {
+		return nil, errors.Trace(err)
+	}
+	out, err := json.MarshalIndent(generic, "", "  ")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return out, nil
+}
@@ -0,0 +1,54 @@
+package user
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/growerlab/backend/app/model/loginaudit"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	sshkeyModel "github.com/growerlab/backend/app/model/sshkey"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportUserData(t *testing.T) {
+	secret := "totp-secret"
+	user := &userModel.User{
+		ID:                1,
+		Email:             "moli@example.com",
+		EncryptedPassword: "$2a$10$hash",
+		Username:          "moli",
+		TOTPSecret:        &secret,
+	}
+	ns := &nsModel.Namespace{ID: 2, Path: "moli"}
+	sessions := []*sessionModel.Session{
+		{ID: 3, OwnerID: 1, Token: "token-of-moli"},
+		{ID: 4, OwnerID: 9, Token: "token-of-other"},
+	}
+	audits := []*loginaudit.LoginAudit{
+		{ID: 5, UserID: 1, ClientIP: "10.0.0.1", Success: true},
+		{ID: 6, UserID: 9, ClientIP: "10.0.0.9"},
+	}
+	keys := []*sshkeyModel.SSHKey{{ID: 7, UserID: 1, Title: "laptop"}}
+
+	export := buildUserDataExport(user, ns, sessions, audits, keys, "token-of-moli")
+	out, err := marshalSorted(export)
+	assert.Nil(t, err)
+	doc := string(out)
+
+	assert.NotContains(t, doc, "encrypted_password")
+	assert.NotContains(t, doc, "$2a$10$hash")
+	assert.NotContains(t, doc, secret)
+	assert.NotContains(t, doc, "token-of-moli")
+	assert.NotContains(t, doc, "10.0.0.9")
+	assert.Len(t, export.Sessions, 1)
+	assert.True(t, export.Sessions[0].Current)
+
+	// key 按字典序排列，且多次序列化结果一致
+	assert.True(t, strings.Index(doc, `"exported_at"`) < strings.Index(doc, `"login_history"`))
+	assert.True(t, strings.Index(doc, `"login_history"`) < strings.Index(doc, `"namespace"`))
+	assert.True(t, strings.Index(doc, `"sessions"`) < strings.Index(doc, `"ssh_keys"`))
+	again, _ := marshalSorted(export)
+	assert.Equal(t, doc, string(again))
+}
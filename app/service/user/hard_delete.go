@@ -0,0 +1,121 @@
+package user
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	repoModel "github.com/growerlab/backend/app/model/repository"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type HardDeleteUserPayload struct {
+	UserID int64 `json:"user_id"`
+	DryRun bool  `json:"dry_run"` // 为true时只返回将要删除的数据，不执行删除
+}
+
+type HardDeleteUserResult struct {
+	UserID  int64                    `json:"user_id"`
+	DryRun  bool                     `json:"dry_run"`
+	Deleted []*userModel.DeletedRows `json:"deleted"`
+}
+
+// HardDeleteUser 管理员永久删除用户（例如用户依法要求删除个人数据），软删除的用户同样可以删除
+// 1. 用户还有仓库，或是组织的owner时拒绝，需要先处理仓库、转让组织
+// 2. 在同一个事务中删除用户及其关联的数据
+// 3. 日志中只记录用户id和删除的行数，不记录用户名、邮箱等个人信息
+func HardDeleteUser(ctx *gin.Context, userID int64, dryRun bool) (result *HardDeleteUserResult, err error) {
+	admin, err := currentAdmin(ctx, userModel.RoleSuperAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if admin.ID == userID {
+		return nil, errors.AccessDenied(errors.User, errors.Self)
+	}
+
+	err = db.Transact(func(tx sqlx.Ext) error {
+		u, err := userModel.GetUserIncludingDeleted(tx, userID)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return errors.NotFoundError(errors.User)
+		}
+		if u.IsStaff() {
+			return errors.AccessDenied(errors.User, errors.NoPermission)
+		}
+		if err = checkHardDeletable(tx, u); err != nil {
+			return err
+		}
+
+		var deleted []*userModel.DeletedRows
+		if dryRun {
+			deleted, err = userModel.CountHardDeleteUser(tx, u.ID)
+		} else {
+			deleted, err = userModel.HardDeleteUser(tx, u.ID)
+		}
+		if err != nil {
+			return err
+		}
+		result = &HardDeleteUserResult{
+			UserID:  u.ID,
+			DryRun:  dryRun,
+			Deleted: deleted,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	userModel.InvalidateUserTokens(userID)
+	var rows int64
+	for _, d := range result.Deleted {
+		rows += d.Rows
+	}
+	logger.Ctx(ctx).
+		With("target_user_id", userID).
+		With("deleted_rows", rows).
+		Warn("user hard deleted")
+	return result, nil
+}
+
+// checkHardDeletable 用户的仓库、组织不会随用户一起删除，需要先由用户或管理员处理
+func checkHardDeletable(tx sqlx.Queryer, u *userModel.User) error {
+	exists, err := repoModel.ExistsRepositoriesOfUser(tx, u.ID, u.NamespaceID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.P(errors.User, errors.ID, errors.InUse)
+	}
+
+	owned, err := nsModel.ListNamespacesByOwner(tx, nsModel.TypeOrg, u.ID)
+	if err != nil {
+		return err
+	}
+	if len(owned) > 0 {
+		return errors.P(errors.Organization, errors.ID, errors.InUse)
+	}
+
+	// 用户是组织唯一的owner成员时，删除后组织将没有owner
+	orgs, err := nsModel.ListNamespacesByMember(tx, nsModel.TypeOrg, u.ID)
+	if err != nil {
+		return err
+	}
+	for _, org := range orgs {
+		owners, err := nsModel.ListOwnersForUpdate(tx, org.ID)
+		if err != nil {
+			return err
+		}
+		if len(owners) == 1 && owners[0].UserID == u.ID {
+			return errors.P(errors.Member, errors.Role, errors.LastOwner)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	"github.com/growerlab/backend/app/model/idempotency"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/jmoiron/sqlx"
+)
+
+// RegisterIdempotencyWindow 注册的幂等键的有效期，期间使用相同的键重试时返回首次注册的结果
+const RegisterIdempotencyWindow = 24 * time.Hour
+
+// idempotencyKeyRegex 可见的ASCII字符（例如uuid）
+var idempotencyKeyRegex = regexp.MustCompile(`^[\x21-\x7e]+$`)
+
+func validateIdempotencyKey(key string) error {
+	if len(key) > idempotency.KeyMaxLen || !idempotencyKeyRegex.MatchString(key) {
+		return errors.P(errors.Request, errors.IdempotencyKey, errors.Invalid)
+	}
+	return nil
+}
+
+// registerRequestHash 注册请求的摘要，用于发现同一个幂等键被用于不同的注册请求
+// 不包含密码，避免在数据库中保存可用于验证密码的摘要
+func registerRequestHash(payload *NewUserPayload) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(payload.Email) + "\n" + payload.Username))
+	return hex.EncodeToString(sum[:])
+}
+
+func buildRegisterIdempotencyKey(payload *NewUserPayload, userID int64) *idempotency.Key {
+	now := clk.Now()
+	return &idempotency.Key{
+		Scope:       idempotency.ScopeRegister,
+		ClientKey:   payload.IdempotencyKey,
+		RequestHash: registerRequestHash(payload),
+		ResourceID:  userID,
+		CreatedAt:   now.Unix(),
+		ExpiredAt:   now.Add(RegisterIdempotencyWindow).Unix(),
+	}
+}
+
+// saveRegisterIdempotencyKey 在注册的事务中保存幂等键（同时删除已过期的同名键）
+func saveRegisterIdempotencyKey(tx sqlx.Execer, payload *NewUserPayload, userID int64) error {
+	err := idempotency.DeleteExpiredKey(tx, idempotency.ScopeRegister, payload.IdempotencyKey, clk.Now().Unix())
+	if err != nil {
+		return err
+	}
+	return idempotency.AddKey(tx, buildRegisterIdempotencyKey(payload, userID))
+}
+
+// replayRegister 幂等键有效时，返回首次注册的结果；没有可用的结果时返回 nil, nil
+// 同一个键用于不同的注册请求时返回 P(Request, IdempotencyKey, InUse)
+func replayRegister(payload *NewUserPayload) (*RegisterResult, error) {
+	k, err := idempotency.GetKey(db.DB, idempotency.ScopeRegister, payload.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil || k.Expired(clk.Now().Unix()) {
+		return nil, nil
+	}
+	if k.RequestHash != registerRequestHash(payload) {
+		return nil, errors.P(errors.Request, errors.IdempotencyKey, errors.InUse)
+	}
+	user, err := userModel.GetUser(db.DB, k.ResourceID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, nil
+	}
+	return newRegisterResult(user), nil
+}
@@ -0,0 +1,42 @@
+package user
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/growerlab/backend/app/utils/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateIdempotencyKey(t *testing.T) {
+	assert.Nil(t, validateIdempotencyKey("3f2b8c1e-7d4a-4a5b-9c3e-1f2a3b4c5d6e"))
+	assert.Nil(t, validateIdempotencyKey(strings.Repeat("a", 64)))
+
+	assert.NotNil(t, validateIdempotencyKey(strings.Repeat("a", 65)))
+	assert.NotNil(t, validateIdempotencyKey("has space"))
+	assert.NotNil(t, validateIdempotencyKey("中文"))
+}
+
+func TestRegisterRequestHash(t *testing.T) {
+	a := &NewUserPayload{Email: "A@example.com", Username: "alice", Password: "secret-1"}
+	b := &NewUserPayload{Email: "a@example.com", Username: "alice", Password: "secret-2"}
+	c := &NewUserPayload{Email: "a@example.com", Username: "alice2"}
+
+	// 邮箱不区分大小写，密码不参与摘要
+	assert.Equal(t, registerRequestHash(a), registerRequestHash(b))
+	assert.NotEqual(t, registerRequestHash(a), registerRequestHash(c))
+	assert.Len(t, registerRequestHash(a), 64)
+}
+
+func TestBuildRegisterIdempotencyKey(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1600000000, 0))
+	defer SetClock(fake)()
+
+	k := buildRegisterIdempotencyKey(&NewUserPayload{Email: "a@example.com", Username: "alice", IdempotencyKey: "k1"}, 7)
+	assert.Equal(t, "register", k.Scope)
+	assert.Equal(t, "k1", k.ClientKey)
+	assert.Equal(t, int64(7), k.ResourceID)
+	assert.False(t, k.Expired(fake.Now().Add(RegisterIdempotencyWindow).Unix()))
+	assert.True(t, k.Expired(fake.Now().Add(RegisterIdempotencyWindow+time.Second).Unix()))
+}
@@ -0,0 +1,97 @@
+package user
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/growerlab/backend/app/utils/clientip"
+	"github.com/growerlab/backend/app/utils/logger"
+	"github.com/growerlab/backend/app/utils/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ImpersonationLifetime 代登录session的有效期（不会被延长）
+const ImpersonationLifetime = 30 * time.Minute
+
+type ImpersonatePayload struct {
+	UserID int64 `json:"user_id"`
+}
+
+type ImpersonateResult struct {
+	Token     string `json:"token"`
+	ExpiredAt int64  `json:"expired_at"`
+}
+
+// Impersonate 管理员以目标用户的身份登录（用于客服排查问题）
+// 生成的session记录发起的管理员（impersonator_id），只允许在管理员当前的网络中使用，且不会被延长
+// token 只在响应中返回，不写入cookie，避免覆盖管理员自己的登录状态
+// 不允许代登录其他管理员（拥有任意角色的用户），避免借此获得其他管理员的权限
+// 该session不能创建凭证、修改账号安全设置，见 RejectImpersonated
+func Impersonate(ctx *gin.Context, targetUserID int64) (result *ImpersonateResult, err error) {
+	admin, err := currentAdmin(ctx, userModel.RoleSupport)
+	if err != nil {
+		return nil, err
+	}
+	if admin.ID == targetUserID {
+		return nil, errors.AccessDenied(errors.User, errors.Self)
+	}
+
+	clientIP := clientip.FromRequest(ctx.Request)
+	err = db.Transact(func(tx sqlx.Ext) error {
+		target, err := userModel.GetUser(tx, targetUserID)
+		if err != nil {
+			return err
+		}
+		if target == nil {
+			return errors.NotFoundError(errors.User)
+		}
+		if target.IsStaff() {
+			return errors.AccessDenied(errors.User, errors.NoPermission)
+		}
+
+		now := time.Now()
+		sess := &sessionModel.Session{
+			OwnerID:        target.ID,
+			Token:          uuid.UUID(),
+			ClientIP:       clientIP,
+			CreatedAt:      now.Unix(),
+			ExpiredAt:      now.Add(ImpersonationLifetime).Unix(),
+			BindIP:         true,
+			ImpersonatorID: &admin.ID,
+		}
+		err = sessionModel.New(tx).Add(sess)
+		if err != nil {
+			return err
+		}
+		result = &ImpersonateResult{Token: sess.Token, ExpiredAt: sess.ExpiredAt}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Ctx(ctx).With("target_user_id", targetUserID).Warn("impersonation session issued")
+	return result, nil
+}
+
+// RejectImpersonated 当前请求使用的是代登录的session时返回 AccessDenied
+// 代登录只用于排查问题，不允许借此创建长期有效的凭证（访问令牌、SSH key、两步验证、第三方账号）或修改账号安全设置
+func RejectImpersonated(ctx *gin.Context) error {
+	token := session.GetUserToken(ctx)
+	if len(token) == 0 {
+		return nil
+	}
+	sess, err := sessionModel.GetSessionByToken(db.DB, token)
+	if err != nil {
+		return err
+	}
+	if sess != nil && sess.Impersonated() {
+		return errors.AccessDenied(errors.Session, errors.NoPermission)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package user
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/clientip"
+	"github.com/jmoiron/sqlx"
+)
+
+// ImportUsersMaxBatch 单次导入的用户数上限
+const ImportUsersMaxBatch = 500
+
+type ImportUsersPayload struct {
+	Users []*NewUserPayload `json:"users"`
+}
+
+type ImportUsersResult struct {
+	IDs []int64 `json:"ids"` // 与请求中的用户顺序一致
+}
+
+// ImportUsers 管理员批量导入用户（从其他系统迁移）
+// 所有用户及其命名空间在同一个事务中创建，任意一个失败时整体回滚
+// 导入的用户视为已验证过邮箱，不再发送激活邮件
+func ImportUsers(ctx *gin.Context, payload *ImportUsersPayload) (*ImportUsersResult, error) {
+	_, err := currentAdmin(ctx, userModel.RoleSuperAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload.Users) == 0 {
+		return nil, errors.P(errors.User, errors.ID, errors.Empty)
+	}
+	if len(payload.Users) > ImportUsersMaxBatch {
+		return nil, errors.P(errors.User, errors.ID, errors.InvalidLength)
+	}
+
+	users := make([]*userModel.User, 0, len(payload.Users))
+	for _, p := range payload.Users {
+		if err = validateRegisterUser(p); err != nil {
+			return nil, err
+		}
+		user, err := buildUser(p, clientip.FromRequest(ctx.Request))
+		if err != nil {
+			return nil, err
+		}
+		verifiedAt := time.Now().Unix()
+		user.VerifiedAt = &verifiedAt
+		// 导入的用户邮箱视为已验证，与激活后的用户一致，默认公开主邮箱
+		user.PublicEmail = user.Email
+		users = append(users, user)
+	}
+
+	err = db.Transact(func(tx sqlx.Ext) error {
+		err := userModel.AddUsers(tx, users)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			ns := buildNamespace(user)
+			err = nsModel.AddNamespace(tx, ns)
+			if err != nil {
+				return err
+			}
+			err = userModel.UpdateNamespace(tx, user.ID, ns.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportUsersResult{IDs: make([]int64, 0, len(users))}
+	for _, user := range users {
+		result.IDs = append(result.IDs, user.ID)
+	}
+	return result, nil
+}
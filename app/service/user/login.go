@@ -2,22 +2,27 @@ package user
 
 import (
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/common/events"
 	"github.com/growerlab/backend/app/model/db"
+	"github.com/growerlab/backend/app/model/loginaudit"
 	sessionModel "github.com/growerlab/backend/app/model/session"
 	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/clientip"
+	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/growerlab/backend/app/utils/logger"
 	"github.com/growerlab/backend/app/utils/pwd"
+	"github.com/growerlab/backend/app/utils/ratelimit"
 	"github.com/growerlab/backend/app/utils/uuid"
 	"github.com/jmoiron/sqlx"
 	"gopkg.in/asaskevich/govalidator.v9"
 )
 
-const TokenExpiredTime = 24 * time.Hour * 30 // 30天过期
-const tokenField = "auth-user-token"
-
 // Login 用户登录
 //  用户邮箱是否已验证
 //	更新用户最后的登录时间/IP
@@ -26,26 +31,144 @@ func Login(ctx *gin.Context, req *LoginBasicAuth) (
 	result *UserLoginResult,
 	err error,
 ) {
-	loginService := NewLoginService(ctx.ClientIP(), req)
+	// 参数错误不计入登录失败（不消耗限流额度，也不记录登录日志）
+	if err = req.Validate(); err != nil {
+		return nil, err
+	}
+
+	limiter := getLoginLimiter()
+	ip := clientip.FromRequest(ctx.Request)
+	if !limiter.Allow(ip) {
+		return nil, errors.AccessDenied(errors.User, errors.RateLimited)
+	}
+
+	start := time.Now()
+	defer func() {
+		loginLatency.Observe(time.Since(start).Seconds())
+	}()
+
+	loginService := NewLoginService(ip, req)
+	loginService.userAgent = ctx.Request.UserAgent()
 	result, err = loginService.Do(db.DB)
 	if err != nil {
-		return nil, err
+		// 只有失败的登录消耗额度，正常用户的登录不受影响
+		limiter.Hit(ip, 1)
+		loginService.auditFailure()
+		reason := loginFailureReason(err)
+		loginFailure.WithLabelValues(reason).Inc()
+		logger.Ctx(ctx).WithFields(loginService.logFields()).With("reason", reason).Warn("login failed")
+		return nil, clientLoginError(err, conf.GetConf().GetLogin().StrictPrivacy)
+	}
+	// 需要两步验证时，尚未生成session
+	if result.TOTPRequired {
+		return
 	}
-	loginService.SetCookie(ctx)
+	loginService.succeeded(ctx)
 	return
 }
 
+// succeeded 登录事务提交之后：下发cookie、清除被删除的session的缓存、记录日志和事件
+func (l *LoginService) succeeded(ctx *gin.Context) {
+	l.SetCookie(ctx)
+	if l.evicted > 0 {
+		userModel.InvalidateUserTokens(l.user.ID)
+	}
+	loginSuccess.Inc()
+	logger.Ctx(ctx).WithFields(l.logFields()).Info("login succeeded")
+	publishUserEvent(events.UserLoggedIn, l.user)
+}
+
+// clientLoginError 返回给客户端的登录错误
+// strict 时，用户不存在、未激活、密码错误统一返回 InvalidCredentials（具体原因只记录在日志中）
+func clientLoginError(err error, strict bool) error {
+	if !strict {
+		return err
+	}
+	switch loginFailureReason(err) {
+	case FailureNotFound, FailureNotVerified, FailureBadPassword:
+		return errors.AccessDenied(errors.User, errors.InvalidCredentials)
+	}
+	return err
+}
+
+// LoginLimiter 按客户端IP限制登录失败次数（滑动窗口），为nil时使用内存实现
+// 多实例部署时，可在启动时替换为基于redis的实现
+var LoginLimiter ratelimit.Limiter
+var loginLimiterOnce sync.Once
+
+func getLoginLimiter() ratelimit.Limiter {
+	loginLimiterOnce.Do(func() {
+		if LoginLimiter == nil {
+			limit := conf.GetConf().GetLogin().IPFailedPerMinute
+			if limit <= 0 {
+				limit = 20
+			}
+			LoginLimiter = ratelimit.NewMemoryLimiter(time.Minute, limit)
+		}
+	})
+	return LoginLimiter
+}
+
 type LoginBasicAuth struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Identifier string `json:"identifier" form:"identifier"` // 用户名或邮箱
+	Email      string `json:"email" form:"email"`           // 已废弃，请使用 Identifier（为兼容旧版本前端保留）
+	Password   string `json:"password" form:"password"`
+	TOTPCode   string `json:"totp_code" form:"totp_code"` // 两步验证码或恢复码，仅启用了两步验证的用户需要
+	RememberMe *bool  `json:"remember_me" form:"remember_me"`
+	BindIP     bool   `json:"bind_ip" form:"bind_ip"` // 为true时，session只能在登录时的网络中使用
+}
+
+// Validate 检查必填参数，不访问数据库
+// 登录标识或密码为空时返回 InvalidParameter，而不是查询后返回 NotFound
+func (a *LoginBasicAuth) Validate() error {
+	login := strings.TrimSpace(a.Login())
+	switch {
+	case len(login) == 0:
+		return errors.P(errors.User, errors.Identifier, errors.Empty)
+	case !govalidator.IsByteLength(login, 1, 255):
+		return errors.P(errors.User, errors.Identifier, errors.InvalidLength)
+	case len(a.Password) == 0:
+		return errors.P(errors.User, errors.Password, errors.Empty)
+	case !govalidator.IsByteLength(a.Password, PasswordLenMin, PasswordLenMax):
+		return errors.P(errors.User, errors.Password, errors.InvalidLength)
+	}
+	return nil
+}
+
+// Remember 是否“记住我”，未传入时为true（与旧版本保持一致，30天过期）
+func (a *LoginBasicAuth) Remember() bool {
+	return a.RememberMe == nil || *a.RememberMe
+}
+
+// tokenLifetime session的有效期（见配置 session）
+func (a *LoginBasicAuth) tokenLifetime() time.Duration {
+	if a.Remember() {
+		return sessionConf.RememberTTL()
+	}
+	return sessionConf.TTL()
+}
+
+// Login 登录使用的用户名或邮箱
+// 同时传入时，Identifier 优先，Email 被忽略
+func (a *LoginBasicAuth) Login() string {
+	if len(a.Identifier) > 0 {
+		return a.Identifier
+	}
+	return a.Email
 }
 
 type LoginService struct {
-	ip   string
-	auth *LoginBasicAuth
+	ip        string
+	userAgent string
+	auth      *LoginBasicAuth
+
+	// user 根据登录标识找到的用户（登录失败时也可能存在）
+	user *userModel.User
 
 	// session 登录完成后的session
 	session *sessionModel.Session
+	// evicted 因超出session数量上限被删除的session数量
+	evicted int64
 }
 
 func NewLoginService(ip string, auth *LoginBasicAuth) *LoginService {
@@ -55,8 +178,13 @@ func NewLoginService(ip string, auth *LoginBasicAuth) *LoginService {
 	}
 }
 
+// SetCookie 记住我时，cookie的有效期与session一致；否则为浏览器会话cookie（关闭浏览器即失效）
 func (l *LoginService) SetCookie(ctx *gin.Context) {
-	ctx.SetCookie(tokenField, l.session.Token, 0, "/", ctx.Request.Host, false, false)
+	maxAge := 0
+	if l.auth.Remember() {
+		maxAge = int(l.auth.tokenLifetime().Seconds())
+	}
+	setTokenCookie(ctx, l.session.Token, maxAge)
 }
 
 func (l *LoginService) Do(src sqlx.Ext) (
@@ -68,73 +196,175 @@ func (l *LoginService) Do(src sqlx.Ext) (
 		return nil, err
 	}
 
-	err = db.Transact(func(tx sqlx.Ext) error {
-		err = userModel.UpdateLogin(tx, user.ID, l.ip)
-		if err != nil {
-			return err
+	// 已启用两步验证，但没有提交验证码时，告知客户端需要验证码（客户端带上验证码重新提交登录）
+	if user.TOTPEnabled() && len(l.auth.TOTPCode) == 0 {
+		return &UserLoginResult{TOTPRequired: true}, nil
+	}
+
+	// 登录是高并发路径，遇到死锁时重试（事务内只修改数据库和 l.session、result，可以安全地重复执行）
+	err = db.TransactRetry(func(tx sqlx.Ext) error {
+		if user.TOTPEnabled() {
+			err = verifySecondFactor(tx, user, l.auth.TOTPCode)
+			if err != nil {
+				return err
+			}
 		}
 
-		// 生成TOKEN返回给客户端
-		l.session = l.buildAuthSession(user.ID, l.ip)
-		err = sessionModel.New(tx).Add(l.session)
+		err = l.rehashPassword(tx, user)
 		if err != nil {
 			return err
 		}
-
-		// namespace
-		ns := user.Namespace()
-		result = &UserLoginResult{
-			Token:         l.session.Token,
-			NamespacePath: ns.Path,
-			Name:          user.Name,
-			Email:         user.Email,
-			PublicEmail:   user.PublicEmail,
-		}
-		return nil
+		result, err = l.issueSession(tx, user)
+		return err
 	})
 	return result, err
 }
 
-func (r *LoginService) prepare(src sqlx.Queryer) (user *userModel.User, err error) {
-	switch true {
-	case !govalidator.IsByteLength(r.auth.Email, 1, 255):
-		return nil, errors.InvalidParameterError(errors.User, errors.Email, errors.Empty)
-	case !govalidator.IsByteLength(r.auth.Password, PasswordLenMin, PasswordLenMax):
-		return nil, errors.InvalidParameterError(errors.User, errors.Password, errors.InvalidLength)
+// issueSession 认证通过后（密码、第三方账号）在事务中生成session：更新登录时间/IP、删除超出数量上限的session、记录登录日志
+func (l *LoginService) issueSession(tx sqlx.Ext, user *userModel.User) (*UserLoginResult, error) {
+	err := userModel.UpdateLogin(tx, user.ID, l.ip)
+	if err != nil {
+		return nil, err
 	}
 
-	if strings.Contains(r.auth.Email, "@") {
-		user, err = userModel.GetUserByEmail(src, r.auth.Email)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		user, err = userModel.GetUserByUsername(src, r.auth.Email)
+	// 生成TOKEN返回给客户端
+	l.session = l.buildAuthSession(user.ID, l.ip, l.auth.tokenLifetime())
+	err = sessionModel.New(tx).Add(l.session)
+	if err != nil {
+		return nil, err
+	}
+	// 超出session数量上限时，在同一事务中删除最早的session
+	if limit := conf.GetConf().GetLogin().MaxSessions; limit > 0 {
+		l.evicted, err = sessionModel.DeleteOldestSessions(tx, user.ID, limit)
 		if err != nil {
 			return nil, err
 		}
 	}
+	err = loginaudit.AddLoginAudit(tx, l.buildAudit(true))
+	if err != nil {
+		return nil, err
+	}
+
+	// namespace
+	ns := user.Namespace()
+	return &UserLoginResult{
+		Token:         l.session.Token,
+		NamespacePath: ns.Path,
+		Name:          user.Name,
+		Email:         user.Email,
+		PublicEmail:   user.PublicEmail,
+	}, nil
+}
+
+func (r *LoginService) prepare(src sqlx.Ext) (user *userModel.User, err error) {
+	if err = r.auth.Validate(); err != nil {
+		return nil, err
+	}
+	login := strings.TrimSpace(r.auth.Login())
+
+	user, err = userModel.GetUserByEmailOrUsername(src, login)
+	if err != nil {
+		return nil, err
+	}
 
 	if user == nil {
+		pwd.DummyCompare(r.auth.Password)
 		return nil, errors.NotFoundError(errors.User)
 	}
+	r.user = user
 	if !user.Verified() {
 		return nil, errors.AccessDenied(errors.User, errors.NotActivated)
 	}
+	if user.Locked(clk.Now().Unix()) {
+		return nil, errors.AccessDenied(errors.User, errors.Locked)
+	}
 
 	ok := pwd.ComparePassword(user.EncryptedPassword, r.auth.Password)
 	if !ok {
+		// 记录失败次数（不在登录事务中，登录失败也需要保存）
+		policy := conf.GetConf().GetLogin()
+		now := clk.Now()
+		lockedUntil := now.Add(time.Duration(policy.LockSeconds) * time.Second).Unix()
+		windowStart := now.Add(-policy.FailedWindow()).Unix()
+		err = userModel.UpdateLoginFailed(src, user.ID, policy.MaxFailedAttempts, now.Unix(), windowStart, lockedUntil)
+		if err != nil {
+			return nil, err
+		}
 		return nil, errors.InvalidParameterError(errors.User, errors.Password, errors.NotEqual)
 	}
+	// 密码正确后再提示停用，避免向不知道密码的人暴露账号状态
+	if user.Suspended() {
+		return nil, errors.AccessDenied(errors.User, errors.Suspended)
+	}
 	return user, nil
 }
 
-func (r *LoginService) buildAuthSession(userID int64, clientIP string) *sessionModel.Session {
-	return &sessionModel.Session{
+// rehashPassword 已保存的密码算法与当前配置不同、或 cost 低于当前配置时，使用本次登录的明文密码重新生成
+func (r *LoginService) rehashPassword(tx sqlx.Execer, user *userModel.User) error {
+	if !pwd.NeedsRehashForConfig(user.EncryptedPassword) {
+		return nil
+	}
+	encrypted, err := pwd.HashPassword(r.auth.Password)
+	if err != nil {
+		// 不影响本次登录，下次登录时再尝试
+		logger.Error("rehash password for user %d: %v", user.ID, err)
+		return nil
+	}
+	return userModel.RehashPassword(tx, user.ID, encrypted)
+}
+
+func (r *LoginService) logFields() logger.Fields {
+	fields := logger.Fields{"client_ip": r.ip}
+	if r.user != nil {
+		fields[logger.FieldUserID] = r.user.ID
+	}
+	return fields
+}
+
+// auditFailure 记录一次失败的登录（不在登录事务中，失败的记录也需要保存）
+func (r *LoginService) auditFailure() {
+	err := loginaudit.AddLoginAudit(db.DB, r.buildAudit(false))
+	if err != nil {
+		logger.Error("add login audit: %v", err)
+	}
+}
+
+func (r *LoginService) buildAudit(success bool) *loginaudit.LoginAudit {
+	a := &loginaudit.LoginAudit{
+		Identifier: truncate(strings.TrimSpace(r.auth.Login()), 255),
+		ClientIP:   r.ip,
+		UserAgent:  truncate(r.userAgent, 255),
+		Success:    success,
+	}
+	if r.user != nil {
+		a.UserID = r.user.ID
+	}
+	return a
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	// 避免截断多字节字符
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
+
+func (r *LoginService) buildAuthSession(userID int64, clientIP string, lifetime time.Duration) *sessionModel.Session {
+	now := clk.Now()
+	sess := &sessionModel.Session{
 		OwnerID:   userID,
 		Token:     uuid.UUID(),
 		ClientIP:  clientIP,
-		CreatedAt: time.Now().Unix(),
-		ExpiredAt: time.Now().Add(TokenExpiredTime).Unix(),
+		CreatedAt: now.Unix(),
+		ExpiredAt: now.Add(lifetime).Unix(),
+		BindIP:    r.auth.BindIP,
+	}
+	if ua := truncate(r.userAgent, 255); len(ua) > 0 {
+		sess.UserAgent = &ua
 	}
+	return sess
 }
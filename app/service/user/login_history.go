@@ -0,0 +1,50 @@
+package user
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	"github.com/growerlab/backend/app/model/loginaudit"
+	"github.com/growerlab/backend/app/service/common/session"
+)
+
+const (
+	LoginHistoryDefaultLimit = 20
+	LoginHistoryMaxLimit     = 100
+)
+
+type LoginHistoryItem struct {
+	ClientIP  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	Success   bool   `json:"success"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// ListLoginHistory 当前用户最近的登录记录（账号安全页面）
+func ListLoginHistory(ctx *gin.Context, limit uint64) ([]*LoginHistoryItem, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	if limit == 0 {
+		limit = LoginHistoryDefaultLimit
+	}
+	if limit > LoginHistoryMaxLimit {
+		limit = LoginHistoryMaxLimit
+	}
+
+	audits, err := loginaudit.ListLoginAuditsByUser(db.DB, sess.User().ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*LoginHistoryItem, 0, len(audits))
+	for _, a := range audits {
+		result = append(result, &LoginHistoryItem{
+			ClientIP:  a.ClientIP,
+			UserAgent: a.UserAgent,
+			Success:   a.Success,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+	return result, nil
+}
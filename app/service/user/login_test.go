@@ -0,0 +1,39 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginBasicAuthValidate(t *testing.T) {
+	assert.Nil(t, (&LoginBasicAuth{Identifier: "moli", Password: "password1"}).Validate())
+	assert.Nil(t, (&LoginBasicAuth{Email: "moli@example.com", Password: "password1"}).Validate())
+
+	assert.Equal(t,
+		errors.P(errors.User, errors.Identifier, errors.Empty).Error(),
+		(&LoginBasicAuth{Identifier: "  ", Password: "password1"}).Validate().Error())
+	assert.Equal(t,
+		errors.P(errors.User, errors.Password, errors.Empty).Error(),
+		(&LoginBasicAuth{Identifier: "moli"}).Validate().Error())
+	assert.Equal(t,
+		errors.P(errors.User, errors.Password, errors.InvalidLength).Error(),
+		(&LoginBasicAuth{Identifier: "moli", Password: "short"}).Validate().Error())
+}
+
+func TestClientLoginError(t *testing.T) {
+	generic := errors.AccessDenied(errors.User, errors.InvalidCredentials).Error()
+	notActivated := errors.AccessDenied(errors.User, errors.NotActivated)
+	badPassword := errors.InvalidParameterError(errors.User, errors.Password, errors.NotEqual)
+	locked := errors.AccessDenied(errors.User, errors.Locked)
+
+	// 默认返回具体原因
+	assert.Equal(t, notActivated, clientLoginError(notActivated, false))
+	assert.Equal(t, badPassword, clientLoginError(badPassword, false))
+
+	assert.Equal(t, generic, clientLoginError(notActivated, true).Error())
+	assert.Equal(t, generic, clientLoginError(badPassword, true).Error())
+	assert.Equal(t, generic, clientLoginError(errors.NotFoundError(errors.User), true).Error())
+	assert.Equal(t, locked, clientLoginError(locked, true))
+}
@@ -0,0 +1,82 @@
+package user
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/jmoiron/sqlx"
+)
+
+// Logout 用户退出登录
+// 删除当前token对应的session（包括已过期的），并清除cookie
+func Logout(ctx *gin.Context) error {
+	token := session.GetUserToken(ctx)
+	if len(token) == 0 {
+		return errors.NotFoundError(errors.Session)
+	}
+
+	err := db.Transact(func(tx sqlx.Ext) error {
+		// 已过期的session同样删除（并清除cookie），只有token不存在时返回NotFound
+		sess, err := sessionModel.GetSessionByToken(tx, token)
+		if err != nil {
+			return err
+		}
+		if sess == nil {
+			return errors.NotFoundError(errors.Session)
+		}
+
+		err = sessionModel.DeleteSessionByToken(tx, sess.Token)
+		if err != nil {
+			return err
+		}
+
+		clearCookie(ctx)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	userModel.InvalidateToken(token)
+	return nil
+}
+
+type LogoutAllResult struct {
+	Count int64 `json:"count"` // 被注销的session数量（含当前session）
+}
+
+// LogoutAll 注销当前用户的所有session（含当前请求的session）
+func LogoutAll(ctx *gin.Context) (result *LogoutAllResult, err error) {
+	token := session.GetUserToken(ctx)
+	if len(token) == 0 {
+		return nil, errors.Unauthorize()
+	}
+
+	var userID int64
+	err = db.Transact(func(tx sqlx.Ext) error {
+		user, err := userModel.GetUserByUserToken(tx, token)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return errors.Unauthorize()
+		}
+
+		count, err := sessionModel.DeleteSessionsByOwner(tx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		clearCookie(ctx)
+		result = &LogoutAllResult{Count: count}
+		userID = user.ID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	userModel.InvalidateUserTokens(userID)
+	return result, nil
+}
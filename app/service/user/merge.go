@@ -0,0 +1,136 @@
+package user
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	repoModel "github.com/growerlab/backend/app/model/repository"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// DuplicateAccountsInfo 归一化邮箱相同的一组用户
+type DuplicateAccountsInfo struct {
+	NormalizedEmail string           `json:"normalized_email"`
+	Users           []*AdminUserInfo `json:"users"`
+}
+
+type MergeAccountsPayload struct {
+	KeepUserID  int64 `json:"keep_user_id"`  // 保留的账号
+	MergeUserID int64 `json:"merge_user_id"` // 被合并（并软删除）的账号
+}
+
+type MergeAccountsResult struct {
+	KeepUserID  int64                   `json:"keep_user_id"`
+	MergeUserID int64                   `json:"merge_user_id"`
+	Merged      []*userModel.MergedRows `json:"merged"`
+}
+
+// AdminFindDuplicateAccounts 管理员查看归一化邮箱相同的账号（例如邮箱归一化之前通过 +tag 重复注册）
+func AdminFindDuplicateAccounts(ctx *gin.Context) ([]*DuplicateAccountsInfo, error) {
+	if _, err := currentAdmin(ctx, userModel.RoleModerator, userModel.RoleSupport); err != nil {
+		return nil, err
+	}
+	groups, err := userModel.FindDuplicateAccounts(db.Replica())
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]*userModel.User, 0)
+	for _, g := range groups {
+		all = append(all, g.Users...)
+	}
+	if err = userModel.WithNamespaces(db.Replica(), all); err != nil {
+		return nil, err
+	}
+
+	result := make([]*DuplicateAccountsInfo, 0, len(groups))
+	for _, g := range groups {
+		info := &DuplicateAccountsInfo{
+			NormalizedEmail: g.NormalizedEmail,
+			Users:           make([]*AdminUserInfo, 0, len(g.Users)),
+		}
+		for _, u := range g.Users {
+			info.Users = append(info.Users, toAdminUserInfo(u))
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// MergeAccounts 管理员（super_admin）将 mergeID 合并到 keepID（两个账号的归一化邮箱必须相同）
+// 1. session、SSH key、token、邮箱、组织、仓库转移到 keepID，mergeID 被软删除
+// 2. 两个账号都拥有仓库时拒绝（仓库路径可能冲突），需要先由用户处理其中一方的仓库
+// 3. 在同一个事务中完成，日志中只记录用户id
+func MergeAccounts(ctx *gin.Context, keepID, mergeID int64) (result *MergeAccountsResult, err error) {
+	if _, err = currentAdmin(ctx, userModel.RoleSuperAdmin); err != nil {
+		return nil, err
+	}
+	if keepID == mergeID {
+		return nil, errors.P(errors.User, errors.ID, errors.Invalid)
+	}
+
+	err = db.Transact(func(tx sqlx.Ext) error {
+		keep, err := userModel.GetUser(tx, keepID)
+		if err != nil {
+			return err
+		}
+		merge, err := userModel.GetUser(tx, mergeID)
+		if err != nil {
+			return err
+		}
+		if keep == nil || merge == nil {
+			return errors.NotFoundError(errors.User)
+		}
+		if err = checkMergeable(tx, keep, merge); err != nil {
+			return err
+		}
+
+		merged, err := userModel.MergeAccounts(tx, keep.ID, merge.ID)
+		if err != nil {
+			return err
+		}
+		result = &MergeAccountsResult{
+			KeepUserID:  keep.ID,
+			MergeUserID: merge.ID,
+			Merged:      merged,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// session 已转移到 keepID，两个用户的token缓存都需要失效
+	userModel.InvalidateUserTokens(keepID)
+	userModel.InvalidateUserTokens(mergeID)
+	logger.Ctx(ctx).
+		With("keep_user_id", keepID).
+		With("merge_user_id", mergeID).
+		Warn("user accounts merged")
+	return result, nil
+}
+
+// checkMergeable 只合并归一化邮箱相同的普通用户，且最多一方拥有仓库
+func checkMergeable(tx sqlx.Queryer, keep, merge *userModel.User) error {
+	if keep.IsStaff() || merge.IsStaff() {
+		return errors.AccessDenied(errors.User, errors.NoPermission)
+	}
+	if len(keep.NormalizedEmail) == 0 || keep.NormalizedEmail != merge.NormalizedEmail {
+		return errors.P(errors.User, errors.Email, errors.NotEqual)
+	}
+
+	keepHasRepos, err := repoModel.ExistsRepositoriesOfUser(tx, keep.ID, keep.NamespaceID)
+	if err != nil {
+		return err
+	}
+	mergeHasRepos, err := repoModel.ExistsRepositoriesOfUser(tx, merge.ID, merge.NamespaceID)
+	if err != nil {
+		return err
+	}
+	if keepHasRepos && mergeHasRepos {
+		return errors.ConflictError(errors.Repository)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/growerlab/backend/app/common/errors"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckMergeable(t *testing.T) {
+	keep := &userModel.User{ID: 1, NormalizedEmail: "a@gmail.com"}
+
+	// 以下情况在查询仓库之前返回
+	staff := &userModel.User{ID: 2, NormalizedEmail: "a@gmail.com", Roles: userModel.RoleSupport}
+	err := checkMergeable(nil, keep, staff)
+	assert.Equal(t, errors.AccessDenied(errors.User, errors.NoPermission).Error(), err.Error())
+
+	other := &userModel.User{ID: 3, NormalizedEmail: "b@gmail.com"}
+	err = checkMergeable(nil, keep, other)
+	assert.Equal(t, errors.P(errors.User, errors.Email, errors.NotEqual).Error(), err.Error())
+
+	// 归一化之前注册的用户没有 normalized_email，不能合并
+	err = checkMergeable(nil, &userModel.User{ID: 4}, &userModel.User{ID: 5})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,91 @@
+package user
+
+import (
+	"time"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	"github.com/growerlab/backend/app/utils/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 登录失败的原因（login_failure_total 的 reason 标签）
+const (
+	FailureNotFound    = "not_found"
+	FailureNotVerified = "not_verified"
+	FailureBadPassword = "bad_password"
+	FailureLocked      = "locked"
+	FailureOther       = "other"
+)
+
+var (
+	loginSuccess = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "growerlab_login_success_total",
+		Help: "Number of successful logins.",
+	})
+	loginFailure = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "growerlab_login_failure_total",
+		Help: "Number of failed logins by reason.",
+	}, []string{"reason"})
+	loginLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "growerlab_login_duration_seconds",
+		Help:    "Login latency in seconds.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+func init() {
+	prometheus.MustRegister(&activeSessionsCollector{
+		desc: prometheus.NewDesc("growerlab_active_sessions", "Number of unexpired sessions.", nil, nil),
+		count: func() (int64, error) {
+			if db.DB == nil {
+				return 0, errors.New("database is not initialized")
+			}
+			return sessionModel.CountActiveSessions(db.DB, time.Now().Unix())
+		},
+	})
+}
+
+// activeSessionsCollector 每次抓取时查询数据库
+// 查询失败时不输出该指标（而不是输出0），避免误报session数量骤降
+type activeSessionsCollector struct {
+	desc  *prometheus.Desc
+	count func() (int64, error)
+}
+
+func (c *activeSessionsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *activeSessionsCollector) Collect(ch chan<- prometheus.Metric) {
+	count, err := c.count()
+	if err != nil {
+		logger.Warn("collect active sessions: %v", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count))
+}
+
+// loginFailureReasons 与 LoginService.prepare 返回的错误对应
+var loginFailureReasons = map[string]string{
+	message(errors.NotFoundError(errors.User)):                                           FailureNotFound,
+	message(errors.AccessDenied(errors.User, errors.NotActivated)):                       FailureNotVerified,
+	message(errors.InvalidParameterError(errors.User, errors.Password, errors.NotEqual)): FailureBadPassword,
+	message(errors.AccessDenied(errors.User, errors.Locked)):                             FailureLocked,
+}
+
+func loginFailureReason(err error) string {
+	if reason, ok := loginFailureReasons[message(err)]; ok {
+		return reason
+	}
+	return FailureOther
+}
+
+func message(err error) string {
+	if e, ok := errors.Cause(err).(*errors.Result); ok {
+		return e.Message
+	}
+	return ""
+}
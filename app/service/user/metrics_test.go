@@ -0,0 +1,43 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginFailureReason(t *testing.T) {
+	assert.Equal(t, FailureNotFound, loginFailureReason(errors.NotFoundError(errors.User)))
+	assert.Equal(t, FailureNotVerified, loginFailureReason(errors.AccessDenied(errors.User, errors.NotActivated)))
+	assert.Equal(t, FailureBadPassword, loginFailureReason(errors.P(errors.User, errors.Password, errors.NotEqual)))
+	assert.Equal(t, FailureLocked, loginFailureReason(errors.AccessDenied(errors.User, errors.Locked)))
+	assert.Equal(t, FailureOther, loginFailureReason(errors.AccessDenied(errors.User, errors.Suspended)))
+	assert.Equal(t, FailureOther, loginFailureReason(errors.New("unknown")))
+}
+
+func TestActiveSessionsCollector(t *testing.T) {
+	desc := prometheus.NewDesc("active_sessions", "h", nil, nil)
+	collect := func(c *activeSessionsCollector) []prometheus.Metric {
+		ch := make(chan prometheus.Metric, 1)
+		c.Collect(ch)
+		close(ch)
+		var result []prometheus.Metric
+		for m := range ch {
+			result = append(result, m)
+		}
+		return result
+	}
+
+	got := collect(&activeSessionsCollector{desc: desc, count: func() (int64, error) { return 3, nil }})
+	assert.Len(t, got, 1)
+	var m dto.Metric
+	assert.Nil(t, got[0].Write(&m))
+	assert.Equal(t, 3.0, m.GetGauge().GetValue())
+
+	// 查询失败时不输出
+	got = collect(&activeSessionsCollector{desc: desc, count: func() (int64, error) { return 0, errors.New("db") }})
+	assert.Empty(t, got)
+}
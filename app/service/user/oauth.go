@@ -0,0 +1,585 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/common/events"
+	"github.com/growerlab/backend/app/common/validate"
+	"github.com/growerlab/backend/app/model/db"
+	"github.com/growerlab/backend/app/model/oauthidentity"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	nsSvc "github.com/growerlab/backend/app/service/namespace"
+	"github.com/growerlab/backend/app/utils/cache"
+	"github.com/growerlab/backend/app/utils/clientip"
+	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/growerlab/backend/app/utils/logger"
+	"github.com/growerlab/backend/app/utils/oauth"
+	"github.com/growerlab/backend/app/utils/pwd"
+	"github.com/growerlab/backend/app/utils/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// oauthStateCookie 发起授权时下发的state，回调时与提交的state比较，防止CSRF（登录到攻击者的账号、绑定攻击者的第三方账号）
+	oauthStateCookie = "oauth-state"
+	oauthStateTTL    = 10 * time.Minute
+	// oauthTicketTTL 需要两步验证时，第三方登录的结果保留的时间
+	oauthTicketTTL = 5 * time.Minute
+)
+
+// oauthProviders 已启用的第三方登录，启动时由 InitConfig 根据配置 oauth 创建
+var oauthProviders = map[string]oauth.Provider{}
+
+// pendingOAuthLogins 需要两步验证的第三方登录：ticket => *oauth.Identity
+// 授权码只能使用一次，所以客户端提交验证码时使用 ticket 代替 code
+var pendingOAuthLogins = cache.NewLRU(10000, oauthTicketTTL)
+
+func newOAuthProviders(configs map[string]*conf.OAuthProvider) map[string]oauth.Provider {
+	providers := make(map[string]oauth.Provider)
+	for name, c := range configs {
+		if !c.Enabled() {
+			continue
+		}
+		switch name {
+		case oauth.ProviderGitHub:
+			providers[name] = oauth.NewGitHub(c.ClientID, c.ClientSecret, c.RedirectURL)
+		case oauth.ProviderGoogle:
+			providers[name] = oauth.NewGoogle(c.ClientID, c.ClientSecret, c.RedirectURL)
+		}
+	}
+	return providers
+}
+
+func getOAuthProvider(name string) (oauth.Provider, error) {
+	p, ok := oauthProviders[name]
+	if !ok {
+		return nil, errors.P(errors.OAuthIdentity, errors.Provider, errors.Invalid)
+	}
+	return p, nil
+}
+
+type OAuthAuthorizeResult struct {
+	URL string `json:"url"` // 客户端跳转到该地址进行授权
+}
+
+// OAuthCallbackPayload 第三方回调到前端后，前端提交的 code、state
+type OAuthCallbackPayload struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+	// Ticket、TOTPCode 登录需要两步验证时，提交上一次返回的 oauth_ticket 和验证码（此时不需要 code、state）
+	Ticket     string `json:"ticket"`
+	TOTPCode   string `json:"totp_code"`
+	RememberMe *bool  `json:"remember_me"`
+	BindIP     bool   `json:"bind_ip"`
+}
+
+// loginAuth 复用密码登录的session有效期、IP绑定、登录日志等逻辑（不包含密码）
+func (p *OAuthCallbackPayload) loginAuth(identity *oauth.Identity) *LoginBasicAuth {
+	login := identity.Login
+	if len(login) == 0 {
+		login = identity.Email
+	}
+	return &LoginBasicAuth{
+		Identifier: identity.Provider + ":" + login,
+		TOTPCode:   p.TOTPCode,
+		RememberMe: p.RememberMe,
+		BindIP:     p.BindIP,
+	}
+}
+
+type OAuthIdentityInfo struct {
+	Provider    string `json:"provider"`
+	Login       string `json:"login"`
+	Email       string `json:"email"`
+	CreatedAt   int64  `json:"created_at"`
+	LastLoginAt *int64 `json:"last_login_at"`
+}
+
+type OAuthIdentitiesResult struct {
+	HasPassword bool                 `json:"has_password"` // 为false时，至少需要保留一个第三方账号
+	Identities  []*OAuthIdentityInfo `json:"identities"`
+}
+
+func toOAuthIdentityInfo(i *oauthidentity.Identity) *OAuthIdentityInfo {
+	return &OAuthIdentityInfo{
+		Provider:    i.Provider,
+		Login:       i.Login,
+		Email:       i.Email,
+		CreatedAt:   i.CreatedAt,
+		LastLoginAt: i.LastLoginAt,
+	}
+}
+
+// OAuthAuthorize 开始第三方登录（或绑定）：生成state并写入cookie，返回授权地址
+func OAuthAuthorize(ctx *gin.Context, providerName string) (*OAuthAuthorizeResult, error) {
+	provider, err := getOAuthProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	state, err := oauth.GenerateState()
+	if err != nil {
+		return nil, err
+	}
+	setOAuthStateCookie(ctx, provider.Name()+":"+state, int(oauthStateTTL.Seconds()))
+	return &OAuthAuthorizeResult{URL: provider.AuthCodeURL(state)}, nil
+}
+
+func setOAuthStateCookie(ctx *gin.Context, value string, maxAge int) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     "/",
+		Domain:   conf.GetConf().GetCookie().Domain,
+		Secure:   secureCookie(ctx),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// checkOAuthState state必须与发起授权时写入cookie的值相同，校验后删除cookie（每个state只能使用一次）
+func checkOAuthState(ctx *gin.Context, providerName, state string) error {
+	expected, _ := ctx.Cookie(oauthStateCookie)
+	setOAuthStateCookie(ctx, "", -1)
+	if len(state) == 0 || len(expected) == 0 ||
+		subtle.ConstantTimeCompare([]byte(expected), []byte(providerName+":"+state)) != 1 {
+		return errors.P(errors.OAuthIdentity, errors.State, errors.NotEqual)
+	}
+	return nil
+}
+
+// exchangeOAuthCode 校验state，并使用code获取第三方的用户信息
+func exchangeOAuthCode(ctx *gin.Context, providerName string, payload *OAuthCallbackPayload) (*oauth.Identity, error) {
+	provider, err := getOAuthProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkOAuthState(ctx, providerName, payload.State); err != nil {
+		return nil, err
+	}
+	if len(payload.Code) == 0 {
+		return nil, errors.P(errors.OAuthIdentity, errors.Code, errors.Empty)
+	}
+	identity, err := provider.Exchange(ctx.Request.Context(), payload.Code)
+	if err != nil {
+		if _, ok := err.(*oauth.Error); ok {
+			return nil, errors.P(errors.OAuthIdentity, errors.Code, errors.Invalid)
+		}
+		return nil, err
+	}
+	if len(identity.ID) == 0 {
+		return nil, errors.Errorf("%s returned an empty user id", providerName)
+	}
+	return identity, nil
+}
+
+// pendingOAuthIdentity 需要两步验证时保存的第三方登录结果
+func pendingOAuthIdentity(providerName, ticket string) (*oauth.Identity, error) {
+	v, ok := pendingOAuthLogins.Get(ticket)
+	if !ok {
+		return nil, errors.P(errors.OAuthIdentity, errors.Token, errors.Expired)
+	}
+	identity := v.(*oauth.Identity)
+	if identity.Provider != providerName {
+		return nil, errors.P(errors.OAuthIdentity, errors.Provider, errors.NotEqual)
+	}
+	return identity, nil
+}
+
+// HandleOAuthCallback 第三方登录的回调：使用code获取第三方账号，找到（或创建）对应的用户并登录
+//  1. 已绑定的第三方账号，登录到绑定的用户
+//  2. 第三方已验证的邮箱与已激活的用户相同时，绑定到该用户，而不是重复创建用户；与未激活的用户相同时拒绝（无法确认未激活账号的归属）
+//  3. 否则创建新用户（已激活，没有密码），用户名根据第三方的用户名生成
+//
+// 启用了两步验证的用户同样需要提交验证码；session的生成与密码登录相同（见 LoginService.issueSession）
+func HandleOAuthCallback(ctx *gin.Context, providerName string, payload *OAuthCallbackPayload) (
+	result *UserLoginResult,
+	err error,
+) {
+	limiter := getLoginLimiter()
+	ip := clientip.FromRequest(ctx.Request)
+	if !limiter.Allow(ip) {
+		return nil, errors.AccessDenied(errors.User, errors.RateLimited)
+	}
+
+	var identity *oauth.Identity
+	if len(payload.Ticket) > 0 {
+		identity, err = pendingOAuthIdentity(providerName, payload.Ticket)
+	} else {
+		identity, err = exchangeOAuthCode(ctx, providerName, payload)
+	}
+	if err != nil {
+		limiter.Hit(ip, 1)
+		logger.Ctx(ctx).With("client_ip", ip).With("provider", providerName).With("reason", err.Error()).Warn("oauth login failed")
+		return nil, err
+	}
+
+	loginService := NewLoginService(ip, payload.loginAuth(identity))
+	loginService.userAgent = ctx.Request.UserAgent()
+	result, err = loginService.doOAuth(ctx.Request.Context(), identity)
+	if err != nil {
+		limiter.Hit(ip, 1)
+		loginService.auditFailure()
+		reason := loginFailureReason(err)
+		loginFailure.WithLabelValues(reason).Inc()
+		logger.Ctx(ctx).WithFields(loginService.logFields()).With("reason", reason).Warn("oauth login failed")
+		return nil, err
+	}
+	if result.TOTPRequired {
+		return
+	}
+	if len(payload.Ticket) > 0 {
+		pendingOAuthLogins.Delete(payload.Ticket)
+	}
+	loginService.succeeded(ctx)
+	return
+}
+
+// doOAuth 第三方账号对应的用户，见 HandleOAuthCallback
+func (l *LoginService) doOAuth(ctx context.Context, identity *oauth.Identity) (*UserLoginResult, error) {
+	linked, err := oauthidentity.GetIdentity(db.DB, identity.Provider, identity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *userModel.User
+	switch {
+	case linked != nil:
+		user, err = userModel.GetUser(db.DB, linked.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, errors.NotFoundError(errors.User)
+		}
+	case identity.EmailVerified && len(identity.Email) > 0:
+		user, err = userModel.GetUserByEmail(db.DB, identity.Email)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil && !user.Verified() {
+			return nil, errors.P(errors.User, errors.Email, errors.AlreadyExists)
+		}
+	}
+
+	created := user == nil
+	if created {
+		user, err = newOAuthUser(identity, l.ip)
+		if err != nil {
+			return nil, err
+		}
+	} else if err = checkOAuthLogin(user); err != nil {
+		l.user = user
+		return nil, err
+	}
+	l.user = user
+
+	// 已启用两步验证，但没有提交验证码时，保存第三方登录的结果，客户端带上 ticket 和验证码重新提交
+	if user.TOTPEnabled() && len(l.auth.TOTPCode) == 0 {
+		ticket := uuid.UUID()
+		pendingOAuthLogins.Set(ticket, identity)
+		return &UserLoginResult{TOTPRequired: true, OAuthTicket: ticket}, nil
+	}
+
+	var result *UserLoginResult
+	err = db.Transact(func(tx sqlx.Ext) error {
+		if created {
+			err = userModel.CreateUserWithNamespaceContext(ctx, tx, user)
+			if err != nil {
+				return err
+			}
+		} else if user.TOTPEnabled() {
+			err = verifySecondFactor(tx, user, l.auth.TOTPCode)
+			if err != nil {
+				return err
+			}
+		}
+
+		if linked != nil {
+			err = oauthidentity.UpdateLastLogin(tx, linked.ID, identity.Login, identity.Email)
+		} else {
+			err = linkOAuthIdentity(tx, user.ID, identity, true)
+		}
+		if err != nil {
+			return err
+		}
+		result, err = l.issueSession(tx, user)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		publishUserEvent(events.UserRegistered, user)
+	}
+	return result, nil
+}
+
+// checkOAuthLogin 已有用户通过第三方账号登录时的状态检查（与密码登录一致）
+func checkOAuthLogin(user *userModel.User) error {
+	if !user.Verified() {
+		return errors.AccessDenied(errors.User, errors.NotActivated)
+	}
+	if user.Locked(clk.Now().Unix()) {
+		return errors.AccessDenied(errors.User, errors.Locked)
+	}
+	if user.Suspended() {
+		return errors.AccessDenied(errors.User, errors.Suspended)
+	}
+	return nil
+}
+
+// linkOAuthIdentity 将第三方账号绑定到用户，用户已绑定了同一个第三方的其他账号时返回 AlreadyExists
+func linkOAuthIdentity(tx sqlx.Ext, userID int64, identity *oauth.Identity, login bool) error {
+	existing, err := oauthidentity.GetIdentityByUser(tx, userID, identity.Provider)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.AlreadyExistsError(errors.OAuthIdentity, errors.AlreadyExists)
+	}
+	i := &oauthidentity.Identity{
+		UserID:         userID,
+		Provider:       identity.Provider,
+		ProviderUserID: identity.ID,
+		Login:          truncate(identity.Login, 255),
+		Email:          truncate(identity.Email, 255),
+	}
+	if login {
+		now := clk.Now().Unix()
+		i.LastLoginAt = &now
+	}
+	return oauthidentity.AddIdentity(tx, i)
+}
+
+// newOAuthUser 通过第三方账号注册的用户：使用第三方已验证的邮箱（因此直接激活），没有密码（之后可以通过 SetPassword 设置）
+func newOAuthUser(identity *oauth.Identity, clientIP string) (*userModel.User, error) {
+	if !identity.EmailVerified || len(identity.Email) == 0 {
+		return nil, errors.P(errors.OAuthIdentity, errors.Email, errors.Empty)
+	}
+	if err := validate.ValidateEmail(identity.Email); err != nil {
+		return nil, err
+	}
+	// 被已注销的账号占用，需要联系管理员处理
+	exists, err := userModel.ExistsEmailIncludingDeleted(db.DB, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.P(errors.User, errors.Email, errors.Deleted)
+	}
+
+	username, err := availableOAuthUsername(oauthUsernameBase(identity))
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(identity.Name)
+	if len(name) == 0 {
+		name = username
+	}
+	now := clk.Now().Unix()
+	return &userModel.User{
+		Email:             identity.Email,
+		EncryptedPassword: "",
+		Username:          username,
+		Name:              truncate(name, 255),
+		CreatedAt:         now,
+		VerifiedAt:        &now,
+		RegisterIP:        clientIP,
+	}, nil
+}
+
+var oauthUsernameInvalidChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// oauthUsernameSuffixLen 用户名已被占用时添加的后缀（-0000）的长度
+const oauthUsernameSuffixLen = 5
+
+// oauthUsernameBase 将第三方的用户名（没有时使用邮箱@之前的部分）转换为符合用户名规则的字符，不检查长度和是否已被占用
+func oauthUsernameBase(identity *oauth.Identity) string {
+	base := identity.Login
+	if len(base) == 0 {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	base = oauthUsernameInvalidChars.ReplaceAllString(strings.ToLower(base), "-")
+	// 必须以字母开头
+	base = strings.TrimLeftFunc(base, func(r rune) bool { return r < 'a' || r > 'z' })
+	if max := validate.Username.MaxLen - oauthUsernameSuffixLen; len(base) > max {
+		base = base[:max]
+	}
+	return strings.TrimRight(base, "-_")
+}
+
+// availableOAuthUsername base 已被占用（或太短、为保留字）时，尝试添加随机的数字后缀
+func availableOAuthUsername(base string) (string, error) {
+	if len(base) == 0 {
+		base = "user"
+	}
+	candidates := []string{base}
+	for i := 0; i < 5; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		candidates = append(candidates, fmt.Sprintf("%s-%04d", base, n.Int64()))
+	}
+	for _, name := range candidates {
+		if validateUsername(name) != nil {
+			continue
+		}
+		reason, err := nsSvc.CheckTaken(db.DB, name)
+		if err != nil {
+			return "", err
+		}
+		if len(reason) == 0 {
+			return name, nil
+		}
+	}
+	return "", errors.P(errors.User, errors.Username, errors.AlreadyExists)
+}
+
+// LinkOAuthIdentity 已登录的用户绑定第三方账号（先通过 OAuthAuthorize 授权），绑定后可以使用该第三方账号登录
+func LinkOAuthIdentity(ctx *gin.Context, providerName string, payload *OAuthCallbackPayload) (*OAuthIdentityInfo, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	user := sess.User()
+	identity, err := exchangeOAuthCode(ctx, providerName, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var info *OAuthIdentityInfo
+	err = db.Transact(func(tx sqlx.Ext) error {
+		linked, err := oauthidentity.GetIdentity(tx, identity.Provider, identity.ID)
+		if err != nil {
+			return err
+		}
+		// 已绑定到其他用户（包括当前用户）
+		if linked != nil {
+			return errors.AlreadyExistsError(errors.OAuthIdentity, errors.AlreadyExists)
+		}
+		err = linkOAuthIdentity(tx, user.ID, identity, false)
+		if err != nil {
+			return err
+		}
+		linked, err = oauthidentity.GetIdentityByUser(tx, user.ID, identity.Provider)
+		if err != nil {
+			return err
+		}
+		info = toOAuthIdentityInfo(linked)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Ctx(ctx).With(logger.FieldUserID, user.ID).With("provider", identity.Provider).Info("oauth identity linked")
+	return info, nil
+}
+
+// canUnlinkOAuth 解绑后用户仍然至少有一种登录方式：已设置密码，或者还绑定了其他第三方账号
+func canUnlinkOAuth(user *userModel.User, identities int64) bool {
+	return user.HasPassword() || identities > 1
+}
+
+// UnlinkOAuthIdentity 解绑第三方账号，没有密码时不能解绑最后一个第三方账号（需要先通过 SetPassword 设置密码）
+func UnlinkOAuthIdentity(ctx *gin.Context, providerName string) error {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return errors.Unauthorize()
+	}
+	user := sess.User()
+	err := db.Transact(func(tx sqlx.Ext) error {
+		linked, err := oauthidentity.GetIdentityByUser(tx, user.ID, providerName)
+		if err != nil {
+			return err
+		}
+		if linked == nil {
+			return errors.NotFoundError(errors.OAuthIdentity)
+		}
+		// 使用数据库中的用户，session中缓存的用户可能还没有密码
+		current, err := userModel.GetUser(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return errors.NotFoundError(errors.User)
+		}
+		count, err := oauthidentity.CountIdentitiesByUser(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		if !canUnlinkOAuth(current, count) {
+			return errors.AccessDenied(errors.OAuthIdentity, errors.LastAuthMethod)
+		}
+		return oauthidentity.DeleteIdentity(tx, linked.ID)
+	})
+	if err != nil {
+		return err
+	}
+	logger.Ctx(ctx).With(logger.FieldUserID, user.ID).With("provider", providerName).Info("oauth identity unlinked")
+	return nil
+}
+
+// ListOAuthIdentities 当前用户绑定的第三方账号
+func ListOAuthIdentities(ctx *gin.Context) (*OAuthIdentitiesResult, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	user := sess.User()
+	identities, err := oauthidentity.ListIdentitiesByUser(db.DB, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := &OAuthIdentitiesResult{
+		HasPassword: user.HasPassword(),
+		Identities:  make([]*OAuthIdentityInfo, 0, len(identities)),
+	}
+	for _, i := range identities {
+		result.Identities = append(result.Identities, toOAuthIdentityInfo(i))
+	}
+	return result, nil
+}
+
+type SetPasswordPayload struct {
+	Password string `json:"password"`
+}
+
+// SetPassword 通过第三方账号注册（没有密码）的用户设置密码，之后可以使用密码登录、解绑第三方账号
+// 已设置了密码的用户需要使用 ChangePassword
+func SetPassword(ctx *gin.Context, password string) error {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return errors.Unauthorize()
+	}
+	user := sess.User()
+	if user.HasPassword() {
+		return errors.P(errors.User, errors.Password, errors.AlreadyExists)
+	}
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	encrypted, err := pwd.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	err = userModel.SetInitialPassword(db.DB, user.ID, encrypted)
+	if err != nil {
+		return err
+	}
+	// 缓存的用户中没有密码
+	userModel.InvalidateUserTokens(user.ID)
+	return nil
+}
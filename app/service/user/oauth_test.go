@@ -0,0 +1,46 @@
+package user
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/growerlab/backend/app/common/validate"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuthUsernameBase(t *testing.T) {
+	assert.Equal(t, "moli", oauthUsernameBase(&oauth.Identity{Login: "MoLi"}))
+	assert.Equal(t, "mo-li", oauthUsernameBase(&oauth.Identity{Login: "mo.li"}))
+	// google 没有用户名，使用邮箱@之前的部分
+	assert.Equal(t, "moli-dev", oauthUsernameBase(&oauth.Identity{Email: "moli+dev@gmail.com"}))
+	// 必须以字母开头
+	assert.Equal(t, "abc", oauthUsernameBase(&oauth.Identity{Login: "123-abc"}))
+	assert.Equal(t, "", oauthUsernameBase(&oauth.Identity{Login: "2024"}))
+
+	long := oauthUsernameBase(&oauth.Identity{Login: strings.Repeat("a", 100)})
+	assert.Len(t, long, validate.Username.MaxLen-oauthUsernameSuffixLen)
+}
+
+func TestCanUnlinkOAuth(t *testing.T) {
+	withPassword := &userModel.User{EncryptedPassword: "hash"}
+	withoutPassword := &userModel.User{}
+
+	assert.True(t, canUnlinkOAuth(withPassword, 1))
+	assert.True(t, canUnlinkOAuth(withoutPassword, 2))
+	// 没有密码时不能解绑最后一个第三方账号
+	assert.False(t, canUnlinkOAuth(withoutPassword, 1))
+}
+
+func TestOAuthLoginAuth(t *testing.T) {
+	payload := &OAuthCallbackPayload{TOTPCode: "123456", BindIP: true}
+
+	auth := payload.loginAuth(&oauth.Identity{Provider: oauth.ProviderGitHub, Login: "moli"})
+	assert.Equal(t, "github:moli", auth.Identifier)
+	assert.Equal(t, "123456", auth.TOTPCode)
+	assert.True(t, auth.BindIP)
+
+	auth = payload.loginAuth(&oauth.Identity{Provider: oauth.ProviderGoogle, Email: "moli@gmail.com"})
+	assert.Equal(t, "google:moli@gmail.com", auth.Identifier)
+}
@@ -0,0 +1,93 @@
+package user
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/growerlab/backend/app/utils/pwd"
+	"github.com/growerlab/backend/app/utils/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type ChangePasswordPayload struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePasswordResult 当前设备重新签发的token，客户端需要使用新的token替换旧的token
+type ChangePasswordResult struct {
+	Token string `json:"token"`
+}
+
+// ChangePassword 已登录用户修改密码
+// 修改成功后，用户的所有session都将被注销；当前设备会重新签发一个session（使用cookie时同时更新cookie），保持登录状态
+// 修改密码之前创建的session即使没有被删除也不能再认证（见 userModel.PasswordChangedCond）
+func ChangePassword(ctx *gin.Context, oldPassword, newPassword string) (*ChangePasswordResult, error) {
+	if err := ReauthWithPassword(ctx, oldPassword); err != nil {
+		return nil, err
+	}
+	sess := session.New(ctx)
+	user := sess.User()
+	if oldPassword == newPassword {
+		return nil, errors.P(errors.User, errors.Password, errors.Unchanged)
+	}
+	if err := validatePassword(newPassword); err != nil {
+		return nil, err
+	}
+
+	encrypted, err := pwd.HashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	var renewed *sessionModel.Session
+	err = db.Transact(func(tx sqlx.Ext) error {
+		current, err := sessionModel.GetSessionByToken(tx, sess.Token())
+		if err != nil {
+			return err
+		}
+		if current == nil || current.OwnerID != user.ID {
+			return errors.Unauthorize()
+		}
+		err = userModel.UpdatePassword(tx, user.ID, encrypted)
+		if err != nil {
+			return err
+		}
+		_, err = sessionModel.DeleteSessionsByOwner(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		renewed = renewSession(current, clk.Now())
+		return sessionModel.New(tx).Add(renewed)
+	})
+	if err != nil {
+		return nil, err
+	}
+	userModel.InvalidateUserTokens(user.ID)
+
+	// token来自cookie时，同时更新cookie
+	if cookieToken, _ := ctx.Cookie(session.CookieName()); cookieToken == sess.Token() {
+		maxAge := 0
+		if renewed.Lifetime() >= sessionConf.RememberTTL() {
+			maxAge = int(renewed.Lifetime().Seconds())
+		}
+		setTokenCookie(ctx, renewed.Token, maxAge)
+	}
+	return &ChangePasswordResult{Token: renewed.Token}, nil
+}
+
+// renewSession 修改密码后为当前设备重新签发的session：新的token、创建时间为 now，有效期、IP绑定等与旧session相同
+// 创建时间不早于 password_changed_at，所以新session仍然有效
+func renewSession(old *sessionModel.Session, now time.Time) *sessionModel.Session {
+	renewed := *old
+	renewed.ID = 0
+	renewed.Token = uuid.UUID()
+	renewed.CreatedAt = now.Unix()
+	renewed.ExpiredAt = now.Add(old.Lifetime()).Unix()
+	return &renewed
+}
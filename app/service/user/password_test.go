@@ -0,0 +1,38 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenewSession(t *testing.T) {
+	start := time.Unix(1000000, 0)
+	fake := clock.NewFake(start)
+	defer SetClock(fake)()
+
+	svc := &LoginService{auth: &LoginBasicAuth{BindIP: true}, userAgent: "curl/7.0"}
+	old := svc.buildAuthSession(1, "127.0.0.1", sessionConf.RememberTTL())
+	old.ID = 10
+
+	// 修改密码与重新签发在同一秒
+	fake.Add(time.Hour)
+	changedAt := fake.Now().Unix()
+	renewed := renewSession(old, fake.Now())
+
+	assert.Zero(t, renewed.ID)
+	assert.NotEqual(t, old.Token, renewed.Token)
+	assert.Equal(t, changedAt, renewed.CreatedAt)
+	assert.Equal(t, old.Lifetime(), renewed.Lifetime())
+	assert.Equal(t, old.BindIP, renewed.BindIP)
+	assert.Equal(t, old.ClientIP, renewed.ClientIP)
+	assert.Equal(t, old.Agent(), renewed.Agent())
+	assert.Equal(t, int64(10), old.ID, "old session is not modified")
+
+	user := &userModel.User{PasswordChangedAt: &changedAt}
+	assert.False(t, user.AcceptsSession(old.CreatedAt))
+	assert.True(t, user.AcceptsSession(renewed.CreatedAt))
+}
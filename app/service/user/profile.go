@@ -0,0 +1,121 @@
+package user
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/common/validate"
+	"github.com/growerlab/backend/app/model/db"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/jmoiron/sqlx"
+)
+
+// ProfileNameLenMax 昵称的最大长度（字符数）
+const ProfileNameLenMax = 255
+
+type UpdateProfilePayload struct {
+	Name        string `json:"name"`
+	PublicEmail string `json:"public_email"` // 为空时不公开邮箱
+}
+
+// ProfileResult 用户本人的资料，包含私有邮箱
+type ProfileResult struct {
+	Username    string `json:"username"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	PublicEmail string `json:"public_email"`
+}
+
+// GetPublicProfile 用户的公开信息（任何人可见）
+// 私有邮箱只通过登录结果等返回给用户本人，这里只返回 public_email
+func GetPublicProfile(username string) (*userModel.PublicProfile, error) {
+	u, err := userModel.GetUserByUsername(db.DB, username)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.NotFoundError(errors.User)
+	}
+	return u.ToPublicProfile(), nil
+}
+
+// UpdateProfile 修改当前用户的昵称和公开邮箱，返回修改后的资料
+func UpdateProfile(ctx *gin.Context, req *UpdateProfilePayload) (result *ProfileResult, err error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	user := sess.User()
+
+	name := strings.TrimSpace(req.Name)
+	if utf8.RuneCountInString(name) > ProfileNameLenMax {
+		return nil, errors.P(errors.User, errors.Name, errors.InvalidLength)
+	}
+	publicEmail := strings.TrimSpace(req.PublicEmail)
+	emails, err := userModel.ListUserEmails(db.DB, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err = validatePublicEmail(user, publicEmail, verifiedEmails(emails)...); err != nil {
+		return nil, err
+	}
+
+	err = db.Transact(func(tx sqlx.Ext) error {
+		err := userModel.UpdateProfile(tx, user.ID, name, publicEmail)
+		if err != nil {
+			return err
+		}
+		updated, err := userModel.GetUser(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		if updated == nil {
+			return errors.NotFoundError(errors.User)
+		}
+		result = &ProfileResult{
+			Username:    updated.Username,
+			Name:        updated.Name,
+			Email:       updated.Email,
+			PublicEmail: updated.PublicEmail,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	userModel.InvalidateUserTokens(user.ID)
+	return result, nil
+}
+
+// validatePublicEmail public_email 为空，或是用户已验证的主邮箱、其他邮箱（verified）
+// 避免用户在个人主页上展示他人的邮箱
+func validatePublicEmail(user *userModel.User, publicEmail string, verified ...string) error {
+	if publicEmail == "" {
+		return nil
+	}
+	if validate.EmailReason(publicEmail) != "" {
+		return errors.P(errors.User, errors.PublicEmail, errors.Invalid)
+	}
+	if user.VerifiedAt != nil && strings.EqualFold(user.Email, publicEmail) {
+		return nil
+	}
+	for _, e := range verified {
+		if strings.EqualFold(e, publicEmail) {
+			return nil
+		}
+	}
+	return errors.P(errors.User, errors.PublicEmail, errors.Invalid)
+}
+
+func verifiedEmails(emails []*userModel.UserEmail) []string {
+	result := make([]string, 0, len(emails))
+	for _, e := range emails {
+		if e.VerifiedAt != nil {
+			result = append(result, e.Email)
+		}
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/growerlab/backend/app/common/errors"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePublicEmail(t *testing.T) {
+	verifiedAt := int64(1)
+	u := &userModel.User{Email: "me@example.com", VerifiedAt: &verifiedAt}
+	invalid := errors.P(errors.User, errors.PublicEmail, errors.Invalid).Error()
+
+	assert.Nil(t, validatePublicEmail(u, ""))
+	assert.Nil(t, validatePublicEmail(u, "me@example.com"))
+	assert.Nil(t, validatePublicEmail(u, "Me@Example.com"))
+	assert.Equal(t, invalid, validatePublicEmail(u, "not-an-email").Error())
+	assert.Equal(t, invalid, validatePublicEmail(u, "other@example.com").Error())
+
+	// 已验证的其他邮箱
+	assert.Nil(t, validatePublicEmail(u, "other@example.com", "Other@example.com"))
+
+	// 邮箱未验证
+	u.VerifiedAt = nil
+	assert.Nil(t, validatePublicEmail(u, ""))
+	assert.Equal(t, invalid, validatePublicEmail(u, "me@example.com").Error())
+}
+
+func TestVerifiedEmails(t *testing.T) {
+	verifiedAt := int64(1)
+	emails := []*userModel.UserEmail{
+		{Email: "a@example.com", VerifiedAt: &verifiedAt},
+		{Email: "b@example.com"},
+	}
+	assert.Equal(t, []string{"a@example.com"}, verifiedEmails(emails))
+}
@@ -0,0 +1,52 @@
+package user
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/growerlab/backend/app/utils/pwd"
+	"github.com/growerlab/backend/app/utils/ratelimit"
+)
+
+// ReauthLimiter 按用户限制再次确认密码的失败次数，由 InitConfig 按配置（login.reauth_*）创建
+// 未初始化时使用默认限制的内存实现
+var ReauthLimiter ratelimit.Limiter
+var reauthLimiterOnce sync.Once
+
+func getReauthLimiter() ratelimit.Limiter {
+	reauthLimiterOnce.Do(func() {
+		if ReauthLimiter == nil {
+			maxFailures, window := (&conf.Login{}).ReauthLimit()
+			ReauthLimiter = ratelimit.NewMemoryLimiter(window, maxFailures)
+		}
+	})
+	return ReauthLimiter
+}
+
+// ReauthWithPassword 修改密码、修改邮箱、注销账号等敏感操作前，再次确认当前用户的密码
+// 密码错误时统一返回 P(User, Password, NotEqual)；时间窗口内失败次数达到上限（配置 login.reauth_*）后返回 RateLimited
+// TODO 开启了TOTP的用户可以在这里要求二次验证
+func ReauthWithPassword(ctx *gin.Context, password string) error {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return errors.Unauthorize()
+	}
+	return reauth(getReauthLimiter(), sess.User(), password)
+}
+
+func reauth(limiter ratelimit.Limiter, user *userModel.User, password string) error {
+	key := strconv.FormatInt(user.ID, 10)
+	if !limiter.Allow(key) {
+		return errors.AccessDenied(errors.User, errors.RateLimited)
+	}
+	if !pwd.ComparePassword(user.EncryptedPassword, password) {
+		limiter.Hit(key, 1)
+		return errors.P(errors.User, errors.Password, errors.NotEqual)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/growerlab/backend/app/common/errors"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/pwd"
+	"github.com/growerlab/backend/app/utils/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReauth(t *testing.T) {
+	hashed, err := pwd.HashPassword("correct-password")
+	assert.Nil(t, err)
+	user := &userModel.User{ID: 1, EncryptedPassword: hashed}
+	other := &userModel.User{ID: 2, EncryptedPassword: hashed}
+	limiter := ratelimit.NewMemoryLimiter(time.Minute, 2)
+
+	assert.Nil(t, reauth(limiter, user, "correct-password"))
+
+	mismatch := errors.P(errors.User, errors.Password, errors.NotEqual)
+	assert.Equal(t, mismatch.Error(), reauth(limiter, user, "wrong").Error())
+	assert.Equal(t, mismatch.Error(), reauth(limiter, user, "wrong").Error())
+
+	// 达到失败上限后，正确的密码也会被拒绝
+	limited := errors.AccessDenied(errors.User, errors.RateLimited)
+	assert.Equal(t, limited.Error(), reauth(limiter, user, "correct-password").Error())
+
+	// 按用户计数
+	assert.Nil(t, reauth(limiter, other, "correct-password"))
+}
@@ -0,0 +1,55 @@
+package user
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/model/db"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	"github.com/growerlab/backend/app/service/common/session"
+)
+
+// RefreshSession 滑动过期
+// 当请求携带的token仍有效，但剩余有效期不足 RefreshThreshold 时，将过期时间延长 RememberTTL（见配置 session）
+// 已过期的token、未勾选“记住我”的短期session不会被延长
+func RefreshSession(ctx *gin.Context) error {
+	token := session.GetUserToken(ctx)
+	if len(token) == 0 {
+		return nil
+	}
+
+	sess, err := sessionModel.GetSessionByToken(db.DB, token)
+	if err != nil {
+		return err
+	}
+	if sess == nil || sess.Impersonated() {
+		return nil
+	}
+
+	now := clk.Now()
+	if !shouldRefresh(sess, now) {
+		return nil
+	}
+	lifetime := sessionConf.RememberTTL()
+	err = sessionModel.ExtendSession(db.DB, token, now.Add(lifetime).Unix())
+	if err != nil {
+		return err
+	}
+	// token来自cookie时，同时延长cookie的有效期
+	if cookieToken, _ := ctx.Cookie(session.CookieName()); cookieToken == token {
+		setTokenCookie(ctx, token, int(lifetime.Seconds()))
+	}
+	return nil
+}
+
+// shouldRefresh session仍有效、剩余有效期不足 RefreshThreshold，且是“记住我”的长期session
+func shouldRefresh(sess *sessionModel.Session, now time.Time) bool {
+	if !sess.Valid(now.Unix()) {
+		return false
+	}
+	if sess.Remaining(now) >= sessionConf.RefreshThreshold() {
+		return false
+	}
+	// 未勾选“记住我”的session不延长
+	return sess.Lifetime() >= sessionConf.RememberTTL()
+}
@@ -0,0 +1,58 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	"github.com/growerlab/backend/app/utils/clock"
+	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldRefresh(t *testing.T) {
+	start := time.Unix(1000000, 0)
+	fake := clock.NewFake(start)
+	defer SetClock(fake)()
+
+	// 有效期来自注入的配置
+	defer SetSessionConfig(&conf.Session{RememberTTLHours: 10 * 24, TTLHours: 12, RefreshThresholdHours: 48})()
+	rememberTTL, ttl := 10*24*time.Hour, 12*time.Hour
+	threshold := 48 * time.Hour
+
+	// 通过 buildAuthSession 创建，时间来自 fake
+	svc := &LoginService{auth: &LoginBasicAuth{}}
+	long := svc.buildAuthSession(1, "127.0.0.1", rememberTTL)
+	short := svc.buildAuthSession(1, "127.0.0.1", ttl)
+	assert.Equal(t, start.Unix(), long.CreatedAt)
+	assert.Equal(t, start.Add(rememberTTL).Unix(), long.ExpiredAt)
+
+	// 剩余有效期仍超过阈值
+	assert.False(t, shouldRefresh(long, fake.Now()))
+
+	// 剩余有效期恰好等于阈值时不延长，少1秒时延长
+	fake.Set(start.Add(rememberTTL - threshold))
+	assert.False(t, shouldRefresh(long, fake.Now()))
+	fake.Add(time.Second)
+	assert.True(t, shouldRefresh(long, fake.Now()))
+
+	// 最后一秒仍可延长，过期后不再延长
+	fake.Set(start.Add(rememberTTL))
+	assert.True(t, shouldRefresh(long, fake.Now()))
+	fake.Add(time.Second)
+	assert.False(t, shouldRefresh(long, fake.Now()))
+
+	// 未勾选“记住我”的session不延长
+	fake.Set(start.Add(ttl - time.Hour))
+	assert.False(t, shouldRefresh(short, fake.Now()))
+
+	assert.False(t, shouldRefresh(&sessionModel.Session{}, fake.Now()))
+}
+
+func TestTokenLifetime(t *testing.T) {
+	defer SetSessionConfig(&conf.Session{RememberTTLHours: 48, TTLHours: 2})()
+	remember, forget := true, false
+	assert.Equal(t, 48*time.Hour, (&LoginBasicAuth{}).tokenLifetime())
+	assert.Equal(t, 48*time.Hour, (&LoginBasicAuth{RememberMe: &remember}).tokenLifetime())
+	assert.Equal(t, 2*time.Hour, (&LoginBasicAuth{RememberMe: &forget}).tokenLifetime())
+}
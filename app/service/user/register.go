@@ -1,24 +1,24 @@
 package user
 
 import (
+	"context"
 	"time"
 
 	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/common/events"
+	"github.com/growerlab/backend/app/common/validate"
 	"github.com/growerlab/backend/app/model/db"
 	nsModel "github.com/growerlab/backend/app/model/namespace"
 	userModel "github.com/growerlab/backend/app/model/user"
+	nsSvc "github.com/growerlab/backend/app/service/namespace"
 	"github.com/growerlab/backend/app/utils/pwd"
 	"github.com/growerlab/backend/app/utils/regex"
-	"github.com/jmoiron/sqlx"
 	"gopkg.in/asaskevich/govalidator.v9"
 )
 
 const (
 	PasswordLenMin = 7
 	PasswordLenMax = 32
-
-	UsernameLenMin = 4
-	UsernameLenMax = 40
 )
 
 type ActivationCodePayload struct {
@@ -29,51 +29,98 @@ type NewUserPayload struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Username string `json:"username"`
+
+	// IdempotencyKey 来自 Idempotency-Key 请求头，为空时不做幂等处理
+	IdempotencyKey string `json:"-"`
+}
+
+// RegisterResult 注册成功后返回，不包含token（激活前不能登录）
+type RegisterResult struct {
+	Username           string `json:"username"`
+	Email              string `json:"email"`
+	ActivationRequired bool   `json:"activation_required"` // 需要点击邮件中的链接激活账号
 }
 
+// UserLoginResult 只返回给用户本人，所以包含私有邮箱（email）
+// 涉及其他用户时，使用 userModel.PublicProfile
 type UserLoginResult struct {
 	Token         string `json:"token"`
 	NamespacePath string `json:"namespace_path"`
 	Email         string `json:"email"`
 	Name          string `json:"name"`
 	PublicEmail   string `json:"public_email"`
+	TOTPRequired  bool   `json:"totp_required"` // 为true时，需要提交两步验证码（其他字段为空）
+	// OAuthTicket 第三方登录需要两步验证时返回，客户端将其与验证码一起重新提交（授权码只能使用一次）
+	OAuthTicket string `json:"oauth_ticket"`
 }
 
 func validateRegisterUser(payload *NewUserPayload) error {
-	if !govalidator.IsEmail(payload.Email) {
-		return errors.P(errors.User, errors.Email, errors.Invalid)
+	if err := validate.ValidateEmail(payload.Email); err != nil {
+		return err
 	}
-	if !govalidator.IsByteLength(payload.Password, PasswordLenMin, PasswordLenMax) {
-		return errors.P(errors.User, errors.Password, errors.InvalidLength)
+	if err := validatePassword(payload.Password); err != nil {
+		return err
 	}
-	if !govalidator.IsByteLength(payload.Username, UsernameLenMin, UsernameLenMax) {
-		return errors.P(errors.User, errors.Username, errors.InvalidLength)
+	if err := validateUsername(payload.Username); err != nil {
+		return err
 	}
-	if !regex.Match(payload.Username, regex.UsernameRegex) {
-		return errors.P(errors.User, errors.Username, errors.Invalid)
+
+	// username是否已被用户或组织占用
+	reason, err := nsSvc.CheckTaken(db.DB, payload.Username)
+	if err != nil {
+		return err
 	}
-	if !regex.Match(payload.Password, regex.PasswordRegex) {
-		return errors.P(errors.User, errors.Password, errors.Invalid)
+	if err = nsSvc.ReasonError(errors.User, errors.Username, reason); err != nil {
+		return err
 	}
 
-	// 不允许使用的关键字
-	if _, invalidUsername := userModel.InvalidUsernameSet[payload.Username]; invalidUsername {
-		return errors.AlreadyExistsError(errors.User, errors.AlreadyExists)
+	// email是否已经存在
+	exists, err := userModel.ExistsEmail(db.DB, payload.Email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.P(errors.User, errors.Email, errors.AlreadyExists)
 	}
 
-	// email, username是否已经存在
-	exists, err := userModel.ExistsEmailOrUsername(db.DB, payload.Username, payload.Email)
+	// 被已注销的账号占用，需要联系管理员处理
+	exists, err = userModel.ExistsEmailIncludingDeleted(db.DB, payload.Email)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return errors.AlreadyExistsError(errors.User, errors.AlreadyExists)
+		return errors.P(errors.User, errors.Email, errors.Deleted)
 	}
 	return nil
 }
 
+// validateUsername 校验用户名的格式（注册、修改用户名）
+func validateUsername(username string) error {
+	return validate.ValidateUsername(username)
+}
+
+// validatePassword 校验新密码（注册、重置密码、修改密码）
+func validatePassword(password string) error {
+	if !govalidator.IsByteLength(password, PasswordLenMin, PasswordLenMax) {
+		return errors.P(errors.User, errors.Password, errors.InvalidLength)
+	}
+	if !regex.Match(password, regex.PasswordRegex) {
+		return errors.P(errors.User, errors.Password, errors.Invalid)
+	}
+	switch pwd.ValidateStrength(password) {
+	case nil:
+		return nil
+	case pwd.ErrTooShort:
+		return errors.P(errors.User, errors.Password, errors.InvalidLength)
+	case pwd.ErrCommon:
+		return errors.P(errors.User, errors.Password, errors.Weak)
+	default:
+		return errors.P(errors.User, errors.Password, errors.Invalid)
+	}
+}
+
 func buildUser(payload *NewUserPayload, clientIP string) (*userModel.User, error) {
-	password, err := pwd.GeneratePassword(payload.Password)
+	password, err := pwd.HashPassword(payload.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -82,8 +129,9 @@ func buildUser(payload *NewUserPayload, clientIP string) (*userModel.User, error
 		EncryptedPassword: password,
 		Username:          payload.Username,
 		Name:              payload.Username,
-		PublicEmail:       payload.Email,
+		PublicEmail:       "", // 邮箱验证（激活）后才公开，见 validatePublicEmail
 		CreatedAt:         time.Now().Unix(),
+		VerifiedAt:        nil, // 激活后设置
 		RegisterIP:        clientIP,
 		IsAdmin:           false,
 		NamespaceID:       0,
@@ -102,33 +150,43 @@ func buildNamespace(user *userModel.User) *nsModel.Namespace {
 // 1. 将用户信息添加到数据库中
 // 2. 发送验证邮件（这里可以考虑使用KeyDB来建立邮件发送队列，避免重启进程后，发送任务丢失）
 // 3. Done
-func Register(payload *NewUserPayload, clientIP string) error {
+func Register(payload *NewUserPayload, clientIP string) (*RegisterResult, error) {
+	return RegisterContext(context.Background(), payload, clientIP)
+}
+
+// RegisterContext 同 Register，ctx 被取消时（例如客户端断开连接）中止注册
+// 用户、命名空间、激活码在同一个事务中创建；注册后不会登录，需要先通过邮件激活
+// 提供了 IdempotencyKey 时，有效期内使用相同的键重试（例如移动端网络超时）会返回首次注册的结果，而不是重复注册或报错
+func RegisterContext(ctx context.Context, payload *NewUserPayload, clientIP string) (*RegisterResult, error) {
 	var err error
+	idempotent := len(payload.IdempotencyKey) > 0
+	if idempotent {
+		if err = validateIdempotencyKey(payload.IdempotencyKey); err != nil {
+			return nil, err
+		}
+		result, err := replayRegister(payload)
+		if result != nil || err != nil {
+			return result, err
+		}
+	}
+
 	err = validateRegisterUser(payload)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = db.Transact(func(tx sqlx.Ext) error {
-		user, err := buildUser(payload, clientIP)
-		if err != nil {
-			return err
-		}
-
-		err = userModel.AddUser(tx, user)
+	var user *userModel.User
+	err = db.TransactContext(ctx, func(tx db.ContextExt) error {
+		user, err = buildUser(payload, clientIP)
 		if err != nil {
 			return err
 		}
 
-		// create namespace
-		ns := buildNamespace(user)
-		err = nsModel.AddNamespace(tx, ns)
-		if err != nil {
+		if err = validatePublicEmail(user, user.PublicEmail); err != nil {
 			return err
 		}
-
-		// set namespace id to user
-		err = userModel.UpdateNamespace(tx, user.ID, ns.ID)
+		// 同时创建个人命名空间
+		err = userModel.CreateUserWithNamespaceContext(ctx, tx, user)
 		if err != nil {
 			return err
 		}
@@ -138,7 +196,29 @@ func Register(payload *NewUserPayload, clientIP string) error {
 		if err != nil {
 			return err
 		}
+
+		if idempotent {
+			return saveRegisterIdempotencyKey(tx, payload, user.ID)
+		}
 		return nil
 	})
-	return err
+	if err != nil {
+		if idempotent {
+			// 并发的重试：先提交的请求已完成注册，这里因唯一约束失败
+			if result, replayErr := replayRegister(payload); result != nil || replayErr != nil {
+				return result, replayErr
+			}
+		}
+		return nil, err
+	}
+	publishUserEvent(events.UserRegistered, user)
+	return newRegisterResult(user), nil
+}
+
+func newRegisterResult(user *userModel.User) *RegisterResult {
+	return &RegisterResult{
+		Username:           user.Username,
+		Email:              user.Email,
+		ActivationRequired: user.VerifiedAt == nil,
+	}
 }
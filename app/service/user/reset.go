@@ -0,0 +1,160 @@
+package user
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	"github.com/growerlab/backend/app/model/reset"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/clientip"
+	"github.com/growerlab/backend/app/utils/logger"
+	"github.com/growerlab/backend/app/utils/pwd"
+	"github.com/growerlab/backend/app/utils/ratelimit"
+	"github.com/growerlab/backend/app/utils/uuid"
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/asaskevich/govalidator.v9"
+)
+
+const PasswordResetExpiredTime = 2 * time.Hour
+
+// 重置密码邮件的发送频率限制
+const (
+	PasswordResetResendInterval = 5 * time.Minute // 同一邮箱两次发送的最小间隔
+	PasswordResetIPPerHour      = 10              // 同一IP每小时最多发送的次数
+)
+
+// PasswordResetLimiter 按客户端IP限制重置密码邮件的发送次数，为nil时使用内存实现
+var PasswordResetLimiter ratelimit.Limiter
+var passwordResetLimiterOnce sync.Once
+
+func getPasswordResetLimiter() ratelimit.Limiter {
+	passwordResetLimiterOnce.Do(func() {
+		if PasswordResetLimiter == nil {
+			PasswordResetLimiter = ratelimit.NewMemoryLimiter(time.Hour, PasswordResetIPPerHour)
+		}
+	})
+	return PasswordResetLimiter
+}
+
+type PasswordResetPayload struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirmPayload struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// RequestPasswordReset 申请重置密码
+// 为避免暴露邮箱是否已注册、以及限流的状态，以下情况均直接返回nil（不发送邮件）：
+// - 邮箱不存在
+// - 距离该邮箱上次发送不足 PasswordResetResendInterval
+// - 当前IP发送次数超出 PasswordResetIPPerHour
+func RequestPasswordReset(ctx *gin.Context, email string) error {
+	if !govalidator.IsEmail(email) {
+		return errors.P(errors.User, errors.Email, errors.Invalid)
+	}
+
+	limiter := getPasswordResetLimiter()
+	ip := clientip.FromRequest(ctx.Request)
+	if !limiter.Allow(ip) {
+		return nil
+	}
+
+	user, err := userModel.GetUserByEmailContext(ctx.Request.Context(), db.DB, email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	var r *reset.PasswordReset
+	err = db.Transact(func(tx sqlx.Ext) error {
+		last, err := reset.GetLatestPasswordReset(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		if last != nil && time.Since(time.Unix(last.CreatedAt, 0)) < PasswordResetResendInterval {
+			return nil
+		}
+		r = buildPasswordReset(user.ID)
+		return reset.AddPasswordReset(tx, r)
+	})
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		return nil
+	}
+	limiter.Hit(ip, 1)
+
+	resetURL := buildWebsiteURL(fmt.Sprintf("reset_password/%s", r.Token))
+	logger.Info("the reset password url: %v", resetURL)
+
+	// TODO 发送邮件
+	return nil
+}
+
+// ConfirmPasswordReset 使用重置token设置新密码
+// 重置成功后，token失效，且用户的所有session将被注销
+func ConfirmPasswordReset(ctx *gin.Context, token, newPassword string) error {
+	if len(token) == 0 {
+		return errors.P(errors.PasswordReset, errors.Token, errors.Empty)
+	}
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	encrypted, err := pwd.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	var userID int64
+	err = db.Transact(func(tx sqlx.Ext) error {
+		r, err := reset.GetPasswordReset(tx, token)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return errors.NotFoundError(errors.PasswordReset)
+		}
+		if r.UsedAt != nil {
+			return errors.P(errors.PasswordReset, errors.Token, errors.Used)
+		}
+		if r.ExpiredAt < time.Now().Unix() {
+			return errors.P(errors.PasswordReset, errors.Token, errors.Expired)
+		}
+
+		err = reset.UsePasswordReset(tx, token)
+		if err != nil {
+			return err
+		}
+		err = userModel.UpdatePassword(tx, r.UserID, encrypted)
+		if err != nil {
+			return err
+		}
+		_, err = sessionModel.DeleteSessionsByOwner(tx, r.UserID)
+		userID = r.UserID
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	userModel.InvalidateUserTokens(userID)
+	return nil
+}
+
+func buildPasswordReset(userID int64) *reset.PasswordReset {
+	return &reset.PasswordReset{
+		UserID:    userID,
+		Token:     uuid.UUID(),
+		ExpiredAt: time.Now().Add(PasswordResetExpiredTime).Unix(),
+	}
+}
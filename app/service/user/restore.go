@@ -0,0 +1,75 @@
+package user
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/jmoiron/sqlx"
+)
+
+// RestoreUser 管理员恢复已注销（软删除）的账号
+// 1. 只能恢复 restore_grace_days 天内注销的账号
+// 2. 邮箱、用户名在注销后被其他账号占用时，不允许恢复
+// 3. 用户的命名空间已被释放时，重新创建
+func RestoreUser(ctx *gin.Context, userID int64) error {
+	if _, err := currentAdmin(ctx, userModel.RoleSupport); err != nil {
+		return err
+	}
+
+	grace := time.Duration(conf.GetConf().GetAccount().RestoreGraceDays) * 24 * time.Hour
+	return db.Transact(func(tx sqlx.Ext) error {
+		u, err := userModel.GetUserIncludingDeleted(tx, userID)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return errors.NotFoundError(errors.User)
+		}
+		if u.DeletedAt == nil {
+			return errors.P(errors.User, errors.ID, errors.Unchanged)
+		}
+		if restoreExpired(*u.DeletedAt, grace, time.Now()) {
+			return errors.AccessDenied(errors.User, errors.Expired)
+		}
+
+		// 已注销的用户不在 ExistsEmailOrUsername 的范围内，所以这里只会匹配到其他用户
+		exists, err := userModel.ExistsEmailOrUsername(tx, u.Username, u.Email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return errors.AlreadyExistsError(errors.User, errors.AlreadyExists)
+		}
+
+		ns, err := nsModel.GetNamespaceByPath(tx, u.Username)
+		if err != nil {
+			return err
+		}
+		if ns != nil && ns.ID != u.NamespaceID {
+			return errors.AlreadyExistsError(errors.Namespace, errors.AlreadyExists)
+		}
+		if ns == nil {
+			ns = buildNamespace(u)
+			err = nsModel.AddNamespace(tx, ns)
+			if err != nil {
+				return err
+			}
+			err = userModel.UpdateNamespace(tx, u.ID, ns.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return conflictError(userModel.RestoreUser(tx, u.ID))
+	})
+}
+
+// restoreExpired 是否已超过允许恢复的期限
+func restoreExpired(deletedAt int64, grace time.Duration, now time.Time) bool {
+	return time.Unix(deletedAt, 0).Add(grace).Before(now)
+}
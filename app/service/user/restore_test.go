@@ -0,0 +1,16 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreExpired(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	grace := 30 * 24 * time.Hour
+	assert.False(t, restoreExpired(now.Add(-time.Hour).Unix(), grace, now))
+	assert.False(t, restoreExpired(now.Add(-grace).Unix(), grace, now))
+	assert.True(t, restoreExpired(now.Add(-grace-time.Second).Unix(), grace, now))
+}
@@ -0,0 +1,61 @@
+package user
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+)
+
+const (
+	SearchUsersDefaultLimit = 10
+	SearchUsersMaxLimit     = 50
+)
+
+type SearchUserResult struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+// SearchUsers 按用户名或名称前缀搜索用户（例如 @某人 时的提示），需要登录
+// withEmail 为true时同时按邮箱搜索，仅限管理员（邮箱是私有信息）
+func SearchUsers(ctx *gin.Context, query string, limit uint64, withEmail bool) ([]*SearchUserResult, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	search := userModel.SearchUsers
+	if withEmail {
+		if _, err := currentAdmin(ctx, userModel.RoleModerator, userModel.RoleSupport); err != nil {
+			return nil, err
+		}
+		search = userModel.SearchUsersWithEmail
+	}
+
+	result := make([]*SearchUserResult, 0)
+	query = strings.TrimSpace(query)
+	if len(query) == 0 {
+		return result, nil
+	}
+	if limit == 0 {
+		limit = SearchUsersDefaultLimit
+	}
+	if limit > SearchUsersMaxLimit {
+		limit = SearchUsersMaxLimit
+	}
+
+	users, err := search(db.Replica(), query, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		result = append(result, &SearchUserResult{
+			Username: u.Username,
+			Name:     u.Name,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,138 @@
+package user
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/base"
+	"github.com/growerlab/backend/app/model/db"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+)
+
+// SessionInfo 用于「账号安全」页面展示的session信息（不包含完整token）
+type SessionInfo struct {
+	ID        string `json:"id"`
+	Token     string `json:"token"` // 脱敏后的token
+	ClientIP  string `json:"client_ip"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiredAt int64  `json:"expired_at"`
+	Current   bool   `json:"current"` // 是否为当前请求的session
+
+	Impersonated bool `json:"impersonated"` // 是否为管理员代登录的session
+
+	UserAgent string `json:"user_agent"`
+	Device    string `json:"device"` // 根据User-Agent解析的设备名，例如「Chrome on macOS」，无法识别时为空
+}
+
+func newSessionInfo(s *sessionModel.Session, currentToken string) *SessionInfo {
+	return &SessionInfo{
+		ID:        strconv.FormatInt(s.ID, 10),
+		Token:     maskToken(s.Token),
+		ClientIP:  s.ClientIP,
+		CreatedAt: s.CreatedAt,
+		ExpiredAt: s.ExpiredAt,
+		Current:   s.Token == currentToken,
+
+		Impersonated: s.Impersonated(),
+
+		UserAgent: s.Agent(),
+		Device:    DeviceName(s.Agent()),
+	}
+}
+
+// ListMySessionsMaxPer 每页最多返回的session数量
+const ListMySessionsMaxPer = 50
+
+// ListMySessions 分页获取当前用户有效的session（page从0开始，最新创建的在前）
+func ListMySessions(ctx *gin.Context, page, per uint64) (*base.Page[*SessionInfo], error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	if per == 0 || per > ListMySessionsMaxPer {
+		per = ListMySessionsMaxPer
+	}
+
+	ownerID := sess.User().ID
+	sessions, err := base.Paginate(page, per,
+		func(page, per uint64) ([]*sessionModel.Session, error) {
+			return sessionModel.ListSessionsByOwner(db.DB, ownerID, page, per)
+		},
+		func() (int64, error) {
+			return sessionModel.CountActiveSessionsByOwner(db.DB, ownerID)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	currentToken := sess.Token()
+	return base.MapPage(sessions, func(s *sessionModel.Session) *SessionInfo {
+		return newSessionInfo(s, currentToken)
+	}), nil
+}
+
+type RevokeSessionPayload struct {
+	ID string `json:"id"`
+}
+
+type RevokeSessionResult struct {
+	ClearCookie bool `json:"clear_cookie"` // 注销的是当前session，客户端应清除cookie
+}
+
+// RevokeSession 注销当前用户的某个session（例如「退出该设备」）
+// 只能注销属于自己的session，注销其他用户的session时返回 AccessDenied
+func RevokeSession(ctx *gin.Context, sessionID string) (*RevokeSessionResult, error) {
+	id, err := strconv.ParseInt(sessionID, 10, 64)
+	if err != nil || id <= 0 {
+		return nil, errors.P(errors.Session, errors.ID, errors.Invalid)
+	}
+
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+
+	target, err := sessionModel.GetSession(db.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkRevokable(target, sess.User().ID); err != nil {
+		return nil, err
+	}
+
+	err = sessionModel.DeleteSessionByToken(db.DB, target.Token)
+	if err != nil {
+		return nil, err
+	}
+	userModel.InvalidateToken(target.Token)
+
+	result := &RevokeSessionResult{}
+	if target.Token == sess.Token() {
+		clearCookie(ctx)
+		result.ClearCookie = true
+	}
+	return result, nil
+}
+
+// checkRevokable session必须存在且属于当前用户
+func checkRevokable(target *sessionModel.Session, userID int64) error {
+	if target == nil {
+		return errors.NotFoundError(errors.Session)
+	}
+	if target.OwnerID != userID {
+		return errors.AccessDenied(errors.Session, errors.NoPermission)
+	}
+	return nil
+}
+
+// maskToken 仅保留token的前4位
+func maskToken(token string) string {
+	const visible = 4
+	if len(token) <= visible {
+		return "****"
+	}
+	return token[:visible] + "****"
+}
@@ -0,0 +1,19 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/growerlab/backend/app/common/errors"
+	sessionModel "github.com/growerlab/backend/app/model/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRevokable(t *testing.T) {
+	// 不存在的session
+	assert.Equal(t, errors.NotFoundError(errors.Session).Error(), checkRevokable(nil, 1).Error())
+	// 其他用户的session
+	other := &sessionModel.Session{OwnerID: 2}
+	assert.Equal(t, errors.AccessDenied(errors.Session, errors.NoPermission).Error(), checkRevokable(other, 1).Error())
+
+	assert.Nil(t, checkRevokable(&sessionModel.Session{OwnerID: 1}, 1))
+}
@@ -0,0 +1,185 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	"github.com/growerlab/backend/app/model/recoverycode"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/growerlab/backend/app/utils/secret"
+	"github.com/growerlab/backend/app/utils/totp"
+	"github.com/jmoiron/sqlx"
+)
+
+const TOTPIssuer = "GrowerLab"
+const RecoveryCodeCount = 10
+
+type EnrollTOTPResult struct {
+	Secret string `json:"secret"` // 用于无法扫码时手动输入
+	URI    string `json:"uri"`
+}
+
+type ConfirmTOTPPayload struct {
+	Code string `json:"code"`
+}
+
+type ConfirmTOTPResult struct {
+	RecoveryCodes []string `json:"recovery_codes"` // 仅在此时返回一次
+}
+
+// EnrollTOTP 开始启用两步验证：生成密钥（加密保存）
+// 用户需要使用 ConfirmTOTP 提交第一个验证码后才真正启用
+func EnrollTOTP(ctx *gin.Context) (*EnrollTOTPResult, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	user := sess.User()
+	if user.TOTPEnabled() {
+		return nil, errors.AlreadyExistsError(errors.TOTP, errors.AlreadyExists)
+	}
+
+	key, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	encrypted, err := secret.Encrypt(key)
+	if err != nil {
+		return nil, err
+	}
+	err = userModel.SetTOTPSecret(db.DB, user.ID, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	// 缓存的用户中还是旧的密钥（或没有密钥）
+	userModel.InvalidateUserTokens(user.ID)
+
+	return &EnrollTOTPResult{
+		Secret: key,
+		URI:    totp.ProvisioningURI(TOTPIssuer, user.Username, key),
+	}, nil
+}
+
+// ConfirmTOTP 验证第一个验证码，启用两步验证，并生成一组恢复码
+// 密钥从数据库中读取：session中缓存的用户可能还没有密钥，或者是重新生成之前的旧密钥
+func ConfirmTOTP(ctx *gin.Context, code string) (*ConfirmTOTPResult, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	userID := sess.User().ID
+
+	codes, hashes, err := buildRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = db.Transact(func(tx sqlx.Ext) error {
+		user, err := userModel.GetUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return errors.NotFoundError(errors.User)
+		}
+		if user.TOTPEnabled() {
+			return errors.AlreadyExistsError(errors.TOTP, errors.AlreadyExists)
+		}
+		if user.TOTPSecret == nil {
+			return errors.NotFoundError(errors.TOTP)
+		}
+
+		key, err := secret.Decrypt(*user.TOTPSecret)
+		if err != nil {
+			return err
+		}
+		step, ok := totp.ValidateStep(key, strings.TrimSpace(code), time.Now())
+		if !ok {
+			return errors.P(errors.TOTP, errors.Code, errors.NotEqual)
+		}
+		// 确认时使用过的code也不能再用于登录
+		if err := useTOTPStep(tx, user.ID, step); err != nil {
+			return err
+		}
+
+		err = userModel.EnableTOTP(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		return recoverycode.ReplaceCodes(tx, user.ID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	userModel.InvalidateUserTokens(userID)
+	return &ConfirmTOTPResult{RecoveryCodes: codes}, nil
+}
+
+// verifySecondFactor 登录时验证两步验证码，code 可以是TOTP验证码或恢复码
+func verifySecondFactor(tx sqlx.Execer, user *userModel.User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == 0 {
+		return errors.P(errors.TOTP, errors.Code, errors.Empty)
+	}
+
+	if len(code) == totp.Digits {
+		key, err := secret.Decrypt(*user.TOTPSecret)
+		if err != nil {
+			return err
+		}
+		step, ok := totp.ValidateStep(key, code, time.Now())
+		if !ok {
+			return errors.P(errors.TOTP, errors.Code, errors.NotEqual)
+		}
+		return useTOTPStep(tx, user.ID, step)
+	}
+
+	ok, err := recoverycode.UseCode(tx, user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.P(errors.TOTP, errors.Code, errors.NotEqual)
+	}
+	return nil
+}
+
+// useTOTPStep 记录本次通过验证的时间步；同一个code（或更早的code）在偏差窗口内被重放时返回错误
+func useTOTPStep(tx sqlx.Execer, userID, step int64) error {
+	ok, err := userModel.UseTOTPStep(tx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.P(errors.TOTP, errors.Code, errors.Used)
+	}
+	return nil
+}
+
+// buildRecoveryCodes 生成恢复码，数据库中只保存hash
+func buildRecoveryCodes() (codes []string, hashes []string, err error) {
+	codes = make([]string, 0, RecoveryCodeCount)
+	hashes = make([]string, 0, RecoveryCodeCount)
+	buf := make([]byte, 5)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		if _, err = rand.Read(buf); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		raw := hex.EncodeToString(buf)
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,28 @@
+package user
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// stepExecer 模拟数据库中 totp_last_step 的条件更新
+type stepExecer struct {
+	affected int64
+}
+
+func (e *stepExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return driver.RowsAffected(e.affected), nil
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	assert.Nil(t, useTOTPStep(&stepExecer{affected: 1}, 1, 100))
+
+	// 时间步不晚于上次记录的值（同一个code被重放）
+	assert.Equal(t,
+		errors.P(errors.TOTP, errors.Code, errors.Used).Error(),
+		useTOTPStep(&stepExecer{affected: 0}, 1, 100).Error())
+}
@@ -0,0 +1,286 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/common/validate"
+	"github.com/growerlab/backend/app/model/db"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	"github.com/growerlab/backend/app/utils/logger"
+	"github.com/growerlab/backend/app/utils/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// UserEmailsMax 每个用户最多添加的邮箱数量（含主邮箱）
+const UserEmailsMax = 10
+
+// UserEmailVerifyExpiredTime 验证链接的有效期
+const UserEmailVerifyExpiredTime = 24 * time.Hour
+
+type AddEmailPayload struct {
+	Email string `json:"email"`
+}
+
+type VerifyEmailPayload struct {
+	Token string `json:"token"`
+}
+
+type EmailIDPayload struct {
+	ID int64 `json:"id"`
+}
+
+// EmailInfo 用户本人可见的邮箱
+// 迁移之前注册的用户，主邮箱在 user_email 中没有记录，此时 ID 为0
+type EmailInfo struct {
+	ID        int64  `json:"id"`
+	Email     string `json:"email"`
+	Primary   bool   `json:"primary"`
+	Verified  bool   `json:"verified"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// ListEmails 当前用户的所有邮箱，主邮箱排在最前
+func ListEmails(ctx *gin.Context) ([]*EmailInfo, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	emails, err := userModel.ListUserEmails(db.DB, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return buildEmailInfos(user, emails), nil
+}
+
+func buildEmailInfos(user *userModel.User, emails []*userModel.UserEmail) []*EmailInfo {
+	primary := &EmailInfo{
+		Email:     user.Email,
+		Primary:   true,
+		Verified:  user.VerifiedAt != nil,
+		CreatedAt: user.CreatedAt,
+	}
+	result := []*EmailInfo{primary}
+	for _, e := range emails {
+		// user.email 才是主邮箱，is_primary 只作为标记
+		if strings.EqualFold(e.Email, user.Email) {
+			primary.ID = e.ID
+			continue
+		}
+		result = append(result, &EmailInfo{
+			ID:        e.ID,
+			Email:     e.Email,
+			Verified:  e.VerifiedAt != nil,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	return result
+}
+
+// AddEmail 为当前用户添加一个邮箱，并向该邮箱发送验证链接
+// 验证之前，该邮箱不能用于登录，也不能设置为主邮箱
+func AddEmail(ctx *gin.Context, email string) error {
+	email = strings.TrimSpace(email)
+	if err := validate.ValidateEmail(email); err != nil {
+		return err
+	}
+	user, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	var ue *userModel.UserEmail
+	err = db.Transact(func(tx sqlx.Ext) error {
+		emails, err := userModel.ListUserEmails(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		if len(buildEmailInfos(user, emails)) >= UserEmailsMax {
+			return errors.P(errors.User, errors.Email, errors.InvalidLength)
+		}
+
+		exists, err := userModel.ExistsEmailOrUsername(tx, "", email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return errors.AlreadyExistsError(errors.User, errors.Email)
+		}
+		// 验证链接已过期的邮箱（包括其他用户添加的）不再占用该地址
+		// 其他用户未验证（且未过期）的邮箱，仍由唯一约束拒绝
+		old, err := userModel.GetUserEmailByAddress(tx, email)
+		if err != nil {
+			return err
+		}
+		if old != nil && old.VerifyExpired(time.Now().Unix()) {
+			if err = userModel.DeleteUserEmail(tx, old.ID); err != nil {
+				return err
+			}
+		}
+
+		expiredAt := time.Now().Add(UserEmailVerifyExpiredTime).Unix()
+		ue = &userModel.UserEmail{
+			UserID:    user.ID,
+			Email:     email,
+			Token:     uuid.UUID(),
+			ExpiredAt: &expiredAt,
+		}
+		return userModel.AddUserEmail(tx, ue)
+	})
+	if err != nil {
+		return err
+	}
+
+	verifyURL := buildWebsiteURL(fmt.Sprintf("verify_email/%s", ue.Token))
+	logger.Info("the verify email url: %v", verifyURL)
+
+	// TODO 发送邮件
+	return nil
+}
+
+// VerifyEmail 通过邮件中的链接验证邮箱
+func VerifyEmail(token string) error {
+	if len(token) == 0 {
+		return errors.P(errors.UserEmail, errors.Token, errors.Empty)
+	}
+	return db.Transact(func(tx sqlx.Ext) error {
+		ue, err := userModel.GetUserEmailByToken(tx, token)
+		if err != nil {
+			return err
+		}
+		if err = checkEmailVerifiable(ue, time.Now().Unix()); err != nil {
+			return err
+		}
+		// 添加之后，该邮箱可能已被其他用户验证或注册
+		u, err := userModel.GetUserByEmail(tx, ue.Email)
+		if err != nil {
+			return err
+		}
+		if u != nil && u.ID != ue.UserID {
+			return errors.AlreadyExistsError(errors.User, errors.Email)
+		}
+		return userModel.VerifyUserEmail(tx, ue.ID)
+	})
+}
+
+// checkEmailVerifiable 未验证的邮箱才能验证，且链接必须在有效期内
+func checkEmailVerifiable(ue *userModel.UserEmail, now int64) error {
+	if ue == nil || ue.VerifiedAt != nil {
+		return errors.NotFoundError(errors.UserEmail)
+	}
+	if ue.VerifyExpired(now) {
+		return errors.P(errors.UserEmail, errors.Token, errors.Expired)
+	}
+	return nil
+}
+
+// SetPrimaryEmail 将已验证的邮箱设置为主邮箱（user.email）
+// 原主邮箱保留为已验证的其他邮箱
+func SetPrimaryEmail(ctx *gin.Context, emailID int64) error {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = db.Transact(func(tx sqlx.Ext) error {
+		ue, err := getOwnEmail(tx, user, emailID)
+		if err != nil {
+			return err
+		}
+		if ue.VerifiedAt == nil {
+			return errors.P(errors.User, errors.Email, errors.NotActivated)
+		}
+		if strings.EqualFold(ue.Email, user.Email) {
+			return errors.P(errors.User, errors.Email, errors.Unchanged)
+		}
+
+		// 迁移之前的主邮箱没有记录时补上，避免修改后丢失
+		old, err := userModel.GetUserEmailByAddress(tx, user.Email)
+		if err != nil {
+			return err
+		}
+		if old == nil {
+			err = userModel.AddUserEmail(tx, &userModel.UserEmail{
+				UserID:     user.ID,
+				Email:      user.Email,
+				VerifiedAt: user.VerifiedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = userModel.SetPrimaryUserEmail(tx, user.ID, ue.ID)
+		if err != nil {
+			return err
+		}
+		err = userModel.UpdateEmail(tx, user.ID, user.Version, ue.Email)
+		if err != nil {
+			return conflictError(err)
+		}
+		if strings.EqualFold(user.PublicEmail, user.Email) {
+			return userModel.UpdateProfile(tx, user.ID, user.Name, ue.Email)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	userModel.InvalidateUserTokens(user.ID)
+	return nil
+}
+
+// DeleteEmail 删除主邮箱以外的邮箱
+func DeleteEmail(ctx *gin.Context, emailID int64) error {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = db.Transact(func(tx sqlx.Ext) error {
+		ue, err := getOwnEmail(tx, user, emailID)
+		if err != nil {
+			return err
+		}
+		if ue.IsPrimary || strings.EqualFold(ue.Email, user.Email) {
+			return errors.P(errors.User, errors.Email, errors.Primary)
+		}
+		err = userModel.DeleteUserEmail(tx, ue.ID)
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(user.PublicEmail, ue.Email) {
+			return userModel.UpdateProfile(tx, user.ID, user.Name, "")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	userModel.InvalidateUserTokens(user.ID)
+	return nil
+}
+
+// getOwnEmail 获取用户自己的邮箱，不存在或属于其他用户时返回NotFound
+func getOwnEmail(src sqlx.Queryer, user *userModel.User, emailID int64) (*userModel.UserEmail, error) {
+	ue, err := userModel.GetUserEmail(src, emailID)
+	if err != nil {
+		return nil, err
+	}
+	if ue == nil || ue.UserID != user.ID {
+		return nil, errors.NotFoundError(errors.UserEmail)
+	}
+	return ue, nil
+}
+
+func currentUser(ctx *gin.Context) (*userModel.User, error) {
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return nil, errors.Unauthorize()
+	}
+	return sess.User(), nil
+}
@@ -0,0 +1,45 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/growerlab/backend/app/common/errors"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildEmailInfos(t *testing.T) {
+	verifiedAt := int64(1)
+	u := &userModel.User{Email: "me@example.com", VerifiedAt: &verifiedAt, CreatedAt: 100}
+
+	// 迁移之前的用户，主邮箱没有记录
+	infos := buildEmailInfos(u, []*userModel.UserEmail{
+		{ID: 2, Email: "work@example.com", CreatedAt: 200},
+	})
+	assert.Equal(t, []*EmailInfo{
+		{ID: 0, Email: "me@example.com", Primary: true, Verified: true, CreatedAt: 100},
+		{ID: 2, Email: "work@example.com", Verified: false, CreatedAt: 200},
+	}, infos)
+
+	infos = buildEmailInfos(u, []*userModel.UserEmail{
+		{ID: 1, Email: "old@example.com", VerifiedAt: &verifiedAt, CreatedAt: 50},
+		{ID: 3, Email: "Me@example.com", IsPrimary: true, VerifiedAt: &verifiedAt, CreatedAt: 300},
+	})
+	assert.Equal(t, []*EmailInfo{
+		{ID: 3, Email: "me@example.com", Primary: true, Verified: true, CreatedAt: 100},
+		{ID: 1, Email: "old@example.com", Verified: true, CreatedAt: 50},
+	}, infos)
+}
+
+func TestCheckEmailVerifiable(t *testing.T) {
+	expiredAt := int64(100)
+	ue := &userModel.UserEmail{ExpiredAt: &expiredAt}
+	assert.Nil(t, checkEmailVerifiable(ue, 100))
+
+	notFound := errors.NotFoundError(errors.UserEmail).Error()
+	assert.Equal(t, errors.P(errors.UserEmail, errors.Token, errors.Expired).Error(), checkEmailVerifiable(ue, 101).Error())
+	assert.Equal(t, notFound, checkEmailVerifiable(nil, 0).Error())
+
+	verifiedAt := int64(50)
+	assert.Equal(t, notFound, checkEmailVerifiable(&userModel.UserEmail{VerifiedAt: &verifiedAt}, 0).Error())
+}
@@ -0,0 +1,101 @@
+package user
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/model/db"
+	nsModel "github.com/growerlab/backend/app/model/namespace"
+	userModel "github.com/growerlab/backend/app/model/user"
+	"github.com/growerlab/backend/app/service/common/session"
+	nsSvc "github.com/growerlab/backend/app/service/namespace"
+	"github.com/jmoiron/sqlx"
+)
+
+type ChangeUsernamePayload struct {
+	Username string `json:"username"`
+}
+
+// ChangeUsername 修改用户名
+// 用户名与用户的namespace.path必须保持一致，所以两者在同一个事务中修改，并记录旧的用户名用于跳转
+// 距离上次修改不足 account.username_cooldown_days 时返回 CooldownError（管理员可以通过 AdminResetUsernameCooldown 解除）
+func ChangeUsername(ctx *gin.Context, username string) error {
+	username = strings.TrimSpace(username)
+	if err := validateUsername(username); err != nil {
+		return err
+	}
+
+	sess := session.New(ctx)
+	if sess == nil || sess.User() == nil {
+		return errors.Unauthorize()
+	}
+	user := sess.User()
+	if user.Username == username {
+		return errors.P(errors.User, errors.Username, errors.Unchanged)
+	}
+	if err := nsSvc.CheckUsernameCooldown(user, clk.Now()); err != nil {
+		return err
+	}
+
+	err := db.Transact(func(tx sqlx.Ext) error {
+		// 仅修改大小写时，不需要检查重复
+		if !strings.EqualFold(user.Username, username) {
+			reason, err := nsSvc.CheckTakenBy(tx, username, user.ID, user.NamespaceID)
+			if err != nil {
+				return err
+			}
+			if err = nsSvc.ReasonError(errors.User, errors.Username, reason); err != nil {
+				return err
+			}
+		}
+
+		// user 可能在本次请求之后被其他请求修改过，此时返回冲突，由客户端刷新后重试
+		err := userModel.UpdateUsername(tx, user.ID, user.Version, username)
+		if err != nil {
+			return conflictError(err)
+		}
+		ns, err := nsModel.GetNamespace(tx, user.NamespaceID)
+		if err != nil {
+			return err
+		}
+		if ns == nil {
+			return errors.NotFoundError(errors.Namespace)
+		}
+		// 同时记录旧path的跳转，旧地址（包括仓库地址）返回301
+		err = nsSvc.RenameTx(tx, ns, username)
+		if err != nil {
+			return err
+		}
+		return userModel.AddUsernameHistory(tx, user.ID, user.Username)
+	})
+	if err != nil {
+		return err
+	}
+	userModel.InvalidateUserTokens(user.ID)
+	return nil
+}
+
+type ResetUsernameCooldownPayload struct {
+	UserID int64 `json:"user_id"`
+}
+
+// AdminResetUsernameCooldown 管理员（support）解除用户修改用户名的冷却期，例如用户误操作后需要改回
+func AdminResetUsernameCooldown(ctx *gin.Context, userID int64) error {
+	if _, err := currentAdmin(ctx, userModel.RoleSupport); err != nil {
+		return err
+	}
+	u, err := userModel.GetUser(db.DB, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return errors.NotFoundError(errors.User)
+	}
+	err = userModel.ResetUsernameChangeCooldown(db.DB, u.ID)
+	if err != nil {
+		return err
+	}
+	userModel.InvalidateUserTokens(u.ID)
+	return nil
+}
@@ -0,0 +1,123 @@
+package clientip
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/utils/conf"
+)
+
+const (
+	headerForwardedFor = "X-Forwarded-For"
+	headerRealIP       = "X-Real-Ip"
+)
+
+// Resolver 根据可信代理列表解析请求的真实客户端IP
+// 只有直接连接的对端（RemoteAddr）属于可信代理时，才读取 X-Forwarded-For / X-Real-Ip，
+// 否则客户端可以伪造这些请求头，绕过基于IP的限流和session的IP绑定
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// NewResolver cidrs 为可信代理的网段，也可以是单个IP（例如 10.0.0.0/8、127.0.0.1、::1）
+func NewResolver(cidrs []string) (*Resolver, error) {
+	r := &Resolver{trusted: make([]*net.IPNet, 0, len(cidrs))}
+	for _, s := range cidrs {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, errors.Errorf("invalid trusted proxy: %s", s)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			r.trusted = append(r.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, errors.Errorf("invalid trusted proxy: %s", s)
+		}
+		r.trusted = append(r.trusted, ipNet)
+	}
+	return r, nil
+}
+
+// Trusted ip 是否属于可信代理
+func (r *Resolver) Trusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range r.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP 返回请求的真实客户端IP
+// X-Forwarded-For 从右往左（离本服务最近的一跳开始）跳过可信代理，第一个不可信的地址即为客户端；
+// 左侧更早的地址可能由客户端伪造，不予采用
+func (r *Resolver) ClientIP(req *http.Request) string {
+	remote := remoteIP(req.RemoteAddr)
+	if !r.Trusted(remote) {
+		if remote == nil {
+			return strings.TrimSpace(req.RemoteAddr)
+		}
+		return remote.String()
+	}
+
+	if xff := req.Header.Get(headerForwardedFor); xff != "" {
+		hops := strings.Split(xff, ",")
+		var client net.IP
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				// 无法解析的地址之后的内容不可信，使用已经确认的最后一跳
+				break
+			}
+			client = ip
+			if !r.Trusted(ip) {
+				break
+			}
+		}
+		if client != nil {
+			return client.String()
+		}
+	}
+	if ip := net.ParseIP(strings.TrimSpace(req.Header.Get(headerRealIP))); ip != nil {
+		return ip.String()
+	}
+	return remote.String()
+}
+
+func remoteIP(remoteAddr string) net.IP {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(remoteAddr))
+	if err != nil {
+		host = remoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// 默认不信任任何代理，只使用 RemoteAddr
+var defaultResolver = &Resolver{}
+
+// InitClientIP 从配置中读取可信代理列表
+func InitClientIP() error {
+	r, err := NewResolver(conf.GetConf().TrustedProxies)
+	if err != nil {
+		return err
+	}
+	defaultResolver = r
+	return nil
+}
+
+// FromRequest 使用配置的可信代理列表解析客户端IP
+func FromRequest(req *http.Request) string {
+	return defaultResolver.ClientIP(req)
+}
@@ -0,0 +1,60 @@
+package clientip
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRequest(remoteAddr, xff, realIP string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	if xff != "" {
+		req.Header.Set(headerForwardedFor, xff)
+	}
+	if realIP != "" {
+		req.Header.Set(headerRealIP, realIP)
+	}
+	return req
+}
+
+func TestNewResolver(t *testing.T) {
+	r, err := NewResolver([]string{"10.0.0.0/8", "127.0.0.1", "::1", " 192.168.0.0/16 "})
+	assert.Nil(t, err)
+	assert.Len(t, r.trusted, 4)
+
+	_, err = NewResolver([]string{"not-an-ip"})
+	assert.NotNil(t, err)
+	_, err = NewResolver([]string{"10.0.0.0/99"})
+	assert.NotNil(t, err)
+}
+
+func TestClientIPUntrustedPeer(t *testing.T) {
+	r, _ := NewResolver([]string{"10.0.0.0/8"})
+
+	// 直接连接的客户端伪造请求头，忽略
+	assert.Equal(t, "203.0.113.7", r.ClientIP(newRequest("203.0.113.7:5000", "1.2.3.4", "")))
+	assert.Equal(t, "203.0.113.7", r.ClientIP(newRequest("203.0.113.7:5000", "", "1.2.3.4")))
+	assert.Equal(t, "2001:db8::1", r.ClientIP(newRequest("[2001:db8::1]:443", "1.2.3.4", "")))
+
+	// 未配置可信代理时，一律使用 RemoteAddr
+	assert.Equal(t, "10.0.0.1", (&Resolver{}).ClientIP(newRequest("10.0.0.1:5000", "1.2.3.4", "")))
+}
+
+func TestClientIPTrustedProxy(t *testing.T) {
+	r, _ := NewResolver([]string{"10.0.0.0/8"})
+
+	assert.Equal(t, "203.0.113.7", r.ClientIP(newRequest("10.0.0.1:5000", "203.0.113.7", "")))
+	// 多级可信代理
+	assert.Equal(t, "203.0.113.7", r.ClientIP(newRequest("10.0.0.1:5000", "203.0.113.7, 10.0.0.2", "")))
+	// 客户端在 X-Forwarded-For 中伪造的地址位于左侧，取最右侧不可信的一跳
+	assert.Equal(t, "203.0.113.7", r.ClientIP(newRequest("10.0.0.1:5000", "1.2.3.4, 203.0.113.7", "")))
+	assert.Equal(t, "203.0.113.7", r.ClientIP(newRequest("10.0.0.1:5000", "garbage, 203.0.113.7", "")))
+	// 全部是可信代理时，取最左侧
+	assert.Equal(t, "10.0.0.3", r.ClientIP(newRequest("10.0.0.1:5000", "10.0.0.3, 10.0.0.2", "")))
+	// 没有 X-Forwarded-For 时使用 X-Real-Ip
+	assert.Equal(t, "203.0.113.7", r.ClientIP(newRequest("10.0.0.1:5000", "", "203.0.113.7")))
+	// 都没有时使用代理地址
+	assert.Equal(t, "10.0.0.1", r.ClientIP(newRequest("10.0.0.1:5000", "", "")))
+}
@@ -3,10 +3,17 @@ package conf
 import (
 	"io/ioutil"
 	"log"
+	"net"
 	"net/url"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/growerlab/backend/app/common/errors"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/asaskevich/govalidator.v9"
 	"gopkg.in/yaml.v2"
 )
 
@@ -18,7 +25,10 @@ var (
 )
 
 type DB struct {
-	URL string `yaml:"url"`
+	URL        string `yaml:"url"`
+	ReplicaURL string `yaml:"replica_url"` // 只读副本，为空时读操作也使用主库
+	// AutoMigrate 启动时执行未执行的数据库迁移（migration目录），为false时需要另外执行
+	AutoMigrate bool `yaml:"auto_migrate"`
 }
 
 type Redis struct {
@@ -38,15 +48,405 @@ type Mensa struct {
 	HttpPort int    `yaml:"http_port"`
 }
 
+type Login struct {
+	MaxFailedAttempts   int `yaml:"max_failed_attempts"`   // 连续登录失败的次数上限，达到后锁定账号
+	FailedWindowSeconds int `yaml:"failed_window_seconds"` // 统计连续登录失败的时间窗口，第一次失败超出该时间后重新计数，0 时使用默认值
+	LockSeconds         int `yaml:"lock_seconds"`          // 账号锁定时长，单位s
+	IPFailedPerMinute   int `yaml:"ip_failed_per_minute"`  // 同一IP每分钟允许登录失败的次数（与账号锁定相互独立）
+	MaxSessions         int `yaml:"max_sessions"`          // 每个用户最多同时存在的session数量，超出时删除最早的session，<=0 时不限制
+	// StrictPrivacy 为true时，用户不存在、未激活、密码错误均返回同一个错误，避免通过登录接口探测账号状态
+	// 具体原因仍然记录在服务端日志中
+	StrictPrivacy bool `yaml:"strict_privacy"`
+	// 修改密码、注销账号等敏感操作再次确认密码时，每个用户在 ReauthWindowSeconds 内最多失败 ReauthMaxFailures 次，0 时使用默认值
+	ReauthMaxFailures   int `yaml:"reauth_max_failures"`
+	ReauthWindowSeconds int `yaml:"reauth_window_seconds"`
+}
+
+var defaultLogin = &Login{
+	MaxFailedAttempts:   5,
+	FailedWindowSeconds: 15 * 60,
+	LockSeconds:         15 * 60,
+	IPFailedPerMinute:   20,
+	MaxSessions:         10,
+	ReauthMaxFailures:   5,
+	ReauthWindowSeconds: 15 * 60,
+}
+
+// FailedWindow 统计连续登录失败的时间窗口，未配置时使用默认值
+func (l *Login) FailedWindow() time.Duration {
+	seconds := l.FailedWindowSeconds
+	if seconds == 0 {
+		seconds = defaultLogin.FailedWindowSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// ReauthLimit 再次确认密码的失败次数上限及其时间窗口，未配置时使用默认值
+func (l *Login) ReauthLimit() (maxFailures int, window time.Duration) {
+	maxFailures, seconds := l.ReauthMaxFailures, l.ReauthWindowSeconds
+	if maxFailures == 0 {
+		maxFailures = defaultLogin.ReauthMaxFailures
+	}
+	if seconds == 0 {
+		seconds = defaultLogin.ReauthWindowSeconds
+	}
+	return maxFailures, time.Duration(seconds) * time.Second
+}
+
+func (l *Login) validate() error {
+	values := map[string]int{
+		"max_failed_attempts":   l.MaxFailedAttempts,
+		"failed_window_seconds": l.FailedWindowSeconds,
+		"lock_seconds":          l.LockSeconds,
+		"ip_failed_per_minute":  l.IPFailedPerMinute,
+		"reauth_max_failures":   l.ReauthMaxFailures,
+		"reauth_window_seconds": l.ReauthWindowSeconds,
+	}
+	for _, key := range sortedKeys(values) {
+		if values[key] < 0 {
+			return errors.Errorf("login.%s must not be negative: %d", key, values[key])
+		}
+	}
+	return nil
+}
+
+// Session 登录session的有效期，0 时使用默认值
+type Session struct {
+	RememberTTLHours      int `yaml:"remember_ttl_hours"`      // 勾选“记住我”时的有效期
+	TTLHours              int `yaml:"ttl_hours"`               // 未勾选“记住我”时的有效期
+	RefreshThresholdHours int `yaml:"refresh_threshold_hours"` // “记住我”的session剩余有效期不足该值时自动延长
+	// TokenCacheSeconds token => 用户的缓存时间（见 user.UserTokenCache），小于0时不缓存
+	TokenCacheSeconds int `yaml:"token_cache_seconds"`
+}
+
+var defaultSession = &Session{
+	RememberTTLHours:      30 * 24,
+	TTLHours:              24,
+	RefreshThresholdHours: 7 * 24,
+	TokenCacheSeconds:     60,
+}
+
+// DefaultSession session的默认配置（未加载配置文件时使用，例如测试）
+func DefaultSession() *Session {
+	s := *defaultSession
+	return &s
+}
+
+// RememberTTL 勾选“记住我”时session的有效期
+func (s *Session) RememberTTL() time.Duration {
+	return hoursOrDefault(s.RememberTTLHours, defaultSession.RememberTTLHours)
+}
+
+// TTL 未勾选“记住我”时session的有效期
+func (s *Session) TTL() time.Duration {
+	return hoursOrDefault(s.TTLHours, defaultSession.TTLHours)
+}
+
+// RefreshThreshold 剩余有效期不足该值时自动延长
+func (s *Session) RefreshThreshold() time.Duration {
+	return hoursOrDefault(s.RefreshThresholdHours, defaultSession.RefreshThresholdHours)
+}
+
+// TokenCacheTTL token的缓存时间，为0时不缓存
+func (s *Session) TokenCacheTTL() time.Duration {
+	switch {
+	case s.TokenCacheSeconds < 0:
+		return 0
+	case s.TokenCacheSeconds == 0:
+		return time.Duration(defaultSession.TokenCacheSeconds) * time.Second
+	}
+	return time.Duration(s.TokenCacheSeconds) * time.Second
+}
+
+func hoursOrDefault(hours, def int) time.Duration {
+	if hours == 0 {
+		hours = def
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func (s *Session) validate() error {
+	if s.RememberTTLHours < 0 || s.TTLHours < 0 || s.RefreshThresholdHours < 0 {
+		return errors.New("session.remember_ttl_hours, ttl_hours and refresh_threshold_hours must be positive")
+	}
+	if s.TTL() > s.RememberTTL() {
+		return errors.Errorf("session.ttl_hours (%s) must not exceed remember_ttl_hours (%s)", s.TTL(), s.RememberTTL())
+	}
+	// 否则每个请求都会延长session
+	if s.RefreshThreshold() >= s.RememberTTL() {
+		return errors.Errorf("session.refresh_threshold_hours (%s) must be less than remember_ttl_hours (%s)",
+			s.RefreshThreshold(), s.RememberTTL())
+	}
+	return nil
+}
+
+// Cookie 登录token的cookie，Domain 为空时只对当前域名有效
+// API与网页位于不同子域名时，可以设置为上级域名（例如 .example.com）以共享登录状态
+type Cookie struct {
+	Name   string `yaml:"name"`
+	Domain string `yaml:"domain"`
+}
+
+const DefaultCookieName = "auth-user-token"
+
+// cookieNameRegex RFC 6265 cookie-name（token）允许的字符
+var cookieNameRegex = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+\\-.^_`|~]+$")
+
+func (c *Cookie) validate() error {
+	if !cookieNameRegex.MatchString(c.Name) {
+		return errors.Errorf("cookie.name '%s' is invalid", c.Name)
+	}
+	if len(c.Domain) == 0 {
+		return nil
+	}
+	domain := strings.TrimPrefix(c.Domain, ".")
+	if !govalidator.IsDNSName(domain) || (!strings.Contains(domain, ".") && domain != "localhost") {
+		return errors.Errorf("cookie.domain '%s' is invalid", c.Domain)
+	}
+	return nil
+}
+
+// BodyLimit 请求体大小的上限（字节），在解析JSON之前检查，<=0 时不限制
+type BodyLimit struct {
+	Auth int64 `yaml:"auth"` // 登录、注册等 /auth 接口
+}
+
+var defaultBodyLimit = &BodyLimit{
+	Auth: 4 << 10,
+}
+
+// Password 新密码使用的哈希算法，已保存的密码在下次登录时自动升级
+type Password struct {
+	Algorithm string `yaml:"algorithm"` // argon2id（默认）或 bcrypt
+	Cost      int    `yaml:"cost"`      // argon2id 为迭代次数，bcrypt 为 cost；0 时使用默认值
+}
+
+// 各算法允许的 cost 范围，过大的值会使每次登录都非常慢
+var passwordCostRange = map[string][2]int{
+	"argon2id": {1, 64},
+	"bcrypt":   {bcrypt.MinCost, bcrypt.MaxCost},
+}
+
+func (p *Password) validate() error {
+	algorithm := p.Algorithm
+	if len(algorithm) == 0 {
+		algorithm = "argon2id"
+	}
+	costRange, ok := passwordCostRange[algorithm]
+	if !ok {
+		return errors.Errorf("password.algorithm '%s' is unknown", p.Algorithm)
+	}
+	if p.Cost != 0 && (p.Cost < costRange[0] || p.Cost > costRange[1]) {
+		return errors.Errorf("password.cost %d is out of range [%d, %d] for %s",
+			p.Cost, costRange[0], costRange[1], algorithm)
+	}
+	return nil
+}
+
+type Account struct {
+	RestoreGraceDays   int `yaml:"restore_grace_days"`   // 注销（软删除）后允许管理员恢复账号的天数
+	ActivationTTLHours int `yaml:"activation_ttl_hours"` // 激活链接的有效期，单位小时，<=0 时使用默认值（24小时）
+	// StaffSkipVerified 为true时，管理后台的用户（拥有角色）不受 RequireVerified 的限制
+	StaffSkipVerified bool `yaml:"staff_skip_verified"`
+	// UsernameCooldownDays 两次修改用户名的最小间隔（天），<=0 时不限制
+	UsernameCooldownDays int `yaml:"username_cooldown_days"`
+	// UsernameQuarantineDays 修改用户名后，旧用户名保留给原用户的天数，期间其他用户不能使用，<=0 时立即释放
+	UsernameQuarantineDays int `yaml:"username_quarantine_days"`
+	// ReservedNamespaces 除默认的保留字外，额外不允许用作用户名、组织名的path（不区分大小写）
+	ReservedNamespaces []string `yaml:"reserved_namespaces"`
+}
+
+var defaultAccount = &Account{
+	RestoreGraceDays:       30,
+	ActivationTTLHours:     24,
+	UsernameCooldownDays:   30,
+	UsernameQuarantineDays: 30,
+}
+
+func (a *Account) validate() error {
+	if a.RestoreGraceDays < 0 {
+		return errors.Errorf("account.restore_grace_days must not be negative: %d", a.RestoreGraceDays)
+	}
+	return nil
+}
+
+// Webhook 用户事件（注册、激活、登录、注销）的推送地址，URL为空时不推送
+type Webhook struct {
+	URL    string `yaml:"url"`
+	Secret string `yaml:"secret"` // 用于HMAC-SHA256签名，接收方通过签名验证请求来源
+}
+
+// Metrics prometheus 指标只在内部地址上提供（不经过对外的API服务），Addr 为空时不提供
+type Metrics struct {
+	Addr string `yaml:"addr"` // 例如 127.0.0.1:9100
+}
+
+// Enabled 是否提供 /metrics
+func (m *Metrics) Enabled() bool {
+	return m != nil && len(m.Addr) > 0
+}
+
+func (m *Metrics) validate() error {
+	if !m.Enabled() {
+		return nil
+	}
+	if _, port, err := net.SplitHostPort(m.Addr); err != nil || len(port) == 0 {
+		return errors.Errorf("metrics.addr '%s' must be host:port", m.Addr)
+	}
+	return nil
+}
+
+// OAuthProvider 第三方登录（OAuth2）的配置，ClientID 为空时不启用
+// RedirectURL 必须与在第三方注册的回调地址完全一致（通常是前端的回调页面，由前端将 code、state 提交到 /auth/oauth/:provider/callback）
+type OAuthProvider struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	RedirectURL  string `yaml:"redirect_url"`
+}
+
+// Enabled 是否启用
+func (p *OAuthProvider) Enabled() bool {
+	return p != nil && len(p.ClientID) > 0
+}
+
+// oauthProviders 支持的第三方登录
+var oauthProviders = map[string]struct{}{
+	"github": {},
+	"google": {},
+}
+
+func validateOAuth(providers map[string]*OAuthProvider) error {
+	for _, name := range sortedKeys(providers) {
+		if _, ok := oauthProviders[name]; !ok {
+			return errors.Errorf("oauth.%s is not a supported provider", name)
+		}
+		p := providers[name]
+		if !p.Enabled() {
+			continue
+		}
+		if len(p.ClientSecret) == 0 {
+			return errors.Errorf("oauth.%s.client_secret is required", name)
+		}
+		if u, err := url.Parse(p.RedirectURL); err != nil || !u.IsAbs() {
+			return errors.Errorf("oauth.%s.redirect_url '%s' must be an absolute url", name, p.RedirectURL)
+		}
+	}
+	return nil
+}
+
+// EmailNormalizeRule 指定邮箱服务商的地址归一化规则（例如gmail忽略 +tag 和 .）
+// 这类规则因服务商而异，所以只对配置了的域名生效
+type EmailNormalizeRule struct {
+	Domains         []string `yaml:"domains"`
+	CanonicalDomain string   `yaml:"canonical_domain"` // 非空时，将 Domains 统一替换为该域名（例如 googlemail.com => gmail.com）
+	StripPlus       bool     `yaml:"strip_plus"`       // 去掉 + 及之后的部分
+	StripDots       bool     `yaml:"strip_dots"`       // 去掉 .
+}
+
 type Config struct {
 	Debug      bool   `yaml:"debug"`
 	WebsiteURL string `yaml:"website_url"`
 	websiteURL *url.URL
+	SecretKey  string `yaml:"secret_key"` // 用于加密保存到数据库中的敏感数据，生产环境必须修改
+	// BehindTLSProxy 服务位于TLS终止的反向代理之后（请求本身是HTTP），此时cookie同样需要设置Secure
+	BehindTLSProxy bool `yaml:"behind_tls_proxy"`
+	// TrustedProxies 可信的反向代理（CIDR或IP），只有来自这些地址的请求才读取 X-Forwarded-For，为空时只使用连接的对端地址
+	TrustedProxies []string `yaml:"trusted_proxies"`
+
+	Port     int       `yaml:"port"`
+	Database *DB       `yaml:"db"`
+	Redis    *Redis    `yaml:"redis"`
+	Mensa    *Mensa    `yaml:"mensa"`
+	Login    *Login    `yaml:"login"`
+	Session  *Session  `yaml:"session"`
+	Account  *Account  `yaml:"account"`
+	Password *Password `yaml:"password"`
+	Cookie   *Cookie   `yaml:"cookie"`
+
+	BodyLimit *BodyLimit `yaml:"body_limit"`
+
+	EmailNormalize []*EmailNormalizeRule `yaml:"email_normalize"`
+	Webhook        *Webhook              `yaml:"webhook"`
+	Metrics        *Metrics              `yaml:"metrics"`
+	// OAuth 第三方登录，key 为 github 或 google
+	OAuth map[string]*OAuthProvider `yaml:"oauth"`
+}
+
+// GetLogin 登录相关的配置，未配置时使用默认值
+func (c *Config) GetLogin() *Login {
+	if c.Login == nil {
+		return defaultLogin
+	}
+	return c.Login
+}
+
+// GetSession session相关的配置，未配置时使用默认值
+func (c *Config) GetSession() *Session {
+	if c.Session == nil {
+		return defaultSession
+	}
+	return c.Session
+}
+
+// GetAccount 账号相关的配置，未配置时使用默认值
+func (c *Config) GetAccount() *Account {
+	if c.Account == nil {
+		return defaultAccount
+	}
+	return c.Account
+}
+
+// GetBodyLimit 请求体大小的上限，未配置时使用默认值
+func (c *Config) GetBodyLimit() *BodyLimit {
+	if c.BodyLimit == nil {
+		return defaultBodyLimit
+	}
+	return c.BodyLimit
+}
+
+// GetCookie 登录cookie的配置，未配置名称时使用 DefaultCookieName
+func (c *Config) GetCookie() *Cookie {
+	if c.Cookie == nil {
+		return &Cookie{Name: DefaultCookieName}
+	}
+	if len(c.Cookie.Name) == 0 {
+		return &Cookie{Name: DefaultCookieName, Domain: c.Cookie.Domain}
+	}
+	return c.Cookie
+}
+
+// validate 启动时检查配置，尽早发现错误（例如拼写错误的cookie域名、超出范围的密码cost）
+// 任意一项不合法时，服务不会启动
+func (c *Config) validate() error {
+	if c.Port < 0 || c.Port > 65535 {
+		return errors.Errorf("port %d is out of range", c.Port)
+	}
+	validators := []func() error{
+		c.GetCookie().validate,
+		c.GetLogin().validate,
+		c.GetSession().validate,
+		c.GetAccount().validate,
+		c.Metrics.validate,
+		func() error { return validateOAuth(c.OAuth) },
+	}
+	if c.Password != nil {
+		validators = append(validators, c.Password.validate)
+	}
+	for _, v := range validators {
+		if err := v(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	Port     int    `yaml:"port"`
-	Database *DB    `yaml:"db"`
-	Redis    *Redis `yaml:"redis"`
-	Mensa    *Mensa `yaml:"mensa"`
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func (c *Config) EnableHTTPS() bool {
@@ -87,6 +487,10 @@ func LoadConfig() error {
 		return errors.Errorf("config for env '%s'", env)
 	}
 
+	if err = config.validate(); err != nil {
+		return err
+	}
+
 	log.Println("Loaded config for env:", env)
 	return nil
 }
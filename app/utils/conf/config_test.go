@@ -0,0 +1,106 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookieValidate(t *testing.T) {
+	valid := []*Cookie{
+		{Name: DefaultCookieName},
+		{Name: DefaultCookieName, Domain: ".example.com"},
+		{Name: "token", Domain: "api.example.com"},
+		{Name: "token", Domain: "localhost"},
+	}
+	for _, c := range valid {
+		assert.Nil(t, c.validate(), c.Domain)
+	}
+
+	invalid := []*Cookie{
+		{Name: ""},
+		{Name: "auth token"},
+		{Name: "a;b"},
+		{Name: "token", Domain: "https://example.com"},
+		{Name: "token", Domain: "example.com:8080"},
+		{Name: "token", Domain: "example"},
+		{Name: "token", Domain: "exa mple.com"},
+	}
+	for _, c := range invalid {
+		assert.NotNil(t, c.validate(), c.Name+" "+c.Domain)
+	}
+}
+
+func TestGetCookie(t *testing.T) {
+	assert.Equal(t, DefaultCookieName, (&Config{}).GetCookie().Name)
+	c := (&Config{Cookie: &Cookie{Domain: ".example.com"}}).GetCookie()
+	assert.Equal(t, DefaultCookieName, c.Name)
+	assert.Equal(t, ".example.com", c.Domain)
+}
+
+func TestSessionDefaults(t *testing.T) {
+	s := (&Config{}).GetSession()
+	assert.Equal(t, 30*24*time.Hour, s.RememberTTL())
+	assert.Equal(t, 24*time.Hour, s.TTL())
+	assert.Equal(t, 7*24*time.Hour, s.RefreshThreshold())
+	assert.Equal(t, 60*time.Second, s.TokenCacheTTL())
+
+	// 未配置的项使用默认值
+	s = &Session{TTLHours: 2, TokenCacheSeconds: -1}
+	assert.Equal(t, 30*24*time.Hour, s.RememberTTL())
+	assert.Equal(t, 2*time.Hour, s.TTL())
+	assert.Zero(t, s.TokenCacheTTL())
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.Nil(t, (&Config{}).validate())
+	assert.Nil(t, (&Config{Password: &Password{Algorithm: "bcrypt", Cost: 12}}).validate())
+	assert.Nil(t, (&Config{Password: &Password{Cost: 3}}).validate())
+	assert.Nil(t, (&Config{OAuth: map[string]*OAuthProvider{
+		"github": {ClientID: "id", ClientSecret: "secret", RedirectURL: "https://example.com/oauth/github"},
+		"google": {}, // 未启用
+	}}).validate())
+	assert.Nil(t, (&Config{Metrics: &Metrics{Addr: "127.0.0.1:9100"}}).validate())
+	assert.Nil(t, (&Config{Metrics: &Metrics{}}).validate())
+
+	invalid := []*Config{
+		{Port: -1},
+		{Port: 70000},
+		{Password: &Password{Algorithm: "md5"}},
+		{Password: &Password{Algorithm: "bcrypt", Cost: 3}},
+		{Password: &Password{Algorithm: "bcrypt", Cost: 32}},
+		{Password: &Password{Cost: 65}},
+		{Session: &Session{TTLHours: -1}},
+		{Session: &Session{RememberTTLHours: 12, TTLHours: 24}},
+		{Session: &Session{RememberTTLHours: 24, RefreshThresholdHours: 24}},
+		{Login: &Login{LockSeconds: -1}},
+		{Login: &Login{FailedWindowSeconds: -1}},
+		{Login: &Login{ReauthMaxFailures: -5}},
+		{Account: &Account{RestoreGraceDays: -1}},
+		{Cookie: &Cookie{Name: "a b"}},
+		{Metrics: &Metrics{Addr: "127.0.0.1"}},
+		{Metrics: &Metrics{Addr: "127.0.0.1:"}},
+		{OAuth: map[string]*OAuthProvider{"gitlab": {}}},
+		{OAuth: map[string]*OAuthProvider{"github": {ClientID: "id", RedirectURL: "https://example.com/cb"}}},
+		{OAuth: map[string]*OAuthProvider{"google": {ClientID: "id", ClientSecret: "secret", RedirectURL: "/cb"}}},
+	}
+	for i, c := range invalid {
+		assert.NotNil(t, c.validate(), i)
+	}
+}
+
+func TestFailedWindow(t *testing.T) {
+	assert.Equal(t, 15*time.Minute, (&Login{}).FailedWindow())
+	assert.Equal(t, time.Hour, (&Login{FailedWindowSeconds: 3600}).FailedWindow())
+}
+
+func TestReauthLimit(t *testing.T) {
+	maxFailures, window := (&Login{}).ReauthLimit()
+	assert.Equal(t, 5, maxFailures)
+	assert.Equal(t, 15*time.Minute, window)
+
+	maxFailures, window = (&Login{ReauthMaxFailures: 3, ReauthWindowSeconds: 60}).ReauthLimit()
+	assert.Equal(t, 3, maxFailures)
+	assert.Equal(t, time.Minute, window)
+}
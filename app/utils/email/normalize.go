@@ -0,0 +1,55 @@
+package email
+
+import (
+	"strings"
+
+	"github.com/growerlab/backend/app/utils/conf"
+)
+
+// Normalize 邮箱地址的归一化形式，仅用于唯一性检查（发送邮件时使用原始地址）
+// 全部转为小写，并对配置中 email_normalize 指定的服务商应用对应的规则，
+// 例如 User.Name+spam@Gmail.com => username@gmail.com
+func Normalize(address string) string {
+	var rules []*conf.EmailNormalizeRule
+	if c := conf.GetConf(); c != nil {
+		rules = c.EmailNormalize
+	}
+	return NormalizeWithRules(address, rules)
+}
+
+func NormalizeWithRules(address string, rules []*conf.EmailNormalizeRule) string {
+	address = strings.ToLower(strings.TrimSpace(address))
+	at := strings.LastIndex(address, "@")
+	if at <= 0 {
+		return address
+	}
+	local, domain := address[:at], address[at+1:]
+
+	rule := findRule(domain, rules)
+	if rule == nil {
+		return address
+	}
+	if rule.StripPlus {
+		if i := strings.Index(local, "+"); i > 0 {
+			local = local[:i]
+		}
+	}
+	if rule.StripDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	if len(rule.CanonicalDomain) > 0 {
+		domain = strings.ToLower(rule.CanonicalDomain)
+	}
+	return local + "@" + domain
+}
+
+func findRule(domain string, rules []*conf.EmailNormalizeRule) *conf.EmailNormalizeRule {
+	for _, r := range rules {
+		for _, d := range r.Domains {
+			if strings.EqualFold(d, domain) {
+				return r
+			}
+		}
+	}
+	return nil
+}
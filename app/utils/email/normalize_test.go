@@ -0,0 +1,36 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/growerlab/backend/app/utils/conf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeWithRules(t *testing.T) {
+	rules := []*conf.EmailNormalizeRule{
+		{
+			Domains:         []string{"gmail.com", "googlemail.com"},
+			CanonicalDomain: "gmail.com",
+			StripPlus:       true,
+			StripDots:       true,
+		},
+	}
+
+	cases := map[string]string{
+		"user@gmail.com":           "user@gmail.com",
+		"User+spam@Gmail.com":      "user@gmail.com",
+		"u.s.e.r@googlemail.com":   "user@gmail.com",
+		" user+a+b@gmail.com ":     "user@gmail.com",
+		"first.last+x@example.com": "first.last+x@example.com",
+		"First.Last@Example.COM":   "first.last@example.com",
+		"+tag@gmail.com":           "+tag@gmail.com",
+		"invalid":                  "invalid",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, NormalizeWithRules(in, rules), in)
+	}
+
+	// 未配置规则时只转为小写
+	assert.Equal(t, "user+spam@gmail.com", NormalizeWithRules("User+spam@Gmail.com", nil))
+}
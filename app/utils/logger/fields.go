@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	FieldRequestID = "request_id"
+	FieldUserID    = "user_id"
+
+	ctxEntryKey = "growerlab-log-entry"
+)
+
+// Fields 结构化日志的字段
+type Fields map[string]interface{}
+
+// Sink 结构化日志的输出，默认以 key=value 的形式写入 LogWriter
+// 测试中可以替换，以断言输出的字段
+var Sink = writeFields
+
+// Entry 带有字段的日志（例如请求范围内的 request_id、user_id）
+type Entry struct {
+	fields Fields
+}
+
+func WithFields(fields Fields) *Entry {
+	return (&Entry{}).WithFields(fields)
+}
+
+// WithFields 返回新的 Entry，不修改原有的字段
+func (e *Entry) WithFields(fields Fields) *Entry {
+	merged := make(Fields, len(e.fields)+len(fields))
+	for k, v := range e.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Entry{fields: merged}
+}
+
+func (e *Entry) With(key string, value interface{}) *Entry {
+	return e.WithFields(Fields{key: value})
+}
+
+func (e *Entry) Info(format string, a ...interface{}) {
+	Sink("[Info]", fmt.Sprintf(format, a...), e.fields)
+}
+
+func (e *Entry) Warn(format string, a ...interface{}) {
+	Sink("[Warn]", fmt.Sprintf(format, a...), e.fields)
+}
+
+func (e *Entry) Error(format string, a ...interface{}) {
+	Sink("[Error]", fmt.Sprintf(format, a...), e.fields)
+}
+
+// Ctx 当前请求的日志（见 controller.RequestID 中间件），不在请求中时不带字段
+func Ctx(c *gin.Context) *Entry {
+	if c != nil {
+		if v, ok := c.Get(ctxEntryKey); ok {
+			return v.(*Entry)
+		}
+	}
+	return &Entry{}
+}
+
+// AddCtxFields 为当前请求之后的日志添加字段（例如解析出当前用户后添加 user_id）
+func AddCtxFields(c *gin.Context, fields Fields) {
+	c.Set(ctxEntryKey, Ctx(c).WithFields(fields))
+}
+
+func writeFields(level, msg string, fields Fields) {
+	var sb = strings.Builder{}
+	_, _ = sb.WriteString(level + " ")
+	_, _ = sb.WriteString(msg)
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(&sb, " %s=%v", k, fields[k])
+	}
+	_ = sb.WriteByte('\n')
+
+	_, _ = io.WriteString(LogWriter, sb.String())
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryFields(t *testing.T) {
+	base := WithFields(Fields{FieldRequestID: "r1"})
+	child := base.With(FieldUserID, int64(1))
+
+	var got Fields
+	old := Sink
+	defer func() { Sink = old }()
+	Sink = func(level, msg string, fields Fields) { got = fields }
+
+	child.Info("login")
+	assert.Equal(t, Fields{FieldRequestID: "r1", FieldUserID: int64(1)}, got)
+
+	// 不影响原有的 Entry
+	base.Info("login")
+	assert.Equal(t, Fields{FieldRequestID: "r1"}, got)
+}
+
+func TestCtxFields(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Empty(t, Ctx(c).fields)
+
+	AddCtxFields(c, Fields{FieldRequestID: "r1"})
+	AddCtxFields(c, Fields{FieldUserID: 2})
+	assert.Equal(t, Fields{FieldRequestID: "r1", FieldUserID: 2}, Ctx(c).fields)
+}
+
+func TestWriteFields(t *testing.T) {
+	var buf bytes.Buffer
+	old := LogWriter
+	defer func() { LogWriter = old }()
+	LogWriter = &buf
+
+	WithFields(Fields{"b": 2, "a": "x"}).Warn("login failed: %s", "bad_password")
+	assert.Equal(t, "[Warn] login failed: bad_password a=x b=2\n", buf.String())
+}
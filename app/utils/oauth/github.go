@@ -0,0 +1,73 @@
+package oauth
+
+import (
+	"context"
+	"strconv"
+)
+
+// GitHub 使用 /user 获取用户信息，/user/emails 获取已验证的主邮箱（用户可能没有公开邮箱）
+type GitHub struct {
+	Config
+	APIURL string
+}
+
+func NewGitHub(clientID, clientSecret, redirectURL string) *GitHub {
+	return &GitHub{
+		Config: Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			AuthURL:      "https://github.com/login/oauth/authorize",
+			TokenURL:     "https://github.com/login/oauth/access_token",
+			Scopes:       []string{"read:user", "user:email"},
+		},
+		APIURL: "https://api.github.com",
+	}
+}
+
+func (g *GitHub) Name() string {
+	return ProviderGitHub
+}
+
+type githubUser struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+func (g *GitHub) Exchange(ctx context.Context, code string) (*Identity, error) {
+	token, err := g.exchangeToken(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	var user githubUser
+	if err = g.getJSON(ctx, g.APIURL+"/user", token, &user); err != nil {
+		return nil, err
+	}
+	var emails []*githubEmail
+	if err = g.getJSON(ctx, g.APIURL+"/user/emails", token, &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Provider: ProviderGitHub,
+		ID:       strconv.FormatInt(user.ID, 10),
+		Login:    user.Login,
+		Name:     user.Name,
+	}
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = e.Email
+			identity.EmailVerified = e.Verified
+			break
+		}
+	}
+	return identity, nil
+}
@@ -0,0 +1,55 @@
+package oauth
+
+import (
+	"context"
+)
+
+// Google 使用 OpenID Connect 的 userinfo 接口获取用户信息
+type Google struct {
+	Config
+	UserInfoURL string
+}
+
+func NewGoogle(clientID, clientSecret, redirectURL string) *Google {
+	return &Google{
+		Config: Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+			TokenURL:     "https://oauth2.googleapis.com/token",
+			Scopes:       []string{"openid", "email", "profile"},
+		},
+		UserInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+	}
+}
+
+func (g *Google) Name() string {
+	return ProviderGoogle
+}
+
+type googleUser struct {
+	Sub           string `json:"sub"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+func (g *Google) Exchange(ctx context.Context, code string) (*Identity, error) {
+	token, err := g.exchangeToken(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	var user googleUser
+	if err = g.getJSON(ctx, g.UserInfoURL, token, &user); err != nil {
+		return nil, err
+	}
+	return &Identity{
+		Provider:      ProviderGoogle,
+		ID:            user.Sub,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+	}, nil
+}
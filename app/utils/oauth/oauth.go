@@ -0,0 +1,158 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/growerlab/backend/app/common/errors"
+)
+
+const (
+	ProviderGitHub = "github"
+	ProviderGoogle = "google"
+
+	requestTimeout = 10 * time.Second
+	// maxResponseSize 第三方接口响应体的上限
+	maxResponseSize = 1 << 20
+)
+
+var httpClient = &http.Client{Timeout: requestTimeout}
+
+// Identity 第三方账号的用户信息
+type Identity struct {
+	Provider      string
+	ID            string // 第三方的用户id（github为数字id，google为sub），不会因改名、改邮箱而变化
+	Login         string // 第三方的用户名，google为空
+	Name          string
+	Email         string
+	EmailVerified bool // 第三方已验证该邮箱
+}
+
+// Provider OAuth2（授权码模式）的第三方登录
+type Provider interface {
+	Name() string
+	// AuthCodeURL 引导用户授权的地址，state 由调用者生成并在回调时校验
+	AuthCodeURL(state string) string
+	// Exchange 使用回调中的 code 换取 access token，并读取用户信息
+	Exchange(ctx context.Context, code string) (*Identity, error)
+}
+
+// Error 第三方返回的错误（例如 code 无效或已过期）
+type Error struct {
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	if len(e.Description) > 0 {
+		return fmt.Sprintf("oauth: %s: %s", e.Code, e.Description)
+	}
+	return "oauth: " + e.Code
+}
+
+// Config 各 Provider 通用的 OAuth2 配置
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	AuthURL      string
+	TokenURL     string
+	Scopes       []string
+}
+
+func (c *Config) AuthCodeURL(state string) string {
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", c.ClientID)
+	v.Set("redirect_uri", c.RedirectURL)
+	v.Set("scope", strings.Join(c.Scopes, " "))
+	v.Set("state", state)
+	sep := "?"
+	if strings.Contains(c.AuthURL, "?") {
+		sep = "&"
+	}
+	return c.AuthURL + sep + v.Encode()
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchangeToken 使用 code 换取 access token
+// github 在出错时仍然返回200，所以以响应中的 error 字段为准
+func (c *Config) exchangeToken(ctx context.Context, code string) (string, error) {
+	v := url.Values{}
+	v.Set("grant_type", "authorization_code")
+	v.Set("code", code)
+	v.Set("client_id", c.ClientID)
+	v.Set("client_secret", c.ClientSecret)
+	v.Set("redirect_uri", c.RedirectURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&token); err != nil {
+		return "", errors.Trace(fmt.Errorf("oauth token endpoint responded with status %d: %v", resp.StatusCode, err))
+	}
+	if len(token.Error) > 0 {
+		return "", &Error{Code: token.Error, Description: token.ErrorDescription}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || len(token.AccessToken) == 0 {
+		return "", errors.Trace(fmt.Errorf("oauth token endpoint responded with status %d", resp.StatusCode))
+	}
+	return token.AccessToken, nil
+}
+
+// getJSON 使用 access token 请求第三方接口，并解析JSON响应
+func (c *Config) getJSON(ctx context.Context, rawURL, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Trace(fmt.Errorf("%s responded with status %d", rawURL, resp.StatusCode))
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// GenerateState 生成随机的 state，用于防止CSRF（回调时必须与发起授权时的值相同）
+func GenerateState() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Trace(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,143 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthCodeURL(t *testing.T) {
+	g := NewGitHub("cid", "secret", "http://localhost/callback")
+	u, err := url.Parse(g.AuthCodeURL("xyz"))
+	assert.Nil(t, err)
+	assert.Equal(t, "github.com", u.Host)
+	q := u.Query()
+	assert.Equal(t, "cid", q.Get("client_id"))
+	assert.Equal(t, "http://localhost/callback", q.Get("redirect_uri"))
+	assert.Equal(t, "read:user user:email", q.Get("scope"))
+	assert.Equal(t, "xyz", q.Get("state"))
+	assert.Empty(t, q.Get("client_secret"))
+}
+
+// newGitHubServer 模拟 github 的token与用户接口，code 不为 good 时返回错误（与github相同，状态码仍为200）
+func newGitHubServer(t *testing.T, emails []*githubEmail) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "secret", r.FormValue("client_secret"))
+		if r.FormValue("code") != "good" {
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "tok"})
+	})
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			json.NewEncoder(w).Encode(&githubUser{ID: 42, Login: "moli", Name: "Mo Li"})
+		}
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			json.NewEncoder(w).Encode(emails)
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func newTestGitHub(server *httptest.Server) *GitHub {
+	g := NewGitHub("cid", "secret", "http://localhost/callback")
+	g.TokenURL = server.URL + "/login/oauth/access_token"
+	g.APIURL = server.URL
+	return g
+}
+
+func TestGitHubExchange(t *testing.T) {
+	server := newGitHubServer(t, []*githubEmail{
+		{Email: "other@example.com", Verified: true},
+		{Email: "moli@example.com", Primary: true, Verified: true},
+	})
+	defer server.Close()
+
+	identity, err := newTestGitHub(server).Exchange(context.Background(), "good")
+	assert.Nil(t, err)
+	assert.Equal(t, &Identity{
+		Provider:      ProviderGitHub,
+		ID:            "42",
+		Login:         "moli",
+		Name:          "Mo Li",
+		Email:         "moli@example.com",
+		EmailVerified: true,
+	}, identity)
+
+	_, err = newTestGitHub(server).Exchange(context.Background(), "bad")
+	oauthErr, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, "bad_verification_code", oauthErr.Code)
+}
+
+func TestGitHubUnverifiedEmail(t *testing.T) {
+	server := newGitHubServer(t, []*githubEmail{{Email: "moli@example.com", Primary: true}})
+	defer server.Close()
+
+	identity, err := newTestGitHub(server).Exchange(context.Background(), "good")
+	assert.Nil(t, err)
+	assert.Equal(t, "moli@example.com", identity.Email)
+	assert.False(t, identity.EmailVerified)
+}
+
+func TestGoogleExchange(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "Bad Request"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "tok"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(&googleUser{Sub: "1001", Name: "Mo Li", Email: "moli@gmail.com", EmailVerified: true})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	g := NewGoogle("cid", "secret", "http://localhost/callback")
+	g.TokenURL = server.URL + "/token"
+	g.UserInfoURL = server.URL + "/userinfo"
+
+	identity, err := g.Exchange(context.Background(), "good")
+	assert.Nil(t, err)
+	assert.Equal(t, &Identity{
+		Provider:      ProviderGoogle,
+		ID:            "1001",
+		Name:          "Mo Li",
+		Email:         "moli@gmail.com",
+		EmailVerified: true,
+	}, identity)
+
+	_, err = g.Exchange(context.Background(), "bad")
+	oauthErr, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, "invalid_grant", oauthErr.Code)
+}
+
+func TestGenerateState(t *testing.T) {
+	a, err := GenerateState()
+	assert.Nil(t, err)
+	b, _ := GenerateState()
+	assert.Len(t, a, 43)
+	assert.NotEqual(t, a, b)
+}
@@ -1,25 +1,156 @@
 package pwd
 
 import (
+	"strings"
+	"sync"
+
 	"github.com/growerlab/argon2"
 	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/utils/conf"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 密码的哈希算法
+// 保存的密码自带算法前缀（argon2id: $argon2id$...，bcrypt: $2a$...），ComparePassword 根据前缀选择验证方式
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
 )
 
+// Algorithm 新密码使用的算法
+// 修改后，其他算法的密码会在用户下次登录时重新生成（见 NeedsRehashForConfig）
+var Algorithm = AlgorithmArgon2id
+
 var argon2Cfg = argon2.DefaultConfig()
 
+// Cost 生成密码时 argon2 的迭代次数（TimeCost）
+// 调高后，旧密码会在用户下次登录时重新生成（见 NeedsRehashForConfig），不需要用户重置密码
+var Cost = int(argon2Cfg.TimeCost)
+
+// BcryptCost 算法为 bcrypt 时使用的 cost
+var BcryptCost = bcrypt.DefaultCost
+
+// InitPassword 从配置中读取密码算法，未配置时使用默认值（argon2id）
+func InitPassword() error {
+	cfg := conf.GetConf().Password
+	if cfg == nil {
+		return nil
+	}
+	switch cfg.Algorithm {
+	case "", AlgorithmArgon2id:
+		Algorithm = AlgorithmArgon2id
+		if cfg.Cost > 0 {
+			Cost = cfg.Cost
+		}
+	case AlgorithmBcrypt:
+		Algorithm = AlgorithmBcrypt
+		if cfg.Cost > 0 {
+			BcryptCost = cfg.Cost
+		}
+	default:
+		return errors.Errorf("unknown password algorithm: %s", cfg.Algorithm)
+	}
+	return nil
+}
+
+// HashPassword 使用当前配置的算法生成密码
+func HashPassword(plain string) (string, error) {
+	switch Algorithm {
+	case AlgorithmBcrypt:
+		raw, err := bcrypt.GenerateFromPassword([]byte(plain), BcryptCost)
+		return string(raw), errors.Trace(err)
+	default:
+		cfg := argon2Cfg
+		cfg.TimeCost = uint32(Cost)
+		raw, err := cfg.Hash([]byte(plain), nil)
+		return string(raw.Encode()), errors.Trace(err)
+	}
+}
+
+// GeneratePassword 同 HashPassword
 func GeneratePassword(src string) (pwd string, err error) {
-	raw, err := argon2Cfg.Hash([]byte(src), nil)
-	return string(raw.Encode()), errors.Trace(err)
+	return HashPassword(src)
 }
 
 func ComparePassword(hashedPwd string, inputPwd string) bool {
-	raw, err := argon2.Decode([]byte(hashedPwd))
-	if err != nil {
+	switch algorithmOf(hashedPwd) {
+	case AlgorithmArgon2id:
+		raw, err := argon2.Decode([]byte(hashedPwd))
+		if err != nil {
+			return false
+		}
+		b, err := raw.Verify([]byte(inputPwd))
+		if err != nil {
+			return false
+		}
+		return b
+	case AlgorithmBcrypt:
+		return bcrypt.CompareHashAndPassword([]byte(hashedPwd), []byte(inputPwd)) == nil
+	default:
 		return false
 	}
-	b, err := raw.Verify([]byte(inputPwd))
-	if err != nil {
+}
+
+// algorithmOf 根据前缀判断已保存的密码使用的算法，无法识别时返回空字符串
+// argon2 的其他变体（argon2i）同样由 argon2 验证
+func algorithmOf(hashed string) string {
+	switch {
+	case strings.HasPrefix(hashed, "$argon2"):
+		return AlgorithmArgon2id
+	case strings.HasPrefix(hashed, "$2a$"), strings.HasPrefix(hashed, "$2b$"), strings.HasPrefix(hashed, "$2y$"):
+		return AlgorithmBcrypt
+	default:
+		return ""
+	}
+}
+
+var dummyHash string
+var dummyHashOnce sync.Once
+
+// DummyCompare 与一个固定的密码做一次比较，结果总是被忽略
+// 用户不存在时调用，使响应时间与用户存在时接近，避免通过耗时判断账号是否存在
+func DummyCompare(inputPwd string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = HashPassword("growerlab-dummy-password")
+	})
+	_ = ComparePassword(dummyHash, inputPwd)
+}
+
+// NeedsRehash 已保存的密码的 cost 是否低于 desiredCost（argon2id 为 TimeCost，bcrypt 为 cost），不比较算法
+// 无法解析的密码返回false（无法安全地判断，交由 ComparePassword 处理）
+func NeedsRehash(encrypted string, desiredCost int) bool {
+	switch algorithmOf(encrypted) {
+	case AlgorithmArgon2id:
+		raw, err := argon2.Decode([]byte(encrypted))
+		if err != nil {
+			return false
+		}
+		return int(raw.Config.TimeCost) < desiredCost
+	case AlgorithmBcrypt:
+		cost, err := bcrypt.Cost([]byte(encrypted))
+		if err != nil {
+			return false
+		}
+		return cost < desiredCost
+	default:
 		return false
 	}
-	return b
+}
+
+// NeedsRehashForConfig 已保存的密码是否需要使用当前配置重新生成：
+// 算法与 Algorithm 不同，或 cost 低于当前配置（argon2id 为 Cost，bcrypt 为 BcryptCost）
+// 无法解析的密码返回false
+func NeedsRehashForConfig(encrypted string) bool {
+	algorithm := algorithmOf(encrypted)
+	if len(algorithm) == 0 {
+		return false
+	}
+	if algorithm != Algorithm {
+		return true
+	}
+	desiredCost := Cost
+	if Algorithm == AlgorithmBcrypt {
+		desiredCost = BcryptCost
+	}
+	return NeedsRehash(encrypted, desiredCost)
 }
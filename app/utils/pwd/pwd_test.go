@@ -1,6 +1,7 @@
 package pwd
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,3 +16,65 @@ func TestPassword(t *testing.T) {
 	ok := ComparePassword(gotPwd, "hello pwd")
 	assert.Equal(t, true, ok, nil)
 }
+
+func TestValidateStrength(t *testing.T) {
+	assert.Equal(t, ErrTooShort, ValidateStrength("a1b2c3"))
+	assert.Equal(t, ErrNoDigit, ValidateStrength("abcdefghij"))
+	assert.Equal(t, ErrNoLetter, ValidateStrength("1234567890"))
+	assert.Equal(t, ErrCommon, ValidateStrength("Password123"))
+	assert.Nil(t, ValidateStrength("grower-9lab-x"))
+}
+
+func TestNeedsRehash(t *testing.T) {
+	defer func(c int) { Cost = c }(Cost)
+
+	Cost = 1
+	hashed, err := HashPassword("hello pwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, NeedsRehashForConfig(hashed))
+	assert.False(t, NeedsRehash(hashed, 1))
+	assert.True(t, NeedsRehash(hashed, 2))
+	assert.False(t, NeedsRehash("invalid", 2))
+	Cost = 2
+	assert.True(t, NeedsRehashForConfig(hashed))
+	assert.False(t, NeedsRehashForConfig("invalid"))
+
+	rehashed, err := HashPassword("hello pwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, NeedsRehashForConfig(rehashed))
+	assert.True(t, ComparePassword(rehashed, "hello pwd"))
+}
+
+func TestBcrypt(t *testing.T) {
+	defer func(a string, c int) { Algorithm, BcryptCost = a, c }(Algorithm, BcryptCost)
+
+	// 旧的 argon2id 密码在切换到 bcrypt 后仍然可以验证，并需要重新生成
+	argonHashed, err := HashPassword("hello pwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Algorithm, BcryptCost = AlgorithmBcrypt, 4
+	hashed, err := HashPassword("hello pwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasPrefix(hashed, "$2a$"))
+	assert.True(t, ComparePassword(hashed, "hello pwd"))
+	assert.False(t, ComparePassword(hashed, "hello"))
+	assert.False(t, NeedsRehashForConfig(hashed))
+	assert.False(t, NeedsRehash(hashed, 4))
+	assert.True(t, NeedsRehash(hashed, 5))
+
+	assert.True(t, ComparePassword(argonHashed, "hello pwd"))
+	assert.True(t, NeedsRehashForConfig(argonHashed))
+
+	// 切换回 argon2id 后，bcrypt 密码需要重新生成
+	Algorithm = AlgorithmArgon2id
+	assert.True(t, ComparePassword(hashed, "hello pwd"))
+	assert.True(t, NeedsRehashForConfig(hashed))
+}
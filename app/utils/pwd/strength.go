@@ -0,0 +1,69 @@
+package pwd
+
+import (
+	_ "embed"
+	"strings"
+	"unicode"
+
+	"github.com/growerlab/backend/app/common/errors"
+)
+
+// 密码强度要求（前端可据此做同样的校验）
+const (
+	MinLength      = 8    // 最小长度
+	RequireDigit   = true // 至少包含一个数字
+	RequireLetter  = true // 至少包含一个字母
+	DenyCommonPwds = true // 不允许使用常见密码
+)
+
+var (
+	ErrTooShort = errors.New("password is too short")
+	ErrNoDigit  = errors.New("password must contain a digit")
+	ErrNoLetter = errors.New("password must contain a letter")
+	ErrCommon   = errors.New("password is too common")
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsRaw string
+
+var commonPasswords = make(map[string]struct{})
+
+func init() {
+	for _, p := range strings.Split(commonPasswordsRaw, "\n") {
+		p = strings.TrimSpace(p)
+		if len(p) > 0 {
+			commonPasswords[p] = struct{}{}
+		}
+	}
+}
+
+// ValidateStrength 校验密码强度
+// 返回 ErrTooShort、ErrNoDigit、ErrNoLetter、ErrCommon 之一，调用者可据此转换为具体的参数错误
+func ValidateStrength(password string) error {
+	if len(password) < MinLength {
+		return ErrTooShort
+	}
+
+	var hasDigit, hasLetter bool
+	for _, r := range password {
+		switch {
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsLetter(r):
+			hasLetter = true
+		}
+	}
+	if RequireDigit && !hasDigit {
+		return ErrNoDigit
+	}
+	if RequireLetter && !hasLetter {
+		return ErrNoLetter
+	}
+
+	if DenyCommonPwds {
+		if _, found := commonPasswords[strings.ToLower(password)]; found {
+			return ErrCommon
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+var _ Limiter = (*MemoryLimiter)(nil)
+
+type hit struct {
+	at   time.Time
+	cost int
+}
+
+// MemoryLimiter 基于内存的滑动窗口限流
+// 窗口 window 内，同一个key消耗的额度之和不能超过 limit
+type MemoryLimiter struct {
+	window time.Duration
+	limit  int
+	now    func() time.Time
+
+	mu        sync.Mutex
+	hits      map[string][]hit
+	lastSweep time.Time
+}
+
+func NewMemoryLimiter(window time.Duration, limit int) *MemoryLimiter {
+	return &MemoryLimiter{
+		window: window,
+		limit:  limit,
+		now:    time.Now,
+		hits:   make(map[string][]hit),
+	}
+}
+
+func (m *MemoryLimiter) Allow(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	used := 0
+	for _, h := range m.prune(key) {
+		used += h.cost
+	}
+	return used < m.limit
+}
+
+func (m *MemoryLimiter) Hit(key string, cost int) {
+	if cost <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hits[key] = append(m.prune(key), hit{at: m.now(), cost: cost})
+	m.sweep()
+}
+
+// prune 删除key在窗口外的记录，调用者需持有锁
+func (m *MemoryLimiter) prune(key string) []hit {
+	hits := m.hits[key]
+	start := m.now().Add(-m.window)
+	i := 0
+	for i < len(hits) && !hits[i].at.After(start) {
+		i++
+	}
+	hits = hits[i:]
+	if len(hits) == 0 {
+		delete(m.hits, key)
+		return nil
+	}
+	m.hits[key] = hits
+	return hits
+}
+
+// sweep 每个窗口清理一次不再活跃的key，避免内存无限增长，调用者需持有锁
+func (m *MemoryLimiter) sweep() {
+	now := m.now()
+	if now.Sub(m.lastSweep) < m.window {
+		return
+	}
+	m.lastSweep = now
+	for key := range m.hits {
+		m.prune(key)
+	}
+}
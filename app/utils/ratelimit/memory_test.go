@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := NewMemoryLimiter(time.Minute, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if !l.Allow("ip") {
+			t.Fatalf("attempt %d should be allowed", i)
+		}
+		l.Hit("ip", 1)
+		now = now.Add(10 * time.Second)
+	}
+	if l.Allow("ip") {
+		t.Fatal("limit reached, should be rejected")
+	}
+	if !l.Allow("other") {
+		t.Fatal("keys should be limited independently")
+	}
+
+	// 第一次的记录滑出窗口后，恢复一个额度
+	now = time.Unix(1000, 0).Add(time.Minute + time.Second)
+	if !l.Allow("ip") {
+		t.Fatal("oldest hit left the window, should be allowed")
+	}
+	l.Hit("ip", 1)
+	if l.Allow("ip") {
+		t.Fatal("limit reached again, should be rejected")
+	}
+
+	// 不消耗额度
+	l.Hit("zero", 0)
+	if _, ok := l.hits["zero"]; ok {
+		t.Fatal("zero cost should not be recorded")
+	}
+}
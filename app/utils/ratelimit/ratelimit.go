@@ -0,0 +1,10 @@
+package ratelimit
+
+// Limiter 限流器
+// 默认使用内存实现（单进程），多实例部署时可替换为基于redis的实现
+type Limiter interface {
+	// Allow key 在当前窗口内是否还有剩余额度
+	Allow(key string) bool
+	// Hit 记录一次消耗，cost 为本次消耗的额度
+	Hit(key string, cost int)
+}
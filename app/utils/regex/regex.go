@@ -4,6 +4,7 @@ import "regexp"
 
 var PasswordRegex = regexp.MustCompile("^[a-zA-Z0-9!#$%&'*+/=?^_`{|}~.-]+$")
 var UsernameRegex = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+var EmailRegex = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)+$")
 var RepositoryNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-\.]{2,50}$`)
 
 func Match(val string, reg *regexp.Regexp) bool {
@@ -0,0 +1,71 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/growerlab/backend/app/common/errors"
+	"github.com/growerlab/backend/app/utils/conf"
+)
+
+// HashToken 一次性token（例如密码重置）在数据库中只保存 sha256，数据库泄露时token不能被直接使用
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Encrypt 使用 AES-GCM 加密需要保存到数据库中的敏感数据（例如TOTP密钥）
+// 密钥由配置中的 secret_key 生成
+func Encrypt(plain string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Trace(err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func Decrypt(encrypted string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if len(raw) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted data")
+	}
+	nonce, data := raw[:gcm.NonceSize()], raw[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return string(plain), nil
+}
+
+func newGCM() (cipher.AEAD, error) {
+	secretKey := conf.GetConf().SecretKey
+	if len(secretKey) == 0 {
+		return nil, errors.New("secret_key is required")
+	}
+	key := sha256.Sum256([]byte(secretKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,14 @@
+package secret
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashToken(t *testing.T) {
+	// 与 MySQL 的 SHA2(token, 256) 一致（迁移中用于转换已有的token）
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", HashToken(""))
+	assert.Len(t, HashToken("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), 64)
+	assert.NotEqual(t, HashToken("a"), HashToken("b"))
+}
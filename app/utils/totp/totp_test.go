@@ -0,0 +1,64 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 附录B的测试数据（SHA1，取后6位）
+func TestValidate(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, code := range cases {
+		assert.True(t, Validate(secret, code, time.Unix(ts, 0)), "time %d", ts)
+	}
+
+	// 允许前后一个时间步的偏差
+	assert.True(t, Validate(secret, "287082", time.Unix(59+Period, 0)))
+	assert.False(t, Validate(secret, "287082", time.Unix(59+3*Period, 0)))
+
+	assert.False(t, Validate(secret, "000000", time.Unix(59, 0)))
+	assert.False(t, Validate(secret, "28708", time.Unix(59, 0)))
+	assert.False(t, Validate("not base32!", "287082", time.Unix(59, 0)))
+}
+
+func TestValidateStep(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	// 59s 处于第1个时间步，在偏差范围内返回的仍是 code 所属的时间步
+	step, ok := ValidateStep(secret, "287082", time.Unix(59, 0))
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), step)
+
+	step, ok = ValidateStep(secret, "287082", time.Unix(59+Period, 0))
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), step)
+
+	_, ok = ValidateStep(secret, "000000", time.Unix(59, 0))
+	assert.False(t, ok)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.Nil(t, err)
+	assert.Len(t, secret, 32)
+	assert.True(t, Validate(secret, generateAt(t, secret, 100), time.Unix(100, 0)))
+}
+
+func generateAt(t *testing.T, secret string, ts int64) string {
+	key, err := b32.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return generate(key, uint64(ts/Period))
+}
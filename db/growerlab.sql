@@ -27,13 +27,83 @@ CREATE TABLE `activate_code` (
   `user_id` int NOT NULL,
   `code` varchar(16) NOT NULL DEFAULT '',
   `created_at` bigint NOT NULL,
-  `used_at` bigint NOT NULL,
-  `expired_at` bigint NOT NULL,
+  `used_at` bigint DEFAULT NULL,
+  `expired_at` bigint NOT NULL COMMENT '创建时间加上激活链接的有效期（account.activation_ttl_hours）',
   PRIMARY KEY (`id`),
-  UNIQUE KEY `unq_code` (`code`)
+  UNIQUE KEY `unq_code` (`code`),
+  KEY `idx_user` (`user_id`),
+  KEY `idx_expired` (`expired_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='用户激活码';
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `email_change`
+--
+
+DROP TABLE IF EXISTS `email_change`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `email_change` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `email` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '待验证的新邮箱',
+  `token` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'sha256(token)',
+  `created_at` bigint NOT NULL,
+  `used_at` bigint DEFAULT NULL,
+  `expired_at` bigint NOT NULL,
+  `previous_email` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '修改前的邮箱',
+  `revert_token` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci DEFAULT NULL COMMENT 'sha256(token)，token发送到旧邮箱，用于恢复旧邮箱',
+  `revert_expired_at` bigint DEFAULT NULL,
+  `reverted_at` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_token` (`token`),
+  UNIQUE KEY `unq_revert_token` (`revert_token`),
+  KEY `idx_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='修改邮箱';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `idempotency_key`
+--
+
+DROP TABLE IF EXISTS `idempotency_key`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `idempotency_key` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `scope` varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '操作，例如register',
+  `client_key` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '客户端提交的Idempotency-Key',
+  `request_hash` char(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '请求内容的摘要',
+  `resource_id` int NOT NULL COMMENT '首次执行的结果，例如注册的用户id',
+  `created_at` bigint NOT NULL,
+  `expired_at` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_scope_key` (`scope`,`client_key`),
+  KEY `idx_expired_at` (`expired_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='幂等键';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `login_audit`
+--
+
+DROP TABLE IF EXISTS `login_audit`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `login_audit` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL DEFAULT '0' COMMENT '用户不存在时为0',
+  `identifier` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '登录时使用的用户名或邮箱',
+  `client_ip` varchar(46) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '',
+  `user_agent` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '',
+  `success` tinyint(1) NOT NULL DEFAULT '0',
+  `created_at` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_user` (`user_id`,`created_at`),
+  KEY `idx_created` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='登录记录';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `namespace`
 --
@@ -52,6 +122,103 @@ CREATE TABLE `namespace` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='命名空间';
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `namespace_redirect`
+--
+
+DROP TABLE IF EXISTS `namespace_redirect`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `namespace_redirect` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `namespace_id` int NOT NULL,
+  `path` varchar(40) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '旧的path',
+  `created_at` bigint NOT NULL COMMENT '改名时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_path` (`path`),
+  KEY `idx_namespace` (`namespace_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='命名空间改名后旧path的跳转';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `namespace_transfer`
+--
+
+DROP TABLE IF EXISTS `namespace_transfer`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `namespace_transfer` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `namespace_id` int NOT NULL,
+  `from_user_id` int NOT NULL COMMENT '转让前的owner',
+  `to_user_id` int NOT NULL COMMENT '转让后的owner',
+  `created_at` bigint NOT NULL COMMENT '转让时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_namespace` (`namespace_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='组织命名空间的owner转让记录';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `namespace_member`
+--
+
+DROP TABLE IF EXISTS `namespace_member`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `namespace_member` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `namespace_id` int NOT NULL COMMENT '组织的命名空间',
+  `user_id` int NOT NULL,
+  `role` tinyint NOT NULL COMMENT '1owner 2成员',
+  `created_at` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_member` (`namespace_id`,`user_id`),
+  KEY `idx_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='组织成员';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `oauth_identity`
+--
+
+DROP TABLE IF EXISTS `oauth_identity`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `oauth_identity` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `provider` varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'github、google',
+  `provider_user_id` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '第三方的用户id',
+  `login` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '第三方的用户名',
+  `email` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '第三方的邮箱',
+  `created_at` bigint NOT NULL,
+  `last_login_at` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_provider_user` (`provider`,`provider_user_id`),
+  UNIQUE KEY `unq_user_provider` (`user_id`,`provider`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='用户绑定的第三方账号';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `password_reset`
+--
+
+DROP TABLE IF EXISTS `password_reset`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `password_reset` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `token` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'sha256(token)',
+  `created_at` bigint NOT NULL,
+  `used_at` bigint DEFAULT NULL,
+  `expired_at` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_token` (`token`),
+  KEY `idx_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='密码重置';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `permission`
 --
@@ -76,6 +243,47 @@ CREATE TABLE `permission` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='权限表';
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `personal_access_token`
+--
+
+DROP TABLE IF EXISTS `personal_access_token`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `personal_access_token` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '',
+  `token_hash` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'sha256(token)',
+  `scopes` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '以逗号分隔',
+  `created_at` bigint NOT NULL,
+  `expired_at` bigint DEFAULT NULL COMMENT 'NULL为永不过期',
+  `last_used_at` bigint DEFAULT NULL,
+  `revoked_at` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_token_hash` (`token_hash`),
+  KEY `idx_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='个人访问令牌';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `recovery_code`
+--
+
+DROP TABLE IF EXISTS `recovery_code`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `recovery_code` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `code_hash` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'sha256(恢复码)',
+  `created_at` bigint NOT NULL,
+  `used_at` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_user` (`user_id`,`code_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='两步验证的恢复码';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `repository`
 --
@@ -103,6 +311,20 @@ CREATE TABLE `repository` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='仓库表';
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `schema_migrations`
+--
+
+DROP TABLE IF EXISTS `schema_migrations`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `schema_migrations` (
+  `version` varchar(64) NOT NULL COMMENT '已执行的迁移（migration目录名）',
+  `applied_at` bigint NOT NULL,
+  PRIMARY KEY (`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `server`
 --
@@ -137,11 +359,78 @@ CREATE TABLE `session` (
   `created_at` bigint NOT NULL,
   `expired_at` bigint NOT NULL,
   `client_ip` varchar(46) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '用户当前登录的ip',
+  `bind_ip` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否只允许在登录时的网络中使用',
+  `impersonator_id` int DEFAULT NULL COMMENT '管理员以该用户身份登录时，发起的管理员id',
+  `user_agent` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci DEFAULT NULL COMMENT '登录时的User-Agent',
   PRIMARY KEY (`id`),
-  UNIQUE KEY `unq_owner` (`owner_id`,`token`)
+  UNIQUE KEY `unq_owner` (`owner_id`,`token`),
+  KEY `idx_expired_at` (`expired_at`),
+  KEY `idx_owner_created_at` (`owner_id`,`created_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `username_history`
+--
+
+DROP TABLE IF EXISTS `username_history`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `username_history` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `username` varchar(40) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '旧的用户名',
+  `created_at` bigint NOT NULL COMMENT '修改时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_username` (`username`),
+  KEY `idx_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='用户名修改历史';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `ssh_key`
+--
+
+DROP TABLE IF EXISTS `ssh_key`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `ssh_key` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `title` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '',
+  `public_key` text CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL,
+  `fingerprint` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'SHA256指纹',
+  `created_at` bigint NOT NULL,
+  `last_used_at` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_fingerprint` (`fingerprint`),
+  KEY `idx_user_created_at` (`user_id`,`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='用户ssh公钥';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `user_email`
+--
+
+DROP TABLE IF EXISTS `user_email`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `user_email` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `email` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '',
+  `is_primary` tinyint(1) NOT NULL DEFAULT '0' COMMENT '主邮箱（与user.email一致）',
+  `token` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'sha256(验证邮箱的token)，验证后清空',
+  `created_at` bigint NOT NULL,
+  `verified_at` bigint DEFAULT NULL,
+  `expired_at` bigint DEFAULT NULL COMMENT '验证链接的有效期',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_email` (`email`),
+  KEY `idx_user` (`user_id`),
+  KEY `idx_token` (`token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='用户的邮箱（一个用户可以有多个邮箱）';
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `user`
 --
@@ -162,11 +451,28 @@ CREATE TABLE `user` (
   `deleted_at` int DEFAULT NULL,
   `verified_at` int DEFAULT NULL,
   `register_ip` varchar(46) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '注册ip',
-  `is_admin` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否管理员',
+  `is_admin` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否管理员（已由roles代替，与是否拥有super_admin保持一致）',
   `namespace_id` int NOT NULL COMMENT '用户的用户域id',
+  `failed_login_count` int NOT NULL DEFAULT '0' COMMENT '连续登录失败次数',
+  `locked_until` bigint DEFAULT NULL COMMENT '账号锁定截止时间',
+  `suspended_at` bigint DEFAULT NULL COMMENT '被管理员停用的时间',
+  `totp_secret` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci DEFAULT NULL COMMENT '加密后的TOTP密钥',
+  `totp_enabled_at` bigint DEFAULT NULL COMMENT '启用两步验证的时间',
+  `totp_last_step` bigint DEFAULT NULL COMMENT '最近一次通过验证的TOTP时间步（unix时间/30），防止验证码被重放',
+  `normalized_email` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '归一化后的邮箱（用于唯一性检查）',
+  `version` bigint NOT NULL DEFAULT '0' COMMENT '乐观锁版本号（用户修改资料、邮箱、用户名时递增）',
+  `roles` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '管理后台的角色，以逗号分隔（super_admin、moderator、support）',
+  `last_username_change_at` bigint DEFAULT NULL COMMENT '最近一次修改用户名的时间',
+  `password_changed_at` bigint DEFAULT NULL COMMENT '最近一次修改密码的时间（之前创建的session无效）',
+  `first_failed_at` bigint DEFAULT NULL COMMENT '本轮连续登录失败中第一次失败的时间（超出时间窗口后重新计数）',
   PRIMARY KEY (`id`),
-  KEY `unq_email` (`email`),
-  KEY `unq_username` (`username`)
+  UNIQUE KEY `unq_email` (`email`),
+  KEY `idx_normalized_email` (`normalized_email`),
+  KEY `idx_namespace` (`namespace_id`),
+  KEY `idx_register_ip` (`register_ip`),
+  KEY `idx_last_login_ip` (`last_login_ip`),
+  KEY `idx_created_at` (`created_at`),
+  UNIQUE KEY `unq_username` (`username`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='用户表';
 /*!40101 SET character_set_client = @saved_cs_client */;
 /*!40103 SET TIME_ZONE=@OLD_TIME_ZONE */;
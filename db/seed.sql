@@ -4,13 +4,33 @@ ALTER USER 'growerlab'@'localhost' WITH MAX_QUERIES_PER_HOUR 0 MAX_UPDATES_PER_H
 GRANT DELETE, SELECT, EXECUTE, CREATE ROUTINE, ALTER ROUTINE, GRANT OPTION, REFERENCES, CREATE VIEW, TRIGGER, UPDATE, DROP, CREATE, LOCK TABLES, EVENT, INDEX, ALTER, SHOW VIEW, INSERT, CREATE TEMPORARY TABLES ON `growerlab`.* TO 'growerlab'@'localhost';
 
 
+/* growerlab.sql 已包含的迁移，不需要再执行 */
+INSERT INTO schema_migrations (version, applied_at)
+VALUES ('F20191013', UNIX_TIMESTAMP(now())),
+       ('F20261013', UNIX_TIMESTAMP(now())),
+       ('F20261014', UNIX_TIMESTAMP(now())),
+       ('F20261015', UNIX_TIMESTAMP(now())),
+       ('F20261016', UNIX_TIMESTAMP(now())),
+       ('F20261017', UNIX_TIMESTAMP(now())),
+       ('F20261018', UNIX_TIMESTAMP(now())),
+       ('F20261019', UNIX_TIMESTAMP(now())),
+       ('F20261020', UNIX_TIMESTAMP(now())),
+       ('F20261021', UNIX_TIMESTAMP(now())),
+       ('F20261022', UNIX_TIMESTAMP(now())),
+       ('F20261023', UNIX_TIMESTAMP(now())),
+       ('F20261024', UNIX_TIMESTAMP(now())),
+       ('F20261025', UNIX_TIMESTAMP(now())),
+       ('F20261026', UNIX_TIMESTAMP(now())),
+       ('F20261027', UNIX_TIMESTAMP(now()));
+
+
 /* admin user */
 INSERT INTO namespace (id, path, owner_id, type) VALUES (1, 'admin', 1, 1);
 
 INSERT INTO `user` (id, email, encrypted_password, username, name, public_email,
-                    last_login_ip, created_at, deleted_at, verified_at, last_login_at, register_ip, is_admin, namespace_id)
+                    last_login_ip, created_at, deleted_at, verified_at, last_login_at, register_ip, is_admin, roles, namespace_id)
 VALUES (1, 'admin@admin.com', '$argon2id$v=19$m=65536,t=1,p=4$r2yY6zOj4vCuQVQ9/71t/Q$FLzA2sWdvOGU4uelTlAWZjnth1C+LDjOfDqDPszvDqA', 'admin', 'admin', 'admin@admin.com',
-        null, UNIX_TIMESTAMP(now()), null, UNIX_TIMESTAMP(now()), null, '127.0.0.1', true, 1);
+        null, UNIX_TIMESTAMP(now()), null, UNIX_TIMESTAMP(now()), null, '127.0.0.1', true, 'super_admin', 1);
 
 INSERT INTO server (summary, host, port, state, created_at, deleted_at)
 VALUES ('local', '127.0.0.1', 9000, 1, UNIX_TIMESTAMP(now()), null);
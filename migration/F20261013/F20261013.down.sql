@@ -0,0 +1,39 @@
+DROP TABLE IF EXISTS `user_email`;
+DROP TABLE IF EXISTS `login_audit`;
+DROP TABLE IF EXISTS `personal_access_token`;
+DROP TABLE IF EXISTS `ssh_key`;
+DROP TABLE IF EXISTS `recovery_code`;
+DROP TABLE IF EXISTS `namespace_transfer`;
+DROP TABLE IF EXISTS `namespace_redirect`;
+DROP TABLE IF EXISTS `namespace_member`;
+DROP TABLE IF EXISTS `username_history`;
+DROP TABLE IF EXISTS `email_change`;
+DROP TABLE IF EXISTS `password_reset`;
+
+ALTER TABLE `session`
+    DROP KEY `idx_expired_at`,
+    DROP COLUMN `impersonator_id`,
+    DROP COLUMN `bind_ip`;
+
+ALTER TABLE `user`
+    DROP KEY `unq_username`,
+    DROP KEY `idx_namespace`,
+    DROP KEY `idx_normalized_email`,
+    DROP KEY `unq_email`,
+    ADD KEY `unq_email` (`email`),
+    ADD KEY `unq_username` (`username`),
+    DROP COLUMN `version`,
+    DROP COLUMN `normalized_email`,
+    DROP COLUMN `totp_enabled_at`,
+    DROP COLUMN `totp_secret`,
+    DROP COLUMN `suspended_at`,
+    DROP COLUMN `locked_until`,
+    DROP COLUMN `failed_login_count`;
+
+UPDATE `activate_code` SET `used_at` = 0 WHERE `used_at` IS NULL;
+
+ALTER TABLE `activate_code`
+    DROP KEY `idx_expired`,
+    DROP KEY `idx_user`,
+    MODIFY COLUMN `used_at` bigint NOT NULL,
+    MODIFY COLUMN `expired_at` bigint NOT NULL;
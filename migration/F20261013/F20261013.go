@@ -0,0 +1,8 @@
+package F20261013
+
+// tables and columns added before the migration runner: password reset, email change,
+// organizations, 2fa, ssh keys, access tokens, login audit, multiple emails, etc.
+
+func main() {
+
+}
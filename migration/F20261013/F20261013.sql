@@ -0,0 +1,158 @@
+ALTER TABLE `activate_code`
+    MODIFY COLUMN `used_at` bigint DEFAULT NULL,
+    MODIFY COLUMN `expired_at` bigint NOT NULL COMMENT '创建时间加上激活链接的有效期（account.activation_ttl_hours）',
+    ADD KEY `idx_user` (`user_id`),
+    ADD KEY `idx_expired` (`expired_at`);
+
+UPDATE `activate_code` SET `used_at` = NULL WHERE `used_at` = 0;
+
+ALTER TABLE `user`
+    ADD COLUMN `failed_login_count` int NOT NULL DEFAULT '0' COMMENT '连续登录失败次数',
+    ADD COLUMN `locked_until` bigint DEFAULT NULL COMMENT '账号锁定截止时间',
+    ADD COLUMN `suspended_at` bigint DEFAULT NULL COMMENT '被管理员停用的时间',
+    ADD COLUMN `totp_secret` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci DEFAULT NULL COMMENT '加密后的TOTP密钥',
+    ADD COLUMN `totp_enabled_at` bigint DEFAULT NULL COMMENT '启用两步验证的时间',
+    ADD COLUMN `normalized_email` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '归一化后的邮箱（用于唯一性检查）',
+    ADD COLUMN `version` bigint NOT NULL DEFAULT '0' COMMENT '乐观锁版本号（用户修改资料、邮箱、用户名时递增）',
+    DROP KEY `unq_email`,
+    DROP KEY `unq_username`,
+    ADD UNIQUE KEY `unq_email` (`email`),
+    ADD KEY `idx_normalized_email` (`normalized_email`),
+    ADD KEY `idx_namespace` (`namespace_id`),
+    ADD UNIQUE KEY `unq_username` (`username`);
+
+ALTER TABLE `session`
+    ADD COLUMN `bind_ip` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否只允许在登录时的网络中使用',
+    ADD COLUMN `impersonator_id` int DEFAULT NULL COMMENT '管理员以该用户身份登录时，发起的管理员id',
+    ADD KEY `idx_expired_at` (`expired_at`);
+
+CREATE TABLE `password_reset` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `token` varchar(36) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '',
+  `created_at` bigint NOT NULL,
+  `used_at` bigint DEFAULT NULL,
+  `expired_at` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_token` (`token`),
+  KEY `idx_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='密码重置';
+
+CREATE TABLE `email_change` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `email` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '待验证的新邮箱',
+  `token` varchar(36) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '',
+  `created_at` bigint NOT NULL,
+  `used_at` bigint DEFAULT NULL,
+  `expired_at` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_token` (`token`),
+  KEY `idx_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='修改邮箱';
+
+CREATE TABLE `username_history` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `username` varchar(40) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '旧的用户名',
+  `created_at` bigint NOT NULL COMMENT '修改时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_username` (`username`),
+  KEY `idx_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='用户名修改历史';
+
+CREATE TABLE `namespace_member` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `namespace_id` int NOT NULL COMMENT '组织的命名空间',
+  `user_id` int NOT NULL,
+  `role` tinyint NOT NULL COMMENT '1owner 2成员',
+  `created_at` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_member` (`namespace_id`,`user_id`),
+  KEY `idx_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='组织成员';
+
+CREATE TABLE `namespace_redirect` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `namespace_id` int NOT NULL,
+  `path` varchar(40) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '旧的path',
+  `created_at` bigint NOT NULL COMMENT '改名时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_path` (`path`),
+  KEY `idx_namespace` (`namespace_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='命名空间改名后旧path的跳转';
+
+CREATE TABLE `namespace_transfer` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `namespace_id` int NOT NULL,
+  `from_user_id` int NOT NULL COMMENT '转让前的owner',
+  `to_user_id` int NOT NULL COMMENT '转让后的owner',
+  `created_at` bigint NOT NULL COMMENT '转让时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_namespace` (`namespace_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='组织命名空间的owner转让记录';
+
+CREATE TABLE `recovery_code` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `code_hash` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'sha256(恢复码)',
+  `created_at` bigint NOT NULL,
+  `used_at` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_user` (`user_id`,`code_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='两步验证的恢复码';
+
+CREATE TABLE `ssh_key` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `title` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '',
+  `public_key` text CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL,
+  `fingerprint` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'SHA256指纹',
+  `created_at` bigint NOT NULL,
+  `last_used_at` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_fingerprint` (`fingerprint`),
+  KEY `idx_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='用户ssh公钥';
+
+CREATE TABLE `personal_access_token` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '',
+  `token_hash` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT 'sha256(token)',
+  `scopes` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '以逗号分隔',
+  `created_at` bigint NOT NULL,
+  `expired_at` bigint DEFAULT NULL COMMENT 'NULL为永不过期',
+  `last_used_at` bigint DEFAULT NULL,
+  `revoked_at` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_token_hash` (`token_hash`),
+  KEY `idx_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='个人访问令牌';
+
+CREATE TABLE `login_audit` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL DEFAULT '0' COMMENT '用户不存在时为0',
+  `identifier` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '登录时使用的用户名或邮箱',
+  `client_ip` varchar(46) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '',
+  `user_agent` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '',
+  `success` tinyint(1) NOT NULL DEFAULT '0',
+  `created_at` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_user` (`user_id`,`created_at`),
+  KEY `idx_created` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='登录记录';
+
+CREATE TABLE `user_email` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `email` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '',
+  `is_primary` tinyint(1) NOT NULL DEFAULT '0' COMMENT '主邮箱（与user.email一致）',
+  `token` varchar(36) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '验证邮箱的token',
+  `created_at` bigint NOT NULL,
+  `verified_at` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_email` (`email`),
+  KEY `idx_user` (`user_id`),
+  KEY `idx_token` (`token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='用户的邮箱（一个用户可以有多个邮箱）';
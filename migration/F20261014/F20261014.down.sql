@@ -0,0 +1,13 @@
+ALTER TABLE `user`
+    DROP KEY `idx_register_ip`,
+    DROP KEY `idx_last_login_ip`;
+
+ALTER TABLE `email_change`
+    DROP KEY `unq_revert_token`,
+    DROP COLUMN `previous_email`,
+    DROP COLUMN `revert_token`,
+    DROP COLUMN `revert_expired_at`,
+    DROP COLUMN `reverted_at`;
+
+ALTER TABLE `user`
+    DROP COLUMN `roles`;
@@ -0,0 +1,7 @@
+package F20261014
+
+// admin roles: migrate is_admin users to super_admin.
+
+func main() {
+
+}
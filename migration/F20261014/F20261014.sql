@@ -0,0 +1,15 @@
+ALTER TABLE `user`
+    ADD COLUMN `roles` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '管理后台的角色，以逗号分隔（super_admin、moderator、support）';
+
+UPDATE `user` SET `roles` = 'super_admin' WHERE `is_admin` = 1 AND `roles` = '';
+
+ALTER TABLE `email_change`
+    ADD COLUMN `previous_email` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '修改前的邮箱',
+    ADD COLUMN `revert_token` varchar(36) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci DEFAULT NULL COMMENT '发送到旧邮箱，用于恢复旧邮箱',
+    ADD COLUMN `revert_expired_at` bigint DEFAULT NULL,
+    ADD COLUMN `reverted_at` bigint DEFAULT NULL,
+    ADD UNIQUE KEY `unq_revert_token` (`revert_token`);
+
+ALTER TABLE `user`
+    ADD KEY `idx_register_ip` (`register_ip`),
+    ADD KEY `idx_last_login_ip` (`last_login_ip`);
@@ -0,0 +1,7 @@
+package F20261015
+
+// session user agent.
+
+func main() {
+
+}
@@ -0,0 +1,2 @@
+ALTER TABLE `session`
+    ADD COLUMN `user_agent` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci DEFAULT NULL COMMENT '登录时的User-Agent';
@@ -0,0 +1,2 @@
+ALTER TABLE `user`
+    DROP KEY `idx_created_at`;
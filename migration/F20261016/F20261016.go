@@ -0,0 +1,7 @@
+package F20261016
+
+// index user.created_at for signup range queries.
+
+func main() {
+
+}
@@ -0,0 +1,2 @@
+ALTER TABLE `user`
+    ADD KEY `idx_created_at` (`created_at`);
@@ -0,0 +1,7 @@
+package F20261017
+
+// idempotency keys for registration.
+
+func main() {
+
+}
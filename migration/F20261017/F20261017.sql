@@ -0,0 +1,12 @@
+CREATE TABLE `idempotency_key` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `scope` varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '操作，例如register',
+  `client_key` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '客户端提交的Idempotency-Key',
+  `request_hash` char(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '请求内容的摘要',
+  `resource_id` int NOT NULL COMMENT '首次执行的结果，例如注册的用户id',
+  `created_at` bigint NOT NULL,
+  `expired_at` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unq_scope_key` (`scope`,`client_key`),
+  KEY `idx_expired_at` (`expired_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='幂等键';
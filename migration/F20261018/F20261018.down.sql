@@ -0,0 +1,2 @@
+ALTER TABLE `user`
+    DROP COLUMN `last_username_change_at`;
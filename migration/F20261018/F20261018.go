@@ -0,0 +1,7 @@
+package F20261018
+
+// username change cooldown, initialized from username_history.
+
+func main() {
+
+}
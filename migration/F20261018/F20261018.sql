@@ -0,0 +1,6 @@
+ALTER TABLE `user`
+    ADD COLUMN `last_username_change_at` bigint DEFAULT NULL COMMENT '最近一次修改用户名的时间';
+
+UPDATE `user` AS u
+    JOIN (SELECT `user_id`, MAX(`created_at`) AS `changed_at` FROM `username_history` GROUP BY `user_id`) AS h ON h.`user_id` = u.`id`
+    SET u.`last_username_change_at` = h.`changed_at`;
@@ -0,0 +1,7 @@
+package F20261019
+
+// reject sessions created before the last password change.
+
+func main() {
+
+}
@@ -0,0 +1,2 @@
+ALTER TABLE `user`
+    ADD COLUMN `password_changed_at` bigint DEFAULT NULL COMMENT '最近一次修改密码的时间（之前创建的session无效）';
@@ -0,0 +1,6 @@
+ALTER TABLE `ssh_key`
+    DROP KEY `idx_user_created_at`,
+    ADD KEY `idx_user` (`user_id`);
+
+ALTER TABLE `session`
+    DROP KEY `idx_owner_created_at`;
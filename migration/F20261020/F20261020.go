@@ -0,0 +1,7 @@
+package F20261020
+
+// indexes for paginating sessions and ssh keys by creation time.
+
+func main() {
+
+}
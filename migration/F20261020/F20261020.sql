@@ -0,0 +1,6 @@
+ALTER TABLE `session`
+    ADD KEY `idx_owner_created_at` (`owner_id`, `created_at`);
+
+ALTER TABLE `ssh_key`
+    DROP KEY `idx_user`,
+    ADD KEY `idx_user_created_at` (`user_id`, `created_at`);
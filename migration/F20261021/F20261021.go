@@ -0,0 +1,7 @@
+package F20261021
+
+// oauth identities (github, google) linked to users.
+
+func main() {
+
+}
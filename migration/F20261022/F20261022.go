@@ -0,0 +1,7 @@
+package F20261022
+
+// window for consecutive login failures.
+
+func main() {
+
+}
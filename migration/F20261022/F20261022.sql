@@ -0,0 +1,2 @@
+ALTER TABLE `user`
+    ADD COLUMN `first_failed_at` bigint DEFAULT NULL COMMENT '本轮连续登录失败中第一次失败的时间（超出时间窗口后重新计数）';
@@ -0,0 +1,4 @@
+DELETE FROM `password_reset`;
+
+ALTER TABLE `password_reset`
+    MODIFY COLUMN `token` varchar(36) NOT NULL DEFAULT '';
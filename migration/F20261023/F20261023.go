@@ -0,0 +1,7 @@
+package F20261023
+
+// store sha256 of password reset tokens.
+
+func main() {
+
+}
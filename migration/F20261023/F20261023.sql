@@ -0,0 +1,4 @@
+ALTER TABLE `password_reset`
+    MODIFY COLUMN `token` varchar(64) NOT NULL DEFAULT '' COMMENT 'sha256(token)';
+
+UPDATE `password_reset` SET `token` = SHA2(`token`, 256);
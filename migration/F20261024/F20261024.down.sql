@@ -0,0 +1,6 @@
+DELETE FROM `email_change` WHERE `used_at` IS NULL;
+
+UPDATE `email_change` SET `token` = LEFT(`token`, 36);
+
+ALTER TABLE `email_change`
+    MODIFY COLUMN `token` varchar(36) NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+package F20261024
+
+// store sha256 of email change tokens.
+
+func main() {
+
+}
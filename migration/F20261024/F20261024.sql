@@ -0,0 +1,4 @@
+ALTER TABLE `email_change`
+    MODIFY COLUMN `token` varchar(64) NOT NULL DEFAULT '' COMMENT 'sha256(token)';
+
+UPDATE `email_change` SET `token` = SHA2(`token`, 256);
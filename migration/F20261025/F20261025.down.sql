@@ -0,0 +1,4 @@
+UPDATE `email_change` SET `revert_token` = NULL;
+
+ALTER TABLE `email_change`
+    MODIFY COLUMN `revert_token` varchar(36) DEFAULT NULL COMMENT '发送到旧邮箱，用于恢复旧邮箱';
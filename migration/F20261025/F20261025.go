@@ -0,0 +1,7 @@
+package F20261025
+
+// store sha256 of email change revert tokens.
+
+func main() {
+
+}
@@ -0,0 +1,4 @@
+ALTER TABLE `email_change`
+    MODIFY COLUMN `revert_token` varchar(64) DEFAULT NULL COMMENT 'sha256(token)，token发送到旧邮箱，用于恢复旧邮箱';
+
+UPDATE `email_change` SET `revert_token` = SHA2(`revert_token`, 256) WHERE `revert_token` IS NOT NULL;
@@ -0,0 +1,5 @@
+UPDATE `user_email` SET `token` = '';
+
+ALTER TABLE `user_email`
+    DROP COLUMN `expired_at`,
+    MODIFY COLUMN `token` varchar(36) NOT NULL DEFAULT '' COMMENT '验证邮箱的token';
@@ -0,0 +1,7 @@
+package F20261026
+
+// store sha256 of secondary email verification tokens and expire them.
+
+func main() {
+
+}
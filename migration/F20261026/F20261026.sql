@@ -0,0 +1,7 @@
+ALTER TABLE `user_email`
+    MODIFY COLUMN `token` varchar(64) NOT NULL DEFAULT '' COMMENT 'sha256(验证邮箱的token)，验证后清空',
+    ADD COLUMN `expired_at` bigint DEFAULT NULL COMMENT '验证链接的有效期' AFTER `verified_at`;
+
+UPDATE `user_email` SET `token` = SHA2(`token`, 256) WHERE `token` <> '';
+
+UPDATE `user_email` SET `expired_at` = `created_at` + 86400 WHERE `verified_at` IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE `user`
+    DROP COLUMN `totp_last_step`;
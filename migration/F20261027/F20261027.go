@@ -0,0 +1,7 @@
+package F20261027
+
+// remember the last accepted TOTP time step to reject replayed codes.
+
+func main() {
+
+}
@@ -0,0 +1,2 @@
+ALTER TABLE `user`
+    ADD COLUMN `totp_last_step` bigint DEFAULT NULL COMMENT '最近一次通过验证的TOTP时间步（unix时间/30），防止验证码被重放' AFTER `totp_enabled_at`;
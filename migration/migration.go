@@ -0,0 +1,9 @@
+package migration
+
+import "embed"
+
+// FS 编译进二进制的迁移文件，每个迁移一个目录，按目录名排序执行
+// 目录中 <目录名>.sql 为升级，<目录名>.down.sql 为回滚（可选）
+//
+//go:embed F*/*.sql
+var FS embed.FS